@@ -0,0 +1,110 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// coreos-install is the Go port of bin/coreos-install, it takes the same
+// options and prints the same messages
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/coreos/init/install"
+)
+
+func main() {
+	// everything we do should be user-access only
+	syscall.Umask(077)
+
+	opts := install.Defaults()
+	defaults := opts
+
+	flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.StringVar(&opts.Device, "d", "", "")
+	flags.StringVar(&opts.Version, "V", opts.Version, "")
+	flags.StringVar(&opts.Board, "B", opts.Board, "")
+	flags.StringVar(&opts.Channel, "C", opts.Channel, "")
+	flags.StringVar(&opts.OEM, "o", opts.OEM, "")
+	flags.StringVar(&opts.CloudConfig, "c", "", "")
+	flags.StringVar(&opts.Ignition, "i", "", "")
+	flags.String("t", "", "") // compatibility option; previously set TMPDIR
+	flags.StringVar(&opts.BaseURL, "b", "", "")
+	flags.StringVar(&opts.KeyFile, "k", "", "")
+	flags.StringVar(&opts.ImageFile, "f", "", "")
+	flags.BoolVar(&opts.CopyNetwork, "n", false, "")
+	flags.Bool("v", false, "")
+
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			fmt.Print(usage(defaults))
+			return
+		}
+		// match getopts' messages
+		msg := err.Error()
+		msg = strings.Replace(msg, "flag provided but not defined: -", "illegal option -- ", 1)
+		msg = strings.Replace(msg, "flag needs an argument: -", "option requires an argument -- ", 1)
+		fmt.Fprintf(os.Stderr, "%s: %s\n", os.Args[0], msg)
+		os.Exit(1)
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "V":
+			opts.VersionSpecified = true
+		case "C":
+			opts.ChannelSpecified = true
+		}
+	})
+
+	opts.Stdout = os.Stdout
+	opts.Stderr = os.Stderr
+	if err := install.Install(context.Background(), opts); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[0], err)
+		os.Exit(1)
+	}
+}
+
+func usage(defaults install.Options) string {
+	oem := defaults.OEM
+	if oem == "" {
+		oem = "(none)"
+	}
+
+	return fmt.Sprintf(`Usage: %s [-C channel] -d /dev/device
+Options:
+    -d DEVICE   Install Container Linux to the given device.
+    -V VERSION  Version to install (e.g. current) [default: %s]
+    -B BOARD    Container Linux board to use [default: %s]
+    -C CHANNEL  Release channel to use (e.g. beta) [default: %s]
+    -o OEM      OEM type to install (e.g. ami) [default: %s]
+    -c CLOUD    Insert a cloud-init config to be executed on boot.
+    -i IGNITION Insert an Ignition config to be executed on boot.
+    -b BASEURL  URL to the image mirror (overrides BOARD)
+    -k KEYFILE  Override default GPG key for verifying image signature
+    -f IMAGE    Install unverified local image file to disk instead of fetching
+    -n          Copy generated network units to the root partition.
+    -v          Super verbose, for debugging.
+    -h          This ;-)
+
+This tool installs CoreOS Container Linux on a block device. If you PXE booted
+Container Linux on a machine then use this tool to make a permanent install.
+
+`, os.Args[0], defaults.Version, defaults.Board, defaults.Channel, oem)
+}
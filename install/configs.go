@@ -0,0 +1,138 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// mountLabel mounts the partition of the installed disk labelled label.
+// The partition is found by label on the disk itself rather than by
+// number, or by label system wide where another disk could conflict.
+// Partitions are listed by lsblk, a glob for /dev/loop1* would match
+// /dev/loop10p9 too.
+func mountLabel(device, label, target string) (func(), error) {
+	out, err := exec.Command("lsblk", "-lnpo", "NAME", device).Output()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't list the partitions of %s: %v", device, err)
+	}
+	args := append([]string{"-t", "LABEL=" + label, "-o", "device"}, strings.Fields(string(out))...)
+	out, err = exec.Command("blkid", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't find the %s partition on %s: %v", label, device, err)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("couldn't find the %s partition on %s", label, device)
+	}
+	partition := fields[0]
+
+	out, err = exec.Command("blkid", "-o", "value", "-s", "TYPE", partition).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	fstype, data := strings.TrimSpace(string(out)), ""
+	if fstype == "btrfs" {
+		data = "subvol=root"
+	}
+
+	if err := os.MkdirAll(target, 0700); err != nil {
+		return nil, err
+	}
+	if err := syscall.Mount(partition, target, fstype, 0, data); err != nil {
+		return nil, fmt.Errorf("couldn't mount %s: %v", partition, err)
+	}
+	return func() { syscall.Unmount(target, 0) }, nil
+}
+
+func writeCloudinit(opts Options, workDir string) error {
+	if opts.CloudConfig == "" && !opts.CopyNetwork {
+		return nil
+	}
+
+	rootfs := filepath.Join(workDir, "rootfs")
+	unmount, err := mountLabel(opts.Device, "ROOT", rootfs)
+	if err != nil {
+		return err
+	}
+	defer unmount()
+
+	if opts.CloudConfig != "" {
+		fmt.Fprintf(opts.Stdout, "Installing cloud-config...\n")
+		dir := filepath.Join(rootfs, "var", "lib", "coreos-install")
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+		if err := copyFile(opts.CloudConfig, filepath.Join(dir, "user_data")); err != nil {
+			return err
+		}
+	}
+
+	if opts.CopyNetwork {
+		fmt.Fprintf(opts.Stdout, "Copying network units to root partition.\n")
+		// don't overwrite anything, keep permissions, and copy the
+		// resolv.conf link as a file
+		units, _ := filepath.Glob("/run/systemd/network/*")
+		args := append([]string{"--recursive", "--no-clobber", "--preserve", "--dereference"}, units...)
+		args = append(args, filepath.Join(rootfs, "etc", "systemd", "network"))
+		if out, err := exec.Command("cp", args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("couldn't copy network units: %v: %s", err, out)
+		}
+	}
+	return nil
+}
+
+func writeIgnition(opts Options, workDir string) error {
+	if opts.Ignition == "" {
+		return nil
+	}
+
+	oemfs := filepath.Join(workDir, "oemfs")
+	unmount, err := mountLabel(opts.Device, "OEM", oemfs)
+	if err != nil {
+		return err
+	}
+	defer unmount()
+
+	fmt.Fprintf(opts.Stdout, "Installing Ignition config %s...\n", opts.Ignition)
+	if err := copyFile(opts.Ignition, filepath.Join(oemfs, "coreos-install.json")); err != nil {
+		return err
+	}
+
+	grub, err := os.OpenFile(filepath.Join(oemfs, "grub.cfg"), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(grub, `set linux_append="$linux_append coreos.config.url=oem:///coreos-install.json"`); err != nil {
+		grub.Close()
+		return err
+	}
+	return grub.Close()
+}
+
+func copyFile(src, dst string) error {
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dst, data, 0600)
+}
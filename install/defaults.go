@@ -0,0 +1,84 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"bufio"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// Defaults are the options the script starts from: the running system's
+// version, channel, board and OEM if it's Container Linux, and the current
+// stable release otherwise
+func Defaults() Options {
+	opts := Options{
+		Version: "current",
+		Channel: "stable",
+		Board:   defaultBoard(),
+	}
+
+	if readEnvFile("/etc/os-release")["ID"] == "coreos" {
+		if v := readEnvFile("/etc/os-release")["VERSION_ID"]; v != "" {
+			opts.Version = v
+		}
+		for _, f := range []string{"/usr/share/coreos/update.conf", "/etc/coreos/update.conf"} {
+			if group := readEnvFile(f)["GROUP"]; group != "" {
+				opts.Channel = group
+			}
+		}
+	}
+
+	for _, f := range []string{"/usr/share/oem/oem-release", "/etc/oem-release"} {
+		if id := readEnvFile(f)["ID"]; id != "" {
+			opts.OEM = id
+		}
+	}
+	return opts
+}
+
+func defaultBoard() string {
+	if board := readEnvFile("/usr/share/coreos/release")["COREOS_RELEASE_BOARD"]; board != "" {
+		return board
+	}
+
+	if runtime.GOARCH == "arm64" {
+		return "arm64-usr"
+	}
+	return "amd64-usr"
+}
+
+// readEnvFile reads the KEY=value lines of a file like os-release, a
+// missing file has no values
+func readEnvFile(path string) map[string]string {
+	values := map[string]string{}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return values
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		kv := strings.SplitN(strings.TrimSpace(scanner.Text()), "=", 2)
+		if len(kv) != 2 || strings.HasPrefix(kv[0], "#") {
+			continue
+		}
+		values[kv[0]] = strings.Trim(kv[1], `"'`)
+	}
+	return values
+}
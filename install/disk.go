@@ -0,0 +1,100 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// from linux/fs.h
+const blkRRPart = 0x125F
+
+// the script wipes the last 1024 sectors first, ZFS keeps labels there
+// that writing the image wouldn't overwrite
+const tailWipeBytes = 1024 * 512
+
+type disk struct {
+	path   string
+	stderr io.Writer
+	// written to, so a failed install has to wipe it
+	modified bool
+}
+
+func writable(path string) bool {
+	return syscall.Access(path, 2) == nil
+}
+
+// write copies the image to the disk and has the kernel reread its
+// partitions
+func (d *disk) write(image io.Reader) error {
+	f, err := os.OpenFile(d.path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	d.modified = true
+	if _, err := f.WriteAt(make([]byte, tailWipeBytes), size-tailWipeBytes); err != nil {
+		return err
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.CopyBuffer(f, image, make([]byte, 1024*1024)); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	settle()
+	if err := d.rereadPartitions(f); err != nil {
+		return err
+	}
+	settle()
+	return nil
+}
+
+func (d *disk) rereadPartitions(f *os.File) error {
+	// give the device a bit more time on each attempt
+	for _, wait := range []time.Duration{0, 1, 2, 4} {
+		time.Sleep(wait * time.Second)
+
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), blkRRPart, 0)
+		if errno == 0 {
+			return nil
+		}
+		fmt.Fprintf(d.stderr, "Failed to reread partitions on %s\n", d.path)
+	}
+	return errorf("Failed to reread partitions on %s", d.path)
+}
+
+// settle waits for udev to create the partition nodes, if it's running
+func settle() {
+	if _, err := exec.LookPath("udevadm"); err == nil {
+		exec.Command("udevadm", "settle").Run()
+	}
+}
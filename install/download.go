@@ -0,0 +1,269 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+func installFromFile(opts Options, disk *disk) (string, error) {
+	f, err := os.Open(opts.ImageFile)
+	if err != nil {
+		return "", errorf("Could not read image file: %s", opts.ImageFile)
+	}
+	defer f.Close()
+
+	fmt.Fprintf(opts.Stdout, "Writing %s...\n", opts.ImageFile)
+	var image io.Reader = f
+	if strings.HasSuffix(opts.ImageFile, ".bz2") {
+		image = bzip2.NewReader(f)
+	}
+
+	if err := disk.write(image); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("CoreOS Container Linux (from %s)", opts.ImageFile), nil
+}
+
+var oldChannels = regexp.MustCompile(`^(alpha|beta|stable)$`)
+
+// release resolves the channel, version and mirror like the script
+func release(opts Options) (channel, version, baseURL string) {
+	channel, version = opts.Channel, opts.Version
+	if opts.ChannelSpecified && !opts.VersionSpecified {
+		version = "current"
+	}
+
+	// for compatibility with old versions that didn't support channels
+	if oldChannels.MatchString(version) {
+		channel, version = version, "current"
+	}
+
+	baseURL = strings.TrimSuffix(opts.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("https://%s.release.core-os.net/%s", channel, opts.Board)
+	}
+	return
+}
+
+func installFromURL(ctx context.Context, opts Options, workDir string, disk *disk) (string, error) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		return "", errorf("Missing gpg!")
+	}
+
+	imageName := "coreos_production_image.bin.bz2"
+	if opts.OEM != "" {
+		imageName = fmt.Sprintf("coreos_production_%s_image.bin.bz2", opts.OEM)
+	}
+
+	channel, version, baseURL := release(opts)
+	if version == "current" {
+		versionURL := baseURL + "/current/version.txt"
+		version = currentVersion(ctx, opts.Client, versionURL)
+		if version == "" {
+			return "", errorf("version.txt unavailable: %s", versionURL)
+		}
+		fmt.Fprintf(opts.Stdout, "Current version of CoreOS Container Linux %s is %s\n", channel, version)
+	}
+
+	imageURL := baseURL + "/" + version + "/" + imageName
+	sigURL := imageURL + ".sig"
+	if !available(ctx, opts.Client, imageURL) {
+		return "", errorf("Image URL unavailable: %s", imageURL)
+	}
+	if !available(ctx, opts.Client, sigURL) {
+		return "", errorf("Image signature unavailable: %s", sigURL)
+	}
+
+	gnupgHome := filepath.Join(workDir, "gnupg")
+	if err := importKey(ctx, gnupgHome, opts.KeyFile); err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(opts.Stdout, "Downloading the signature for %s...\n", imageURL)
+	sigPath := filepath.Join(workDir, imageName+".sig")
+	if err := download(ctx, opts.Client, sigURL, sigPath); err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(opts.Stdout, "Downloading, writing and verifying %s...\n", imageName)
+	if err := writeVerified(ctx, opts, gnupgHome, sigPath, imageURL, imageName, disk); err != nil {
+		return "", err
+	}
+
+	summary := fmt.Sprintf("CoreOS Container Linux %s %s", channel, version)
+	if opts.OEM != "" {
+		summary += fmt.Sprintf(" (%s)", opts.OEM)
+	}
+	return summary, nil
+}
+
+func get(ctx context.Context, client *http.Client, method, url string) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return resp, nil
+}
+
+func currentVersion(ctx context.Context, client *http.Client, url string) string {
+	resp, err := get(ctx, client, "GET", url)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if v := strings.TrimPrefix(scanner.Text(), "COREOS_VERSION="); v != scanner.Text() {
+			return v
+		}
+	}
+	return ""
+}
+
+func available(ctx context.Context, client *http.Client, url string) bool {
+	resp, err := get(ctx, client, "HEAD", url)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
+
+func download(ctx context.Context, client *http.Client, url, path string) error {
+	resp, err := get(ctx, client, "GET", url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func importKey(ctx context.Context, gnupgHome, keyFile string) error {
+	if err := os.MkdirAll(gnupgHome, 0700); err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, "gpg", "--batch", "--quiet", "--import")
+	cmd.Env = append(os.Environ(), "GNUPGHOME="+gnupgHome)
+	if keyFile != "" {
+		f, err := os.Open(keyFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		cmd.Stdin = f
+	} else {
+		cmd.Stdin = strings.NewReader(DefaultKey)
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("gpg --import failed: %v: %s", err, out)
+	}
+	return nil
+}
+
+// errReader remembers the error reading from r, so download failures can
+// be told apart from write failures
+type errReader struct {
+	r   io.Reader
+	err error
+}
+
+func (e *errReader) Read(p []byte) (int, error) {
+	n, err := e.r.Read(p)
+	if err != nil && err != io.EOF {
+		e.err = err
+	}
+	return n, err
+}
+
+// writeVerified streams the image to the disk and to gpg at the same time,
+// like the script's tee, so it's only downloaded once
+func writeVerified(ctx context.Context, opts Options, gnupgHome, sigPath, imageURL, imageName string, disk *disk) error {
+	resp, err := get(ctx, opts.Client, "GET", imageURL)
+	if err != nil {
+		return errorf("1: Download of %s did not complete", imageName)
+	}
+	defer resp.Body.Close()
+	body := &errReader{r: resp.Body}
+
+	gpg := exec.CommandContext(ctx, "gpg", "--batch", "--trusted-key", DefaultKeyID, "--verify", sigPath, "-")
+	gpg.Env = append(os.Environ(), "GNUPGHOME="+gnupgHome)
+	gpg.Stderr = opts.Stderr
+	// a real pipe rather than an io.Pipe, so if gpg exits before it read
+	// the whole image, e.g. over a bad signature, writes to it fail with
+	// EPIPE like the script's tee instead of blocking forever
+	toGpg, err := gpg.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := gpg.Start(); err != nil {
+		return err
+	}
+
+	writeErr := disk.write(bzip2.NewReader(io.TeeReader(body, toGpg)))
+	// gpg needs the whole image even if the write stopped early
+	io.Copy(toGpg, body)
+	toGpg.Close()
+	gpgErr := gpg.Wait()
+
+	var failures []string
+	if body.err != nil {
+		failures = append(failures, fmt.Sprintf("1: Download of %s did not complete", imageName))
+	}
+	if writeErr != nil {
+		if e, ok := writeErr.(*Error); ok {
+			return e
+		}
+		failures = append(failures, fmt.Sprintf("1: Cannot expand %s to %s", imageName, opts.Device))
+	}
+	if gpgErr != nil {
+		failures = append(failures, fmt.Sprintf("1: GPG signature verification failed for %s", imageName))
+	}
+	if len(failures) != 0 {
+		return &Error{strings.Join(failures, "\n")}
+	}
+	return nil
+}
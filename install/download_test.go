@@ -0,0 +1,65 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gpg that rejects the signature without reading the image has to fail the
+// install rather than leave it blocked writing to gpg
+func TestWriteVerifiedEarlyGPGExit(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "gpg"), []byte("#!/bin/sh\nexit 2\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+
+	image := bytes.Repeat([]byte("BZh9"), 4<<20)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(image)
+	}))
+	defer server.Close()
+
+	device := filepath.Join(dir, "disk")
+	if err := ioutil.WriteFile(device, make([]byte, 1<<20), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := Options{Device: device, Client: server.Client(), Stdout: ioutil.Discard, Stderr: ioutil.Discard}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- writeVerified(context.Background(), opts, dir, filepath.Join(dir, "image.sig"), server.URL+"/image.bz2", "image.bz2", &disk{path: device, stderr: ioutil.Discard})
+	}()
+
+	select {
+	case err := <-errc:
+		if err == nil || !strings.Contains(err.Error(), "GPG signature verification failed for image.bz2") {
+			t.Errorf("got %v, expected the signature to fail", err)
+		}
+	case <-time.After(30 * time.Second):
+		// or Close waits for the stuck download
+		server.CloseClientConnections()
+		t.Fatalf("writeVerified blocked after gpg exited")
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package install is a Go implementation of bin/coreos-install. It installs
+// Container Linux the same way, with the same messages, so the tests in
+// tests/ can run against either.
+package install
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+type Options struct {
+	// the whole disk to install to
+	Device  string
+	Version string
+	Board   string
+	Channel string
+	OEM     string
+
+	// config files to install, "" for none
+	CloudConfig string
+	Ignition    string
+
+	// mirror to download from, overrides Board and Channel
+	BaseURL string
+	// armored GPG key to verify the image with instead of DefaultKey
+	KeyFile string
+	// local image to write unverified instead of downloading one
+	ImageFile string
+
+	// copy the PXE booted system's generated network units
+	CopyNetwork bool
+
+	// set when Version or Channel were given explicitly, as they change
+	// how the other is defaulted
+	VersionSpecified bool
+	ChannelSpecified bool
+
+	// progress messages, defaults to discarding them
+	Stdout io.Writer
+	// warnings that don't stop the install
+	Stderr io.Writer
+
+	Client *http.Client
+}
+
+// Error is why an install failed, its message is what the script prints
+type Error struct {
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return e.Msg
+}
+
+func errorf(format string, args ...interface{}) error {
+	return &Error{fmt.Sprintf(format, args...)}
+}
+
+// Install writes Container Linux to opts.Device and installs the configs.
+// If it fails after it started writing the disk, the partition tables are
+// wiped so the disk isn't left half installed.
+func Install(ctx context.Context, opts Options) error {
+	if opts.Stdout == nil {
+		opts.Stdout = ioutil.Discard
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = ioutil.Discard
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+
+	if err := validate(ctx, opts); err != nil {
+		return err
+	}
+
+	workDir, err := ioutil.TempDir("", "coreos-install.")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	disk := &disk{path: opts.Device, stderr: opts.Stderr}
+	err = install(ctx, opts, workDir, disk)
+	if err != nil && disk.modified {
+		exec.Command("wipefs", "--all", "--backup", opts.Device).Run()
+	}
+	return err
+}
+
+func install(ctx context.Context, opts Options, workDir string, disk *disk) error {
+	var summary string
+	var err error
+	if opts.ImageFile != "" {
+		summary, err = installFromFile(opts, disk)
+	} else {
+		summary, err = installFromURL(ctx, opts, workDir, disk)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := writeCloudinit(opts, workDir); err != nil {
+		return err
+	}
+	if err := writeIgnition(opts, workDir); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(opts.Stdout, "Success! %s is installed on %s\n", summary, opts.Device)
+	return nil
+}
+
+// validate checks the options before anything is downloaded or written
+func validate(ctx context.Context, opts Options) error {
+	if opts.Device == "" {
+		return errorf("No target block device provided, -d is required.")
+	}
+
+	out, _ := exec.CommandContext(ctx, "lsblk", "-n", "-d", "-o", "TYPE", opts.Device).Output()
+	switch strings.TrimSpace(string(out)) {
+	case "disk", "loop", "lvm":
+	default:
+		return errorf("Target block device (%s) is not a full disk.", opts.Device)
+	}
+
+	if !writable(opts.Device) {
+		return errorf("Target block device (%s) is not writable (are you root?)", opts.Device)
+	}
+
+	if opts.CloudConfig != "" {
+		if !isRegular(opts.CloudConfig) {
+			return errorf("Cloud config file (%s) does not exist.", opts.CloudConfig)
+		}
+
+		if _, err := exec.LookPath("coreos-cloudinit"); err != nil {
+			fmt.Fprintf(opts.Stderr, "coreos-cloudinit not found. Could not validate config. Continuing...\n")
+		} else if err := exec.CommandContext(ctx, "coreos-cloudinit", "-from-file="+opts.CloudConfig, "-validate").Run(); err != nil {
+			return errorf("Cloud config file (%s) is not valid.", opts.CloudConfig)
+		}
+	}
+
+	if opts.Ignition != "" && !isRegular(opts.Ignition) {
+		return errorf("Ignition config file (%s) does not exist.", opts.Ignition)
+	}
+	return nil
+}
+
+// isRegular is the script's [[ -f ]], which follows symlinks
+func isRegular(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
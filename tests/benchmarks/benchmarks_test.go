@@ -0,0 +1,153 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package benchmarks
+
+import (
+	"context"
+	"flag"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coreos/init/tests/installer"
+	"github.com/coreos/init/tests/util"
+)
+
+var (
+	historyFlag   = flag.String("history", "", "JSON file to record results in and compare them against")
+	thresholdFlag = flag.Float64("regression-threshold", 0.2, "fail benchmarks that got worse than the last recorded run by more than this fraction")
+	commitFlag    = flag.String("commit", "", "commit being measured, recorded in -history")
+)
+
+const benchmarkIgnition = `{"ignition": {"version": "2.1.0"}}`
+
+// BenchmarkInstall installs a synthetic image and, with
+// COREOS_TEST_FIXTURE_DIR, the mirror's current image with -f. Each reports
+// install time, write throughput and validation time, e.g.
+//
+//	go test -run '^$' -bench . -benchtime 3x -history history.json
+func BenchmarkInstall(b *testing.B) {
+	if !util.Host().Has(util.HostLoopDevices) {
+		b.Skip("loop devices are required to benchmark installs")
+	}
+
+	config, err := util.LoadConfig()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	binary := config.Installer
+	if binary == "" {
+		binary, err = filepath.Abs(filepath.Join("..", "..", "bin", "coreos-install"))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	temp := util.NewTempManager()
+	defer temp.Close()
+
+	b.Run("synthetic", func(b *testing.B) {
+		dir, err := temp.Dir("coreos-install-benchmark")
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		image := filepath.Join(dir, "synthetic.bin")
+		if err := SyntheticImage(image); err != nil {
+			b.Skipf("couldn't build a synthetic image: %v", err)
+		}
+		benchmarkInstall(b, binary, image)
+	})
+
+	b.Run("real", func(b *testing.B) {
+		if config.FixtureDir == "" {
+			b.Skip("COREOS_TEST_FIXTURE_DIR is required to benchmark a real image")
+		}
+
+		image, err := RealImage(config.FixtureDir, "amd64-usr")
+		if err != nil {
+			b.Skipf("couldn't find a real image: %v", err)
+		}
+		benchmarkInstall(b, binary, image)
+	})
+}
+
+func benchmarkInstall(b *testing.B, binary, image string) {
+	var install, validate time.Duration
+	var written int64
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, err := installer.Run(context.Background(), installer.Options{
+			Binary:   binary,
+			Args:     []string{"-f", image},
+			Ignition: benchmarkIgnition,
+		})
+		if err != nil {
+			b.Fatalf("%v: %s", err, result.Stderr)
+		}
+		if !result.OK() {
+			b.Fatalf("bad install: %v", result.Problems)
+		}
+
+		install += result.Duration
+		validate += result.ValidateDuration
+		for _, p := range result.Partitions {
+			if end := int64(p.LastLBA+1) * sectorSize; end > written {
+				written = end
+			}
+		}
+	}
+	b.StopTimer()
+
+	m := Measurement{
+		Name:            b.Name(),
+		Time:            time.Now(),
+		Commit:          *commitFlag,
+		InstallSeconds:  install.Seconds() / float64(b.N),
+		WriteMBps:       float64(written) / (1 << 20) / (install.Seconds() / float64(b.N)),
+		ValidateSeconds: validate.Seconds() / float64(b.N),
+	}
+	b.ReportMetric(m.InstallSeconds, "install-s")
+	b.ReportMetric(m.WriteMBps, "MB/s")
+	b.ReportMetric(m.ValidateSeconds, "validate-s")
+
+	record(b, m)
+}
+
+// record adds the measurement to -history and fails the benchmark if it
+// regressed since the last recorded run
+func record(b *testing.B, m Measurement) {
+	if *historyFlag == "" {
+		return
+	}
+
+	history, err := LoadHistory(*historyFlag)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	if baseline, ok := history.Latest(m.Name); ok {
+		for _, r := range Regressions(baseline, m, *thresholdFlag) {
+			b.Errorf("%s regressed since %s: %s", m.Name, baseline.Time.Format(time.RFC3339), r)
+		}
+	}
+
+	history.Add(m)
+	if err := history.Save(*historyFlag); err != nil {
+		b.Fatal(err)
+	}
+}
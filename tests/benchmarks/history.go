@@ -0,0 +1,108 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package benchmarks measures installs and keeps their results in a JSON
+// history so slowdowns between runs are caught. The benchmarks themselves
+// run with go test -bench, see benchmarks_test.go.
+package benchmarks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// Measurement is one benchmark's result
+type Measurement struct {
+	Name string    `json:"name"`
+	Time time.Time `json:"time"`
+	// the commit that was measured, if known
+	Commit string `json:"commit,omitempty"`
+
+	InstallSeconds  float64 `json:"install_seconds"`
+	WriteMBps       float64 `json:"write_mbps"`
+	ValidateSeconds float64 `json:"validate_seconds"`
+}
+
+// History is every measurement recorded so far, oldest first
+type History struct {
+	Measurements []Measurement `json:"measurements"`
+}
+
+// LoadHistory reads the history at path, which doesn't have to exist yet
+func LoadHistory(path string) (*History, error) {
+	h := &History{}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %v", path, err)
+	}
+	return h, nil
+}
+
+func (h *History) Save(path string) error {
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Latest returns the last measurement of the benchmark
+func (h *History) Latest(name string) (Measurement, bool) {
+	for i := len(h.Measurements) - 1; i >= 0; i-- {
+		if h.Measurements[i].Name == name {
+			return h.Measurements[i], true
+		}
+	}
+	return Measurement{}, false
+}
+
+func (h *History) Add(m Measurement) {
+	h.Measurements = append(h.Measurements, m)
+}
+
+// Regressions lists every metric of current that's worse than baseline by
+// more than threshold, e.g. 0.2 for 20%
+func Regressions(baseline, current Measurement, threshold float64) (regressions []string) {
+	for _, m := range []struct {
+		name           string
+		before, after  float64
+		higherIsBetter bool
+	}{
+		{"install time", baseline.InstallSeconds, current.InstallSeconds, false},
+		{"write throughput", baseline.WriteMBps, current.WriteMBps, true},
+		{"validation time", baseline.ValidateSeconds, current.ValidateSeconds, false},
+	} {
+		if m.before == 0 {
+			continue
+		}
+
+		change := (m.after - m.before) / m.before
+		if m.higherIsBetter {
+			change = -change
+		}
+		if change > threshold {
+			regressions = append(regressions, fmt.Sprintf("%s went from %.2f to %.2f, %.0f%% worse", m.name, m.before, m.after, change*100))
+		}
+	}
+	return
+}
@@ -0,0 +1,115 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package benchmarks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/init/tests/util"
+)
+
+// SyntheticImageSize is the size of the image built by SyntheticImage
+const SyntheticImageSize = 1024 * 1024 * 1024
+
+const sectorSize = 512
+
+// the partitions an install is checked for, the rest of the disk is ROOT
+var syntheticLayout = []struct {
+	number int
+	label  string
+	size   string
+}{
+	{1, "EFI-SYSTEM", "+64M"},
+	{3, "USR-A", "+640M"},
+	{6, "OEM", "+64M"},
+	{9, "ROOT", "0"},
+}
+
+// SyntheticImage builds a raw image at path with the partitions of a real
+// one: USR-A and ROOT are filled with random data so writing it costs what
+// writing a real image would, and OEM gets an ext4 filesystem so configs
+// can be installed to it
+func SyntheticImage(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := f.Truncate(SyntheticImageSize); err != nil {
+		return err
+	}
+
+	args := []string{"-o"}
+	for _, p := range syntheticLayout {
+		n := strconv.Itoa(p.number)
+		args = append(args, "-n", n+":0:"+p.size, "-c", n+":"+p.label)
+	}
+	if _, err := util.RunE(context.Background(), "sgdisk", append(args, path)...); err != nil {
+		return err
+	}
+
+	gpt, err := util.ReadGPT(path)
+	if err != nil {
+		return err
+	}
+
+	random := rand.New(rand.NewSource(1))
+	for _, p := range gpt.Partitions {
+		offset := int64(p.FirstLBA) * sectorSize
+		size := int64(p.LastLBA-p.FirstLBA+1) * sectorSize
+
+		switch p.Name {
+		case "USR-A", "ROOT":
+			if _, err := f.Seek(offset, io.SeekStart); err != nil {
+				return err
+			}
+			if _, err := io.CopyN(f, random, size); err != nil {
+				return fmt.Errorf("couldn't fill %s: %v", p.Name, err)
+			}
+		case "OEM":
+			_, err := util.RunE(context.Background(), "mkfs.ext4", "-q", "-F", "-L", "OEM", "-E", fmt.Sprintf("offset=%d", offset), path, strconv.FormatInt(size/1024, 10))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RealImage finds the current production image in a local release mirror
+func RealImage(fixtureDir, board string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(fixtureDir, board, "current", "version.txt"))
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if version := strings.TrimPrefix(line, "COREOS_VERSION="); version != line {
+			image := filepath.Join(fixtureDir, board, version, "coreos_production_image.bin.bz2")
+			_, err := os.Stat(image)
+			return image, err
+		}
+	}
+	return "", fmt.Errorf("%s/current/version.txt doesn't set COREOS_VERSION", board)
+}
@@ -0,0 +1,45 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+// FuzzCoreosInstall mutates the installer's args and Ignition config. Args
+// are NUL separated, see register.FuzzInstall. It needs the same host as
+// the install tests, e.g.
+//
+//	go test -run '^$' -fuzz FuzzCoreosInstall -fuzztime 1h
+func FuzzCoreosInstall(f *testing.F) {
+	config := []byte(`{"ignition": {"version": "2.1.0"}}`)
+	f.Add("-d\x00$DEVICE", []byte(nil))
+	f.Add("-d\x00$DEVICE\x00-i\x00$IGNITION", config)
+	f.Add("-i\x00$IGNITION\x00-d\x00$DEVICE\x00-i\x00$IGNITION", config)
+	f.Add("-d\x00$DEVICE\x00-c\x00$IGNITION", []byte("#cloud-config\n"))
+	f.Add("-V\x00current\x00-C\x00beta\x00-d\x00$DEVICE", []byte(nil))
+	f.Add("-d\x00$DEVICE\x00-o\x00\x00-Z", []byte(nil))
+
+	f.Fuzz(func(t *testing.T, args string, ignition []byte) {
+		register.Test{
+			Name: "fuzz",
+			Func: func(t *testing.T, test register.Test) {
+				test.FuzzInstall(t, register.FuzzArgs(args), ignition)
+			},
+		}.Run(t)
+	})
+}
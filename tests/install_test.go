@@ -15,22 +15,33 @@
 package tests
 
 import (
+	"fmt"
 	"os"
 	"testing"
 
 	"github.com/coreos/init/tests/register"
+	"github.com/coreos/init/tests/util"
 
 	_ "github.com/coreos/init/tests/registry"
 )
 
 func TestMain(m *testing.M) {
+	// probed once up front, tests needing what's missing skip with why
+	fmt.Printf("host:\n%s", util.Host())
 	os.Exit(m.Run())
 }
 
 func TestCoreosInstall(t *testing.T) {
-	for _, test := range register.Tests {
+	for _, test := range register.Tests() {
 		t.Run(test.Name, func(t *testing.T) {
 			test.Run(t)
 		})
 	}
 }
+
+// TestExistingImage validates an install made by another tool, e.g.
+//
+//	go test -run TestExistingImage -validate-image /dev/sdb
+func TestExistingImage(t *testing.T) {
+	register.ExistingImage.Run(t)
+}
@@ -0,0 +1,315 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package installer drives a coreos-install against a disk image and checks
+// the result, for programs that want to test installs without go test:
+//
+//	result, err := installer.Run(ctx, installer.Options{
+//		FixtureDir: "/srv/mirror",
+//		Ignition:   config,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	if !result.OK() {
+//		return fmt.Errorf("bad install: %v", result.Problems)
+//	}
+//
+// It needs the same privileges as the suite, root to attach loop devices
+// and mount partitions.
+package installer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/coreos/init/tests/util"
+)
+
+type Options struct {
+	// coreos-install to run, defaults to the one in PATH
+	Binary string
+
+	// disk image to install to, a sparse one of DiskSize is created and
+	// removed afterwards if empty
+	DiskFile string
+	// defaults to 10GiB
+	DiskSize int64
+
+	// installer options, -d is added
+	Args []string
+	// added to the installer's environment
+	Env []string
+
+	// serve this local release mirror and install from it with -b
+	FixtureDir string
+	// the board to install from the mirror, defaults to amd64-usr
+	Board string
+
+	// configs to install and check, "" for none
+	Ignition    string
+	CloudConfig string
+
+	// defaults to 30 minutes
+	Timeout time.Duration
+
+	// the installer's output is copied here as it runs
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+type Result struct {
+	ExitCode int
+	Stdout   []byte
+	Stderr   []byte
+	Duration time.Duration
+	// how long checking the install took
+	ValidateDuration time.Duration
+
+	// the partition table the install left
+	Partitions []util.GPTPartition
+
+	// what didn't match a good install, empty if the install was good
+	Problems []string
+}
+
+func (r Result) OK() bool {
+	return r.ExitCode == 0 && len(r.Problems) == 0
+}
+
+// partitions every install must have
+var requiredLabels = []string{"EFI-SYSTEM", "USR-A", "OEM", "ROOT"}
+
+const (
+	ignitionPath     = "coreos-install.json"
+	ignitionGrubLine = "coreos.config.url=oem:///coreos-install.json"
+	cloudinitPath    = "var/lib/coreos-install/user_data"
+)
+
+// Run installs to a disk image and checks it. The error is for installs
+// that couldn't be run or exited nonzero, what's wrong with an install that
+// succeeded is in Result.Problems.
+func Run(ctx context.Context, opts Options) (Result, error) {
+	var result Result
+
+	temp := util.NewTempManager()
+	defer temp.Close()
+
+	if opts.Timeout == 0 {
+		opts.Timeout = 30 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	diskFile, err := createDisk(temp, opts)
+	if err != nil {
+		return result, err
+	}
+
+	loop, err := util.AttachLoop(diskFile, util.LoopPartscan)
+	if err != nil {
+		return result, fmt.Errorf("couldn't attach %s: %v", diskFile, err)
+	}
+	defer util.DetachLoop(loop)
+
+	args := append([]string{"-d", loop}, opts.Args...)
+	for _, config := range []struct {
+		flag, contents string
+	}{{"-i", opts.Ignition}, {"-c", opts.CloudConfig}} {
+		if config.contents == "" {
+			continue
+		}
+
+		f, err := temp.File("coreos-install-config")
+		if err != nil {
+			return result, err
+		}
+		_, err = f.WriteString(config.contents)
+		f.Close()
+		if err != nil {
+			return result, err
+		}
+		args = append(args, config.flag, f.Name())
+	}
+
+	if opts.FixtureDir != "" {
+		url, stop, err := serveFixture(opts.FixtureDir)
+		if err != nil {
+			return result, err
+		}
+		defer stop()
+
+		board := opts.Board
+		if board == "" {
+			board = "amd64-usr"
+		}
+		args = append(args, "-b", url+"/"+board)
+	}
+
+	binary := opts.Binary
+	if binary == "" {
+		binary = "coreos-install"
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := util.Command(ctx, binary, args...)
+	cmd.Env = append(os.Environ(), opts.Env...)
+	cmd.Stdout = tee(&stdout, opts.Stdout)
+	cmd.Stderr = tee(&stderr, opts.Stderr)
+
+	start := time.Now()
+	err = cmd.Run()
+	util.Reap(cmd)
+	result.Duration = time.Since(start)
+	result.Stdout, result.Stderr = stdout.Bytes(), stderr.Bytes()
+
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		result.ExitCode = exitErr.ExitCode()
+		return result, fmt.Errorf("%s failed with exit code %d", util.FormatCommand(binary, args...), result.ExitCode)
+	} else if err != nil {
+		return result, fmt.Errorf("couldn't run %s: %v", binary, err)
+	}
+
+	start = time.Now()
+	result.Problems = validate(temp, opts, diskFile, loop, &result)
+	result.ValidateDuration = time.Since(start)
+	return result, nil
+}
+
+func createDisk(temp *util.TempManager, opts Options) (string, error) {
+	if opts.DiskFile != "" {
+		return opts.DiskFile, nil
+	}
+
+	size := opts.DiskSize
+	if size == 0 {
+		size = 10 * 1024 * 1024 * 1024
+	}
+
+	f, err := temp.File("coreos-install-disk")
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	return f.Name(), os.Truncate(f.Name(), size)
+}
+
+func tee(buf *bytes.Buffer, w io.Writer) io.Writer {
+	if w == nil {
+		return buf
+	}
+	return io.MultiWriter(buf, w)
+}
+
+// serveFixture serves a release mirror on localhost
+func serveFixture(dir string) (string, func(), error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, err
+	}
+
+	server := &http.Server{Handler: http.FileServer(http.Dir(dir))}
+	go server.Serve(listener)
+	return "http://" + listener.Addr().String(), func() { server.Close() }, nil
+}
+
+func validate(temp *util.TempManager, opts Options, diskFile, loop string, result *Result) (problems []string) {
+	gpt, err := util.ReadGPT(diskFile)
+	if err != nil {
+		return []string{fmt.Sprintf("couldn't read the partition table: %v", err)}
+	}
+	result.Partitions = gpt.Partitions
+
+	byLabel := map[string]util.GPTPartition{}
+	for _, p := range gpt.Partitions {
+		byLabel[p.Name] = p
+	}
+	for _, label := range requiredLabels {
+		if _, ok := byLabel[label]; !ok {
+			problems = append(problems, fmt.Sprintf("no %s partition", label))
+		}
+	}
+
+	if opts.Ignition != "" {
+		problems = append(problems, checkFiles(temp, loop, byLabel["OEM"], func(root string) []string {
+			return append(
+				checkFile(root, ignitionPath, []byte(opts.Ignition), util.JSONEqual),
+				checkContains(root, "grub.cfg", ignitionGrubLine)...)
+		})...)
+	}
+
+	if opts.CloudConfig != "" {
+		problems = append(problems, checkFiles(temp, loop, byLabel["ROOT"], func(root string) []string {
+			return checkFile(root, cloudinitPath, []byte(opts.CloudConfig), nil)
+		})...)
+	}
+	return problems
+}
+
+// checkFiles mounts a partition read-only and runs check on it
+func checkFiles(temp *util.TempManager, loop string, p util.GPTPartition, check func(root string) []string) []string {
+	if p.Number == 0 {
+		return nil
+	}
+
+	dir, err := temp.MountPoint("coreos-install-check")
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	device := fmt.Sprintf("%sp%d", loop, p.Number)
+	if err := util.Mount(device, dir, "", syscall.MS_RDONLY, ""); err != nil {
+		return []string{fmt.Sprintf("couldn't mount %s: %v", p.Name, err)}
+	}
+	defer util.Unmount(dir, true)
+
+	return check(dir)
+}
+
+func checkFile(root, path string, expected []byte, compare func(expected, actual []byte) error) []string {
+	actual, err := ioutil.ReadFile(filepath.Join(root, path))
+	if err != nil {
+		return []string{fmt.Sprintf("couldn't read %s: %v", path, err)}
+	}
+
+	if compare != nil {
+		if err := compare(expected, actual); err != nil {
+			return []string{fmt.Sprintf("%s doesn't match: %v", path, err)}
+		}
+	} else if !bytes.Equal(expected, actual) {
+		return []string{fmt.Sprintf("%s doesn't match", path)}
+	}
+	return nil
+}
+
+func checkContains(root, path, substr string) []string {
+	data, err := ioutil.ReadFile(filepath.Join(root, path))
+	if err != nil {
+		return []string{fmt.Sprintf("couldn't read %s: %v", path, err)}
+	}
+	if !bytes.Contains(data, []byte(substr)) {
+		return []string{fmt.Sprintf("%s doesn't contain %q", path, substr)}
+	}
+	return nil
+}
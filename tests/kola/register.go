@@ -0,0 +1,79 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build kola
+// +build kola
+
+package kola
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/coreos/mantle/kola/cluster"
+	kolaregister "github.com/coreos/mantle/kola/register"
+
+	"github.com/coreos/init/tests/register"
+	_ "github.com/coreos/init/tests/registry"
+)
+
+// SuiteEnv is the suite binary kola runs the tests from
+const SuiteEnv = "COREOS_INSTALL_SUITE"
+
+var unsafeName = regexp.MustCompile(`[^a-z0-9]+`)
+
+// KolaName is the kola name of a registered test, e.g.
+// coreos.install.hermetic-install-from-a-local-mirror
+func KolaName(name string) string {
+	return "coreos.install." + strings.Trim(unsafeName.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+func init() {
+	for _, test := range register.Tests() {
+		name := test.Name
+		kolaregister.Register(&kolaregister.Test{
+			Name: KolaName(name),
+			// the suite makes its own loop devices and VMs on the kola
+			// host, it doesn't need machines from kola
+			ClusterSize: 0,
+			Platforms:   []string{"qemu"},
+			Run: func(c cluster.TestCluster) {
+				run(c, name)
+			},
+		})
+	}
+}
+
+func run(c cluster.TestCluster, name string) {
+	binary := os.Getenv(SuiteEnv)
+	if binary == "" {
+		c.Skipf("%s isn't set to a suite built with go test -c", SuiteEnv)
+	}
+
+	outcome, err := Suite{Binary: binary}.Run(context.Background(), name)
+	if err != nil {
+		c.Fatal(err)
+	}
+
+	switch outcome.Status {
+	case Pass:
+		c.Logf("%s", outcome.Output)
+	case Skip:
+		c.Skipf("%s", outcome.Output)
+	default:
+		c.Fatalf("%s", outcome.Output)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kola runs the registered coreos-install tests from mantle's kola
+// harness, so they're reported alongside the rest of Container Linux CI.
+// The kola build tag registers them, this file runs them from a suite
+// binary built with go test -c and is usable without mantle.
+package kola
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/coreos/init/tests/util"
+)
+
+// the go test function every registered test runs under
+const suiteTest = "TestCoreosInstall"
+
+type Status string
+
+const (
+	Pass Status = "PASS"
+	Fail Status = "FAIL"
+	Skip Status = "SKIP"
+)
+
+type Outcome struct {
+	Status Status
+	// the test's -test.v output
+	Output []byte
+}
+
+// Suite is the suite binary, built with go test -c
+type Suite struct {
+	Binary string
+	// extra flags for the suite, e.g. -fixture-dir
+	Args []string
+}
+
+// SubtestName is what go test calls a registered test on the command line
+// and in its output
+func SubtestName(name string) string {
+	return strings.Replace(name, " ", "_", -1)
+}
+
+// Run runs one registered test by name and reports how it finished
+func (s Suite) Run(ctx context.Context, name string) (Outcome, error) {
+	subtest := SubtestName(name)
+	args := append([]string{
+		"-test.run", fmt.Sprintf("^%s$/^%s$", suiteTest, regexp.QuoteMeta(subtest)),
+		"-test.v",
+		"-test.count=1",
+	}, s.Args...)
+
+	result, err := util.Exec(ctx, s.Binary, args...)
+	if err != nil {
+		return Outcome{}, err
+	}
+
+	outcome := Outcome{Output: append(result.Stdout, result.Stderr...)}
+	status, ok := parseStatus(result.Stdout, suiteTest+"/"+subtest)
+	if !ok {
+		return outcome, fmt.Errorf("%s didn't report a result for %q, exit code %d", s.Binary, name, result.ExitCode)
+	}
+	outcome.Status = status
+	return outcome, nil
+}
+
+var resultLine = regexp.MustCompile(`^\s*--- (PASS|FAIL|SKIP): (\S+) \(`)
+
+// parseStatus finds the result line for a test in -test.v output
+func parseStatus(output []byte, test string) (Status, bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		m := resultLine.FindStringSubmatch(scanner.Text())
+		if m != nil && m[2] == test {
+			return Status(m[1]), true
+		}
+	}
+	return "", false
+}
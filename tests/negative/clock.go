@@ -0,0 +1,100 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package negative
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Skewed clock",
+		Func: clockTest,
+	})
+}
+
+// machines with a dead RTC battery boot years in the past, the installer has
+// to refuse the mirror's certificate and the signing key rather than trust
+// them blindly
+func clockTest(t *testing.T, test register.Test) {
+	const decade = 10 * 365 * 24 * time.Hour
+
+	// installs from the mirror with the clock skewed by skew, over HTTPS
+	// with a certificate valid for an hour either side of now if tls is set
+	install := func(t *testing.T, skew time.Duration, tls bool) (result register.InstallResult, diskFile, loopDevice string, before register.DiskSnapshot) {
+		ns := test.CreateNetNS(t)
+		t.Cleanup(func() { test.CleanupNetNS(t, ns) })
+
+		inv := register.Invocation{NetNS: ns, ClockSkew: skew}
+		var fixture *register.FixtureServer
+		if tls {
+			var trust register.TLSTrust
+			fixture, trust = test.StartTLSFixtureServer(t, ns.HostAddr)
+			inv = trust.Apply(inv)
+		} else {
+			fixture = test.StartFixtureServer(t, ns.HostAddr)
+		}
+		t.Cleanup(fixture.Close)
+
+		board := register.DefaultBoard()
+		version := fixture.CurrentVersion(t, "/"+board)
+
+		diskFile, loopDevice = test.CreateDevice(t)
+		t.Cleanup(func() { test.CleanupDisk(t, diskFile, loopDevice) })
+
+		before = test.SnapshotDisk(t, diskFile)
+		opts := register.InstallOptions{Device: loopDevice, Board: board, Version: version, BaseURL: fixture.BaseURL(board)}
+		return test.TryCoreOSInstallWith(t, inv, opts.Args()...), diskFile, loopDevice, before
+	}
+
+	// a few minutes of drift is normal and has to work, this also shows the
+	// failures below are down to the clock and not the fixture
+	t.Run("TLS, clock a minute ahead", func(t *testing.T) {
+		result, diskFile, loopDevice, _ := install(t, time.Minute, true)
+		if result.TimedOut || result.ExitCode != 0 {
+			t.Fatalf("install failed with %s: %s", register.ExitName(result), result.Stderr)
+		}
+
+		partitions := test.MountPartitions(t, diskFile, loopDevice)
+		defer test.UnmountPartitions(t, loopDevice, partitions)
+		test.DefaultChecks(t, register.MountPaths(partitions), diskFile, result.Args)
+	})
+
+	for _, c := range []struct {
+		name string
+		skew time.Duration
+	}{
+		{"TLS, clock a decade behind", -decade},
+		{"TLS, clock a decade ahead", decade},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			result, diskFile, loopDevice, before := install(t, c.skew, true)
+			test.ValidateFailedInstall(t, result, register.ErrImageUnavailable, diskFile, loopDevice, before)
+		})
+	}
+
+	// the signing key was created after the skewed clock's now, so gpg
+	// can't use it and the image written so far has to be wiped
+	t.Run("HTTP, clock a decade behind", func(t *testing.T) {
+		result, diskFile, _, _ := install(t, -decade, false)
+		test.ValidateInstallerError(t, result, register.ErrVerifyFailed)
+		test.ValidateWiped(t, diskFile)
+		test.ValidateNoInstallerTempFiles(t)
+	})
+}
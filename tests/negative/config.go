@@ -0,0 +1,85 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package negative
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Bad configs",
+		Func: configTest,
+	})
+}
+
+func configTest(t *testing.T, test register.Test) {
+	// coreos-cloudinit is stubbed out so invalid cloud-configs are caught
+	// the same way whether or not the host has it
+	invalidCloudinit := func(t *testing.T) register.Invocation {
+		path := test.ShadowPath(t, nil, register.ToolStub{
+			Name:     "coreos-cloudinit",
+			ExitCode: 1,
+			Stderr:   "invalid cloud-config",
+		})
+		return register.Invocation{Env: map[string]string{"PATH": path}}
+	}
+
+	test.RunFailureCases(t,
+		register.FailureCase{
+			Name: "missing -i file",
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{Device: device, IgnitionPath: "/nonexistent/config.ign"}.Args()
+			},
+			Error: register.ErrMissingIgnition,
+		},
+		register.FailureCase{
+			Name: "-i directory",
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{Device: device, IgnitionPath: test.TempDir(t, "coreos-install-config")}.Args()
+			},
+			Error: register.ErrMissingIgnition,
+		},
+		register.FailureCase{
+			Name: "missing -c file",
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{Device: device, CloudConfigPath: "/nonexistent/user_data"}.Args()
+			},
+			Error: register.ErrMissingCloudinit,
+		},
+		register.FailureCase{
+			Name: "invalid -c file",
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{Device: device, CloudConfigPath: test.WriteFile(t, "#cloud-config\nnot: [valid\n")}.Args()
+			},
+			Invocation: invalidCloudinit,
+			Error:      register.ErrInvalidCloudinit,
+		},
+		register.FailureCase{
+			Name: "-i and invalid -c file",
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{
+					Device:          device,
+					IgnitionPath:    test.WriteFile(t, `{"ignition": {"version": "2.1.0"}}`),
+					CloudConfigPath: test.WriteFile(t, "#cloud-config\nnot: [valid\n"),
+				}.Args()
+			},
+			Invocation: invalidCloudinit,
+			Error:      register.ErrInvalidCloudinit,
+		},
+	)
+}
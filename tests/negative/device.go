@@ -0,0 +1,62 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package negative
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Bad devices",
+		Func: deviceTest,
+	})
+}
+
+func deviceTest(t *testing.T, test register.Test) {
+	test.RunFailureCases(t,
+		register.FailureCase{
+			Name: "nonexistent device",
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{Device: "/dev/coreos-install-nonexistent"}.Args()
+			},
+			Error: register.ErrBadDevice,
+		},
+		register.FailureCase{
+			Name: "regular file",
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{Device: test.WriteFile(t, "")}.Args()
+			},
+			Error: register.ErrBadDevice,
+		},
+		register.FailureCase{
+			Name: "character device",
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{Device: "/dev/null"}.Args()
+			},
+			Error: register.ErrBadDevice,
+		},
+		register.FailureCase{
+			Name: "not writable",
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{Device: device}.Args()
+			},
+			Invocation: test.UnprivilegedInvocation,
+			Error:      register.ErrDeviceNotWritable,
+		},
+	)
+}
@@ -0,0 +1,61 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package negative
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Invalid flags",
+		Func: flagsTest,
+	})
+}
+
+func flagsTest(t *testing.T, test register.Test) {
+	test.RunFailureCases(t,
+		register.FailureCase{
+			Name: "unknown flag",
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{Device: device, Extra: []string{"-Z"}}.Args()
+			},
+			Error: register.ErrBadOption,
+		},
+		register.FailureCase{
+			Name: "-d without a value",
+			Opts: func(t *testing.T, device string) []string {
+				return []string{"-d"}
+			},
+			Error: register.ErrBadOption,
+		},
+		register.FailureCase{
+			Name: "-i without a value",
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{Device: device, Extra: []string{"-i"}}.Args()
+			},
+			Error: register.ErrBadOption,
+		},
+		register.FailureCase{
+			Name: "no -d",
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{Channel: "stable"}.Args()
+			},
+			Error: register.ErrNoDevice,
+		},
+	)
+}
@@ -0,0 +1,159 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package negative
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Interrupted install",
+		Func: interruptTest,
+		// the go port doesn't catch signals, so it can't wipe the disk
+		ScriptOnly: true,
+	})
+}
+
+// ctrl-c or a shutdown can stop the installer in any phase: before it writes
+// the disk it has to leave it alone, after that it has to wipe what it wrote.
+// Each case stalls the install in its phase until the signal lands, so it
+// can't finish first.
+func interruptTest(t *testing.T, test register.Test) {
+	board := register.DefaultBoard()
+	image := register.OEMImageName("")
+
+	for _, c := range []struct {
+		name   string
+		phase  string
+		signal syscall.Signal
+		// stalls the install in the phase, the returned channel is closed
+		// once it's stuck there, nil if it is as soon as the phase starts
+		stall func(t *testing.T, fixture *register.FixtureServer, version string, opts *register.InstallOptions) <-chan struct{}
+		// the disk was written by the time of the signal
+		written bool
+	}{
+		{"version", register.PhaseVersion, syscall.SIGTERM, func(t *testing.T, fixture *register.FixtureServer, version string, opts *register.InstallOptions) <-chan struct{} {
+			opts.Version = ""
+			return fixture.Hold("HEAD", "/"+board+"/"+version+"/"+image, 0)
+		}, false},
+		{"signature", register.PhaseSignature, syscall.SIGTERM, func(t *testing.T, fixture *register.FixtureServer, version string, opts *register.InstallOptions) <-chan struct{} {
+			return fixture.Hold("GET", "/"+board+"/"+version+"/"+image+".sig", 0)
+		}, false},
+		{"download", register.PhaseWrite, syscall.SIGTERM, func(t *testing.T, fixture *register.FixtureServer, version string, opts *register.InstallOptions) <-chan struct{} {
+			return fixture.Hold("GET", "/"+board+"/"+version+"/"+image, -1)
+		}, true},
+		{"download, ctrl-c", register.PhaseWrite, syscall.SIGINT, func(t *testing.T, fixture *register.FixtureServer, version string, opts *register.InstallOptions) <-chan struct{} {
+			return fixture.Hold("GET", "/"+board+"/"+version+"/"+image, -1)
+		}, true},
+		{"image file", register.PhaseFileWrite, syscall.SIGTERM, func(t *testing.T, fixture *register.FixtureServer, version string, opts *register.InstallOptions) <-chan struct{} {
+			fifo := filepath.Join(test.TempDir(t, "coreos-install-image"), image)
+			if err := syscall.Mkfifo(fifo, 0600); err != nil {
+				t.Fatalf("couldn't create fifo: %v", err)
+			}
+			*opts = register.InstallOptions{Device: opts.Device, ImageFile: fifo}
+			return feedImage(t, fixture.ImagePath("/"+board, version, image), fifo)
+		}, true},
+		{"postprocess", register.PhaseIgnition, syscall.SIGTERM, func(t *testing.T, fixture *register.FixtureServer, version string, opts *register.InstallOptions) <-chan struct{} {
+			// the config has to be a regular file when the options are
+			// checked, by the time the image is downloaded it's swapped
+			// for a fifo that the installer's cp blocks on
+			config := test.WriteFile(t, `{"ignition": {"version": "2.1.0"}}`)
+			opts.IgnitionPath = config
+
+			var once sync.Once
+			fixture.Handle("/"+board+"/"+version+"/"+image, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				once.Do(func() {
+					if err := os.Remove(config); err != nil {
+						t.Errorf("couldn't remove the config: %v", err)
+					} else if err := syscall.Mkfifo(config, 0600); err != nil {
+						t.Errorf("couldn't replace the config with a fifo: %v", err)
+					}
+				})
+				http.ServeFile(w, r, fixture.ImagePath("/"+board, version, image))
+			}))
+			return nil
+		}, true},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			fixture := test.StartFixtureServer(t, "127.0.0.1")
+			defer fixture.Close()
+			version := fixture.CurrentVersion(t, "/"+board)
+			fixture.RequireImage(t, board, version, image)
+
+			diskFile, loopDevice := test.CreateDevice(t)
+			defer test.CleanupDisk(t, diskFile, loopDevice)
+
+			opts := register.InstallOptions{Device: loopDevice, Version: version, BaseURL: fixture.BaseURL(board)}
+			ready := c.stall(t, fixture, version, &opts)
+
+			before := test.SnapshotDisk(t, diskFile)
+			result := test.TryCoreOSInstallWith(t, register.Invocation{
+				Interrupt: &register.Interrupt{Pattern: c.phase, Signal: c.signal, Ready: ready},
+			}, opts.Args()...)
+			if !result.Interrupted {
+				t.Fatalf("install ended with %s before %v was sent", register.ExitName(result), c.signal)
+			}
+
+			test.ValidateFailedCleanly(t, result)
+			if c.written {
+				test.ValidateWiped(t, diskFile)
+			} else {
+				test.ValidateDiskUntouched(t, diskFile, before)
+			}
+		})
+	}
+}
+
+// feedImage writes all but the last byte of image into fifo and keeps it
+// open, so bzip2 waits for the rest. The returned channel is closed once the
+// installer read all but what the pipe buffers.
+func feedImage(t *testing.T, image, fifo string) <-chan struct{} {
+	src, err := os.Open(image)
+	if err != nil {
+		t.Fatalf("couldn't open image: %v", err)
+	}
+	info, err := src.Stat()
+	if err != nil {
+		t.Fatalf("couldn't stat image: %v", err)
+	}
+
+	// opened read-write so it doesn't wait for the installer to open it
+	w, err := os.OpenFile(fifo, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("couldn't open fifo: %v", err)
+	}
+	t.Cleanup(func() {
+		w.Close()
+		src.Close()
+	})
+
+	fed := make(chan struct{})
+	go func() {
+		if _, err := io.CopyN(w, src, info.Size()-1); err == nil {
+			close(fed)
+		}
+	}()
+	return fed
+}
@@ -0,0 +1,77 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package negative
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "I/O errors at known offsets",
+		Func: ioErrorsTest,
+	})
+}
+
+// the backup GPT header and its partition entries
+const backupGPTSectors = 33
+
+func ioErrorsTest(t *testing.T, test register.Test) {
+	// where the image puts its partitions, from a clean install
+	layout := test.InstalledLayout(t)
+	usr, ok := register.FindPartition(layout, register.USRAPartition.Label)
+	if !ok {
+		t.Fatalf("the image has no %s partition", register.USRAPartition.Label)
+	}
+
+	cases := []struct {
+		name string
+		bad  func(t *testing.T, device string) register.SectorRange
+	}{
+		{
+			name: "primary GPT",
+			bad: func(t *testing.T, device string) register.SectorRange {
+				return register.SectorRange{Start: 1, Length: 1}
+			},
+		},
+		{
+			name: "backup GPT",
+			bad: func(t *testing.T, device string) register.SectorRange {
+				size := register.DeviceSectors(t, device)
+				return register.SectorRange{Start: size - backupGPTSectors, Length: backupGPTSectors}
+			},
+		},
+		{
+			name: "middle of USR-A",
+			bad: func(t *testing.T, device string) register.SectorRange {
+				return register.SectorRange{Start: (usr.FirstSector + usr.LastSector) / 2, Length: 8}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			diskFile, loopDevice := test.CreateDevice(t)
+			device := test.CreateErrorDevice(t, loopDevice, c.bad(t, loopDevice))
+			defer test.CleanupDisk(t, diskFile, device)
+
+			result := test.TryCoreOSInstall(t, register.InstallOptions{Device: device}.Args()...)
+			test.ValidateFailedCleanly(t, result)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package negative
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+	"github.com/coreos/init/tests/util"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name:     "Low memory",
+		Func:     lowMemoryTest,
+		Requires: []util.HostCapability{util.HostCgroupV2},
+	})
+}
+
+// the download, decompress and write pipeline has to either fit or fail,
+// an OOM kill mustn't leave a half written disk behind a zero exit
+func lowMemoryTest(t *testing.T, test register.Test) {
+	for _, limit := range []struct {
+		name  string
+		bytes int64
+	}{
+		{"256MiB", 256 << 20},
+		{"64MiB", 64 << 20},
+		{"16MiB", 16 << 20},
+	} {
+		limit := limit
+		t.Run(limit.name, func(t *testing.T) {
+			diskFile, loopDevice := test.CreateDevice(t)
+			defer test.CleanupDisk(t, diskFile, loopDevice)
+
+			result := test.TryCoreOSInstallWith(t, register.Invocation{MemoryLimit: limit.bytes}, register.InstallOptions{Device: loopDevice}.Args()...)
+			if result.ExitCode != 0 || result.TimedOut || result.Stalled {
+				test.ValidateFailedCleanly(t, result)
+				return
+			}
+
+			if result.OOMKills != 0 {
+				t.Fatalf("install succeeded after the OOM killer killed %d of its processes", result.OOMKills)
+			}
+
+			partitions := test.MountPartitions(t, diskFile, loopDevice)
+			defer test.UnmountPartitions(t, loopDevice, partitions)
+			test.DefaultChecks(t, register.MountPaths(partitions), diskFile, result.Args)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package negative
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Storage faults",
+		Func: storageTest,
+	})
+}
+
+func storageTest(t *testing.T, test register.Test) {
+	t.Run("writes fail", func(t *testing.T) {
+		diskFile, loopDevice := test.CreateDevice(t)
+		device := test.CreateFlakeyDevice(t, loopDevice, 0, time.Hour, register.FlakeyErrorWrites)
+		defer test.CleanupDisk(t, diskFile, device)
+
+		result := test.TryCoreOSInstall(t, register.InstallOptions{Device: device}.Args()...)
+		test.ValidateFailedCleanly(t, result)
+	})
+
+	// the disk comes and goes, the install may get lucky but it must never
+	// succeed with a broken result
+	t.Run("intermittent errors", func(t *testing.T) {
+		diskFile, loopDevice := test.CreateDevice(t)
+		device := test.CreateFlakeyDevice(t, loopDevice, 5*time.Second, time.Second, register.FlakeyErrorAll)
+		defer test.CleanupDisk(t, diskFile, device)
+
+		result := test.TryCoreOSInstall(t, register.InstallOptions{Device: device}.Args()...)
+		if result.ExitCode != 0 || result.TimedOut || result.Stalled {
+			test.ValidateFailedCleanly(t, result)
+			return
+		}
+
+		partitions := test.MountPartitions(t, diskFile, device)
+		defer test.UnmountPartitions(t, device, partitions)
+		test.DefaultChecks(t, register.MountPaths(partitions), diskFile, result.Args)
+	})
+
+	// a disk that stops responding can't be recovered from, the install has
+	// to be stopped by its timeout rather than hang the suite
+	t.Run("unresponsive disk", func(t *testing.T) {
+		diskFile, loopDevice := test.CreateDevice(t)
+		device := test.CreateDelayedDevice(t, loopDevice, 30*time.Second)
+		defer test.CleanupDisk(t, diskFile, device)
+
+		result := test.TryCoreOSInstallWith(t, register.Invocation{Timeout: 2 * time.Minute}, register.InstallOptions{Device: device}.Args()...)
+		if !result.TimedOut && result.ExitCode == 0 {
+			t.Fatalf("install succeeded on a disk that takes 30s per I/O")
+		}
+		t.Logf("install ended with %s", register.ExitName(result))
+	})
+}
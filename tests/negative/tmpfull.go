@@ -0,0 +1,54 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package negative
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Full TMPDIR",
+		Func: fullTempDirTest,
+	})
+}
+
+// the signature and keyring go in TMPDIR, running out of space there has to
+// stop the install before it touches the disk
+func fullTempDirTest(t *testing.T, test register.Test) {
+	for _, c := range []struct {
+		name string
+		free int64
+	}{
+		{"no space", 0},
+		{"one page", 4096},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			tmpDir := test.CreateFullTempDir(t, c.free)
+			defer test.CleanupFullTempDir(t, tmpDir)
+
+			diskFile, loopDevice := test.CreateDevice(t)
+			defer test.CleanupDisk(t, diskFile, loopDevice)
+
+			before := test.SnapshotDisk(t, diskFile)
+			result := test.TryCoreOSInstallWith(t, tmpDir.Apply(register.Invocation{}), register.InstallOptions{Device: loopDevice}.Args()...)
+			test.ValidateFailedInstall(t, result, register.ErrNoSpace, diskFile, loopDevice, before)
+			tmpDir.ValidateNoTempFiles(t)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Board architectures",
+		Func: archTest,
+	})
+}
+
+// installs each board's image from the mirror, whatever the host is, and
+// checks the binaries match the board that was asked for
+func archTest(t *testing.T, test register.Test) {
+	var boards []string
+	for board := range register.BoardMachines {
+		boards = append(boards, board)
+	}
+	sort.Strings(boards)
+
+	for _, board := range boards {
+		board := board
+		t.Run(board, func(t *testing.T) {
+			diskFile, loopDevice := test.CreateDevice(t)
+			defer test.CleanupDisk(t, diskFile, loopDevice)
+
+			test.RunCoreOSInstallHermeticWith(t, register.InstallOptions{Device: loopDevice, Board: board}, register.Hermetic{
+				Setup: func(t *testing.T, fixture *register.FixtureServer, opts *register.InstallOptions) {
+					opts.Version = fixture.CurrentVersion(t, "/"+board)
+				},
+			})
+
+			partitions := test.MountPartitions(t, diskFile, loopDevice)
+			defer test.UnmountPartitions(t, loopDevice, partitions)
+
+			v := test.NewValidations(t)
+			// DefaultChecks expects the host's board, so only the layout is
+			// checked on top of the board's release and binaries
+			v.Run("root partition", func(t *testing.T) {
+				test.ValidateDefaultRootPartition(t, diskFile)
+			})
+			v.Run("usr-a partition", func(t *testing.T) {
+				test.ValidateDefaultUSRAPartition(t, diskFile)
+			})
+			v.Run("release", func(t *testing.T) {
+				test.ValidateRelease(t, register.MountPaths(partitions), "", board)
+			})
+			v.Run("architecture", func(t *testing.T) {
+				test.ValidateArchitecture(t, partitions, board)
+			})
+			v.Finish()
+		})
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+	"github.com/coreos/init/tests/util"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Boot the installed system",
+		Func: bootTest,
+	})
+	register.Register(register.Test{
+		Name: "Grow ROOT on first boot",
+		Func: firstBootResizeTest,
+	})
+}
+
+func bootTest(t *testing.T, test register.Test) {
+	test.RequireBoot(t)
+	key := test.GenerateSSHKey(t)
+
+	diskFile, loopDevice := test.CreateDevice(t)
+	defer test.CleanupDisk(t, diskFile, loopDevice)
+
+	check := register.NewBootCheck()
+	check.SSHKeys = []string{key.Public}
+	opts := register.InstallOptions{
+		Device:       loopDevice,
+		IgnitionPath: test.WriteFile(t, check.Ignition()),
+	}
+
+	test.RunCoreOSInstall(t, opts.Args()...)
+
+	test.RunFirmwareMatrix(t, func(t *testing.T, firmware register.Firmware) {
+		if firmware == register.UEFISecureBoot {
+			test.ValidateSecureBootDisk(t, diskFile, loopDevice)
+		}
+
+		vm := test.BootDisk(t, diskFile, register.BootOptions{Firmware: firmware})
+		defer test.ShutdownVM(t, vm)
+
+		test.ValidateBoot(t, vm, check)
+
+		ssh := test.WaitSSH(t, vm, key)
+		test.ValidateSystemRunning(t, ssh)
+		test.ValidateRemoteFile(t, ssh, check.Path, check.Contents)
+	})
+}
+
+// the image leaves ROOT small, the installed system has to grow it and its
+// filesystem to fill the disk on first boot
+func firstBootResizeTest(t *testing.T, test register.Test) {
+	test.RequireBoot(t)
+
+	diskFile, loopDevice := test.CreateDevice(t)
+	defer test.CleanupDisk(t, diskFile, loopDevice)
+
+	check := register.NewBootCheck()
+	test.RunCoreOSInstall(t, register.InstallOptions{
+		Device:       loopDevice,
+		IgnitionPath: test.WriteFile(t, check.Ignition()),
+	}.Args()...)
+
+	vm := test.BootDisk(t, diskFile, register.BootOptions{Writable: true})
+	defer test.ShutdownVM(t, vm)
+	test.ValidateBoot(t, vm, check)
+	test.ShutdownVM(t, vm)
+
+	// the loop device may still cache what the installer wrote
+	util.MustRun(t, "blockdev", "--flushbufs", loopDevice)
+
+	partitions := test.MountPartitions(t, diskFile, loopDevice)
+	defer test.UnmountPartitions(t, loopDevice, partitions)
+
+	test.ValidateRootResize(t, diskFile, partitions, true)
+}
@@ -0,0 +1,139 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Channel and version selection",
+		Func: channelsTest,
+	})
+}
+
+// opts returns the mirror to install from, a base URL path like
+// /beta/amd64-usr, and the flags to pass. version is what should get
+// installed, which the installer has to resolve from the mirror's
+// version.txt if resolved is set and is otherwise passed to -V.
+type channelCase struct {
+	name     string
+	opts     func(t *testing.T, fixture *register.FixtureServer, board string) (base string, opts register.InstallOptions)
+	version  func(t *testing.T, fixture *register.FixtureServer, base string) string
+	resolved bool
+}
+
+func channelsTest(t *testing.T, test register.Test) {
+	current := func(t *testing.T, fixture *register.FixtureServer, base string) string {
+		return fixture.CurrentVersion(t, base)
+	}
+	oldest := func(t *testing.T, fixture *register.FixtureServer, base string) string {
+		versions := fixture.Versions(t, base)
+		if len(versions) == 0 {
+			t.Skipf("the fixture mirror doesn't have any versions at %s", base)
+		}
+		return versions[0]
+	}
+
+	var cases []channelCase
+	for _, channel := range register.Channels[1:] {
+		channel := channel
+		cases = append(cases, channelCase{
+			name: "-C " + channel,
+			opts: func(t *testing.T, fixture *register.FixtureServer, board string) (string, register.InstallOptions) {
+				url := fixture.ChannelBaseURL(t, channel, board)
+				return strings.TrimPrefix(url, fixture.URL), register.InstallOptions{Channel: channel, BaseURL: url}
+			},
+			version:  current,
+			resolved: true,
+		}, channelCase{
+			// old installers only took channels as versions
+			name: "-V " + channel,
+			opts: func(t *testing.T, fixture *register.FixtureServer, board string) (string, register.InstallOptions) {
+				url := fixture.ChannelBaseURL(t, channel, board)
+				return strings.TrimPrefix(url, fixture.URL), register.InstallOptions{Version: channel, BaseURL: url}
+			},
+			version:  current,
+			resolved: true,
+		})
+	}
+
+	cases = append(cases, channelCase{
+		name: "-V current",
+		opts: func(t *testing.T, fixture *register.FixtureServer, board string) (string, register.InstallOptions) {
+			return "/" + board, register.InstallOptions{Version: "current"}
+		},
+		version:  current,
+		resolved: true,
+	}, channelCase{
+		name: "explicit -V",
+		opts: func(t *testing.T, fixture *register.FixtureServer, board string) (string, register.InstallOptions) {
+			return "/" + board, register.InstallOptions{}
+		},
+		version: oldest,
+	}, channelCase{
+		name: "explicit -V wins over -C",
+		opts: func(t *testing.T, fixture *register.FixtureServer, board string) (string, register.InstallOptions) {
+			return "/" + board, register.InstallOptions{Channel: "beta"}
+		},
+		version: oldest,
+	})
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			diskFile, loopDevice := test.CreateDevice(t)
+			defer test.CleanupDisk(t, diskFile, loopDevice)
+
+			var base, version string
+			board := register.DefaultBoard()
+			result := test.RunCoreOSInstallHermeticWith(t, register.InstallOptions{Device: loopDevice, Board: board}, register.Hermetic{
+				Setup: func(t *testing.T, fixture *register.FixtureServer, opts *register.InstallOptions) {
+					var o register.InstallOptions
+					base, o = c.opts(t, fixture, board)
+					version = c.version(t, fixture, base)
+
+					opts.Channel = o.Channel
+					opts.Version = o.Version
+					if !c.resolved {
+						opts.Version = version
+					}
+					if o.BaseURL != "" {
+						opts.BaseURL = o.BaseURL
+					}
+				},
+				Check: func(t *testing.T, fixture *register.FixtureServer, result register.InstallResult) {
+					fixture.ValidateDownloads(t, base, version, c.resolved)
+				},
+			})
+
+			partitions := test.MountPartitions(t, diskFile, loopDevice)
+			defer test.UnmountPartitions(t, loopDevice, partitions)
+
+			v := test.NewValidations(t)
+			v.Run("default", func(t *testing.T) {
+				test.DefaultChecks(t, register.MountPaths(partitions), diskFile, result.Args)
+			})
+			v.Run("release", func(t *testing.T) {
+				test.ValidateRelease(t, register.MountPaths(partitions), version, board)
+			})
+			v.Finish()
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+	"github.com/coreos/init/tests/util"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Concurrent installs",
+		Func: concurrentTest,
+		// each install gets its own loop device and partition mappings
+		Requires: []util.HostCapability{util.HostLoopDevices, util.HostKpartx},
+	})
+}
+
+func concurrentTest(t *testing.T, test register.Test) {
+	type target struct {
+		diskFile, loopDevice, config string
+	}
+
+	var targets []target
+	var installs []register.ConcurrentInstall
+	for i := 0; i < 2; i++ {
+		diskFile, loopDevice := test.CreateDevice(t)
+		defer test.CleanupDisk(t, diskFile, loopDevice)
+
+		// different configs so a collision between the installs shows up
+		// as the wrong config on a disk
+		config := fmt.Sprintf(`{
+		"ignition": {
+			"version": "2.1.0"
+		},
+		"storage": {
+			"files": [{
+				"filesystem": "root",
+				"path": "/etc/coreos-install-test",
+				"contents": { "source": "data:,%d" }
+			}]
+		}
+	}`, i)
+		ignition := test.WriteFile(t, config)
+
+		targets = append(targets, target{diskFile, loopDevice, config})
+		installs = append(installs, register.ConcurrentInstall{
+			Opts: register.InstallOptions{Device: loopDevice, IgnitionPath: ignition}.Args(),
+		})
+	}
+
+	results := test.RunConcurrentInstalls(t, installs...)
+
+	v := test.NewValidations(t)
+	for i, target := range targets {
+		partitions := test.MountPartitions(t, target.diskFile, target.loopDevice)
+		defer test.UnmountPartitions(t, target.loopDevice, partitions)
+
+		v.Run(fmt.Sprintf("disk %d", i), func(t *testing.T) {
+			test.DefaultChecks(t, register.MountPaths(partitions), target.diskFile, results[i].Args)
+			test.ValidateIgnition(t, partitions, target.config)
+		})
+	}
+	v.Finish()
+}
@@ -0,0 +1,65 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+	"github.com/coreos/init/tests/util"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Install from a container",
+		Func: containerTest,
+		// nested containers need the host's cgroups delegated, which
+		// most CI containers don't do
+		Requires: []util.HostCapability{util.HostNotInContainer},
+	})
+}
+
+func containerTest(t *testing.T, test register.Test) {
+	ignition_config := `{
+		"ignition": {
+			"version": "2.1.0"
+		}
+	}`
+	ignition := test.WriteFile(t, ignition_config)
+
+	container := test.ContainerFromFlags(t, ignition)
+
+	diskFile, loopDevice := test.CreateDevice(t)
+	defer test.CleanupDisk(t, diskFile, loopDevice)
+
+	opts := register.InstallOptions{
+		Device:       loopDevice,
+		IgnitionPath: ignition,
+	}
+
+	test.RunCoreOSInstallWith(t, register.Invocation{Container: container}, opts.Args()...)
+
+	partitions := test.MountPartitions(t, diskFile, loopDevice)
+	defer test.UnmountPartitions(t, loopDevice, partitions)
+
+	v := test.NewValidations(t)
+	v.Run("default", func(t *testing.T) {
+		test.DefaultChecks(t, register.MountPaths(partitions), diskFile, opts.Args())
+	})
+	v.Run("ignition", func(t *testing.T) {
+		test.ValidateIgnition(t, partitions, ignition_config)
+	})
+	v.Finish()
+}
@@ -0,0 +1,86 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Label collision",
+		Func: decoyTest,
+	})
+}
+
+// the installer finds ROOT and OEM by label to copy the configs, another
+// disk with the same labels mustn't be mistaken for the one installed to
+func decoyTest(t *testing.T, test register.Test) {
+	t.Run("other disk", func(t *testing.T) {
+		decoyScenario(t, test, false)
+	})
+	t.Run("prefixed device name", func(t *testing.T) {
+		decoyScenario(t, test, true)
+	})
+	t.Run("first boot", func(t *testing.T) {
+		decoyBootTest(t, test)
+	})
+}
+
+func decoyScenario(t *testing.T, test register.Test, prefixed bool) {
+	var decoy register.DecoyDisk
+	test.NewScenario().
+		WithDisk(func(t *testing.T, disk register.ScenarioDisk) {
+			near := ""
+			if prefixed {
+				near = disk.LoopDevice
+			}
+			decoy = test.CreateDecoyDisk(t, near)
+			// outlives the hook, removed once the scenario finished
+			t.Cleanup(func() { test.CleanupDecoyDisk(t, decoy) })
+		}).
+		WithIgnition(combinedIgnitionConfig).
+		WithCloudConfig(combinedCloudConfig).
+		ExpectIgnition(combinedIgnitionConfig).
+		ExpectCloudinit(combinedCloudConfig).
+		Expect("decoy", func(t *testing.T, disk register.ScenarioDisk) {
+			register.ValidatePartitionsOn(t, disk.Partitions, disk.LoopDevice)
+			test.ValidateDecoyUntouched(t, decoy)
+		}).
+		Run(t)
+}
+
+// root=LABEL=ROOT and Ignition's OEM lookup have to find the booted disk
+// with the decoy attached
+func decoyBootTest(t *testing.T, test register.Test) {
+	test.RequireBoot(t)
+
+	diskFile, loopDevice := test.CreateDevice(t)
+	defer test.CleanupDisk(t, diskFile, loopDevice)
+	decoy := test.CreateDecoyDisk(t, "")
+	defer test.CleanupDecoyDisk(t, decoy)
+
+	check := register.NewBootCheck()
+	test.RunCoreOSInstall(t, register.InstallOptions{
+		Device:       loopDevice,
+		IgnitionPath: test.WriteFile(t, check.Ignition()),
+	}.Args()...)
+
+	vm := test.BootDisk(t, diskFile, register.BootOptions{ExtraDisks: []string{decoy.DiskFile}})
+	defer test.ShutdownVM(t, vm)
+	test.ValidateBoot(t, vm, check)
+}
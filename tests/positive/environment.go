@@ -0,0 +1,48 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+	"github.com/coreos/init/tests/util"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Unusual environments",
+		Func: environmentTest,
+	})
+}
+
+func environmentTest(t *testing.T, test register.Test) {
+	ignition := test.WriteFile(t, secondIgnitionConfig)
+
+	cases := util.Map(register.EnvironmentVariants, func(variant register.InvocationVariant) register.OptionCase {
+		return register.OptionCase{
+			Name: variant.Name,
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{Device: device, IgnitionPath: ignition}.Args()
+			},
+			Invocation: variant.Invocation,
+			Validate: func(t *testing.T, diskFile string, partitions []register.Partition) {
+				test.ValidateIgnition(t, partitions, secondIgnitionConfig)
+			},
+		}
+	})
+
+	test.RunOptionCases(t, cases...)
+}
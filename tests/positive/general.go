@@ -31,33 +31,41 @@ func baseTest(t *testing.T, test register.Test) {
 	diskFile, loopDevice := test.CreateDevice(t)
 	defer test.CleanupDisk(t, diskFile, loopDevice)
 
+	wipedTail := test.WipedTail(t, diskFile)
+	test.FillRanges(t, diskFile, wipedTail)
+
 	ignition_config := `{
 		"ignition": {
 			"version": "2.1.0"
 		}
 	}`
 	ignition := test.WriteFile(t, ignition_config)
-	defer test.RemoveAll(t, ignition)
-
-	opts := []string{
-		"-d", loopDevice,
-		"-i", ignition}
 
-	test.RunCoreOSInstall(t, opts...)
+	opts := register.InstallOptions{
+		Device:       loopDevice,
+		IgnitionPath: ignition,
+	}
 
-	devices := test.CreateDeviceMappers(t, loopDevice)
-	defer test.RemoveDeviceMappers(t, loopDevice)
+	test.RunCoreOSInstall(t, opts.Args()...)
 
-	var mountPaths []string
-	for _, device := range devices {
-		path := test.MountDeviceMapper(t, device)
-		if path != "" {
-			mountPaths = append(mountPaths, path)
-			defer test.RemoveAll(t, path)
-			defer test.UnmountPath(t, path)
-		}
-	}
+	partitions := test.MountPartitions(t, diskFile, loopDevice)
+	defer test.UnmountPartitions(t, loopDevice, partitions)
 
-	test.DefaultChecks(t, mountPaths, diskFile)
-	test.ValidateIgnition(t, mountPaths, ignition_config)
+	v := test.NewValidations(t)
+	v.Run("default", func(t *testing.T) {
+		test.DefaultChecks(t, register.MountPaths(partitions), diskFile, opts.Args())
+	})
+	v.Run("root resize", func(t *testing.T) {
+		test.ValidateRootResize(t, diskFile, partitions, false)
+	})
+	v.Run("wiped tail", func(t *testing.T) {
+		test.ValidateZeroed(t, diskFile, wipedTail)
+	})
+	v.Run("ignition", func(t *testing.T) {
+		test.ValidateIgnition(t, partitions, ignition_config)
+	})
+	v.Run("no cloudinit", func(t *testing.T) {
+		test.AssertNoCloudinit(t, partitions)
+	})
+	v.Finish()
 }
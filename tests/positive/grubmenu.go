@@ -0,0 +1,40 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "GRUB menu",
+		Func: grubMenuTest,
+	})
+}
+
+// -i is passed to the kernel through the OEM grub.cfg, which every entry
+// must pick up, including the ones to fall back to by hand
+func grubMenuTest(t *testing.T, test register.Test) {
+	test.NewScenario().
+		WithIgnition(combinedIgnitionConfig).
+		ExpectGrubMenu(register.GrubMenu{
+			Default:    register.DefaultGrubEntry,
+			KernelArgs: []string{"coreos.config.url=oem:///coreos-install.json"},
+		}).
+		Run(t)
+}
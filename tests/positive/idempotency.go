@@ -0,0 +1,64 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Repeated install",
+		Func: repeatedInstallTest,
+	})
+}
+
+func repeatedInstallTest(t *testing.T, test register.Test) {
+	ignition_config := `{
+		"ignition": {
+			"version": "2.1.0"
+		}
+	}`
+	ignition := test.WriteFile(t, ignition_config)
+
+	cloudinit_config := "#cloud-config\n"
+	cloudinit := test.WriteFile(t, cloudinit_config)
+
+	installOpts := register.InstallOptions{
+		IgnitionPath:    ignition,
+		CloudConfigPath: cloudinit,
+	}
+	opts := func(t *testing.T, device string) []string {
+		o := installOpts
+		o.Device = device
+		return o.Args()
+	}
+
+	test.ValidateIdempotentInstall(t, opts, func(t *testing.T, diskFile string, partitions []register.Partition) {
+		v := test.NewValidations(t)
+		v.Run("default", func(t *testing.T) {
+			test.DefaultChecks(t, register.MountPaths(partitions), diskFile, installOpts.Args())
+		})
+		v.Run("ignition", func(t *testing.T) {
+			test.ValidateIgnition(t, partitions, ignition_config)
+		})
+		v.Run("cloudinit", func(t *testing.T) {
+			test.ValidateCloudinit(t, partitions, cloudinit_config)
+		})
+		v.Finish()
+	})
+}
@@ -0,0 +1,56 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Ignition spec versions",
+		Func: ignitionSpecTest,
+	})
+}
+
+func ignitionSpecTest(t *testing.T, test register.Test) {
+	test.RunIgnitionSpecMatrix(t, func(t *testing.T, spec register.IgnitionSpec) {
+		diskFile, loopDevice := test.CreateDevice(t)
+		defer test.CleanupDisk(t, diskFile, loopDevice)
+
+		opts := register.InstallOptions{
+			Device:       loopDevice,
+			IgnitionPath: test.WriteFile(t, spec.Config),
+		}
+		test.RunCoreOSInstall(t, opts.Args()...)
+
+		partitions := test.MountPartitions(t, diskFile, loopDevice)
+		defer test.UnmountPartitions(t, loopDevice, partitions)
+
+		v := test.NewValidations(t)
+		v.Run("default", func(t *testing.T) {
+			test.DefaultChecks(t, register.MountPaths(partitions), diskFile, opts.Args())
+		})
+		v.Run("ignition", func(t *testing.T) {
+			test.ValidateIgnition(t, partitions, spec.Config)
+		})
+		v.Run("spec", func(t *testing.T) {
+			test.ValidateIgnitionSpec(t, partitions, spec)
+		})
+		v.Finish()
+	})
+}
@@ -0,0 +1,87 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/coreos/init/tests/register"
+	"github.com/coreos/init/tests/util"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Ignition config from a URL",
+		Func: ignitionURLTest,
+	})
+}
+
+func ignitionURLTest(t *testing.T, test register.Test) {
+	supported := test.IgnitionURLSupported(t)
+	if !supported {
+		t.Logf("coreos-install doesn't take -i as a URL, expecting every URL to be rejected")
+	}
+
+	fixture := test.StartConfigServer(t, "127.0.0.1")
+	defer fixture.Close()
+
+	fixture.Serve("/config.ign", []byte(firstIgnitionConfig))
+	fixture.Handle("/slow.ign", register.SlowHandler([]byte(secondIgnitionConfig), 10*time.Second))
+	fixture.Handle("/missing.ign", register.StatusHandler(http.StatusNotFound))
+	fixture.Handle("/error.ign", register.StatusHandler(http.StatusInternalServerError))
+
+	// cases that download a config succeed if URLs are supported, every
+	// case fails like a missing file if they aren't
+	served := func(name, path, config string) register.OptionCase {
+		c := register.OptionCase{
+			Name: name,
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{Device: device, IgnitionPath: fixture.URL + path}.Args()
+			},
+			Error: util.Ptr(register.ErrMissingIgnition),
+		}
+		if supported {
+			c.Error = nil
+			c.Validate = func(t *testing.T, diskFile string, partitions []register.Partition) {
+				fixture.AssertRequested(t, path)
+				test.ValidateIgnition(t, partitions, config)
+			}
+		}
+		return c
+	}
+	unavailable := func(name, url string) register.OptionCase {
+		c := register.OptionCase{
+			Name: name,
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{Device: device, IgnitionPath: url}.Args()
+			},
+			Error: util.Ptr(register.ErrMissingIgnition),
+		}
+		if supported {
+			c.Error = util.Ptr(register.ErrIgnitionURLUnavailable)
+		}
+		return c
+	}
+
+	test.RunOptionCases(t,
+		served("served", "/config.ign", firstIgnitionConfig),
+		served("slow server", "/slow.ign", secondIgnitionConfig),
+		unavailable("404", fixture.URL+"/missing.ign"),
+		unavailable("500", fixture.URL+"/error.ign"),
+		unavailable("nothing listening", "http://127.0.0.1:1/config.ign"),
+	)
+}
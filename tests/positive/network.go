@@ -0,0 +1,61 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Copy network units",
+		Func: copyNetworkTest,
+	})
+}
+
+func copyNetworkTest(t *testing.T, test register.Test) {
+	diskFile, loopDevice := test.CreateDevice(t)
+	defer test.CleanupDisk(t, diskFile, loopDevice)
+
+	units := test.CreateNetworkUnits(t)
+	defer test.CleanupNetworkUnits(t, units)
+
+	opts := register.InstallOptions{
+		Device:      loopDevice,
+		CopyNetwork: true,
+	}
+
+	test.RunCoreOSInstallWith(t, register.Invocation{NetworkUnits: &units}, opts.Args()...)
+
+	partitions := test.MountPartitions(t, diskFile, loopDevice)
+	defer test.UnmountPartitions(t, loopDevice, partitions)
+
+	v := test.NewValidations(t)
+	v.Run("default", func(t *testing.T) {
+		test.DefaultChecks(t, register.MountPaths(partitions), diskFile, opts.Args())
+	})
+	v.Run("network units", func(t *testing.T) {
+		test.ValidateNetworkUnits(t, register.MountPaths(partitions), units)
+	})
+	v.Run("no ignition", func(t *testing.T) {
+		test.AssertNoIgnition(t, partitions)
+	})
+	v.Run("no cloudinit", func(t *testing.T) {
+		test.AssertNoCloudinit(t, partitions)
+	})
+	v.Finish()
+}
@@ -0,0 +1,70 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "OEM images",
+		Func: oemTest,
+	})
+}
+
+func oemTest(t *testing.T, test register.Test) {
+	for _, oem := range register.OEMs {
+		oem := oem
+		t.Run(oem.ID, func(t *testing.T) {
+			diskFile, loopDevice := test.CreateDevice(t)
+			defer test.CleanupDisk(t, diskFile, loopDevice)
+
+			// the installer appends to the OEM's own grub.cfg for -i
+			opts := register.InstallOptions{
+				Device:       loopDevice,
+				OEM:          oem.ID,
+				IgnitionPath: test.WriteFile(t, firstIgnitionConfig),
+			}
+			image := register.OEMImageName(oem.ID)
+			result := test.RunCoreOSInstallHermeticWith(t, opts, register.Hermetic{
+				Setup: func(t *testing.T, fixture *register.FixtureServer, opts *register.InstallOptions) {
+					opts.Version = fixture.CurrentVersion(t, "/"+register.DefaultBoard())
+					fixture.RequireImage(t, register.DefaultBoard(), opts.Version, image)
+				},
+				Check: func(t *testing.T, fixture *register.FixtureServer, result register.InstallResult) {
+					fixture.AssertRequested(t, "/"+image)
+				},
+			})
+
+			partitions := test.MountPartitions(t, diskFile, loopDevice)
+			defer test.UnmountPartitions(t, loopDevice, partitions)
+
+			v := test.NewValidations(t)
+			v.Run("default", func(t *testing.T) {
+				test.DefaultChecks(t, register.MountPaths(partitions), diskFile, result.Args)
+			})
+			v.Run("oem", func(t *testing.T) {
+				test.ValidateOEM(t, partitions, oem)
+			})
+			v.Run("ignition", func(t *testing.T) {
+				test.WithKernelArgs("coreos.oem.id="+oem.ID).ValidateIgnition(t, partitions, firstIgnitionConfig)
+			})
+			v.Finish()
+		})
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+	"github.com/coreos/init/tests/util"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Option combinations",
+		Func: optionsTest,
+	})
+}
+
+const (
+	firstIgnitionConfig = `{
+		"ignition": {
+			"version": "2.0.0"
+		}
+	}`
+	secondIgnitionConfig = `{
+		"ignition": {
+			"version": "2.1.0"
+		}
+	}`
+)
+
+func optionsTest(t *testing.T, test register.Test) {
+	test.RunOptionCases(t,
+		register.OptionCase{
+			Name: "last -d wins",
+			Opts: func(t *testing.T, device string) []string {
+				return []string{"-d", "/dev/coreos-install-nonexistent", "-d", device}
+			},
+		},
+		register.OptionCase{
+			Name: "empty -d",
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{Device: device, Extra: []string{"-d", ""}}.Args()
+			},
+			Error: util.Ptr(register.ErrNoDevice),
+		},
+		register.OptionCase{
+			Name: "last -i wins",
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{
+					Device:       device,
+					IgnitionPath: test.WriteFile(t, firstIgnitionConfig),
+					Extra:        []string{"-i", test.WriteFile(t, secondIgnitionConfig)},
+				}.Args()
+			},
+			Validate: func(t *testing.T, diskFile string, partitions []register.Partition) {
+				test.ValidateIgnition(t, partitions, secondIgnitionConfig)
+			},
+		},
+		register.OptionCase{
+			Name: "-i and -c together",
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{
+					Device:          device,
+					IgnitionPath:    test.WriteFile(t, firstIgnitionConfig),
+					CloudConfigPath: test.WriteFile(t, "#cloud-config\n"),
+				}.Args()
+			},
+			Validate: func(t *testing.T, diskFile string, partitions []register.Partition) {
+				test.ValidateIgnition(t, partitions, firstIgnitionConfig)
+				test.ValidateCloudinit(t, partitions, "#cloud-config\n")
+			},
+		},
+		// the installer requires configs to be regular files so configs
+		// from stdin or process substitution are rejected up front
+		register.OptionCase{
+			Name: "-i from stdin",
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{Device: device, IgnitionPath: "-"}.Args()
+			},
+			Invocation: register.Invocation{
+				Stdin: strings.NewReader(firstIgnitionConfig),
+			},
+			Error: util.Ptr(register.ErrMissingIgnition),
+		},
+		register.OptionCase{
+			Name: "-i from process substitution",
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{Device: device, IgnitionPath: register.PipePath(0)}.Args()
+			},
+			Invocation: register.Invocation{
+				Pipes: [][]byte{[]byte(firstIgnitionConfig)},
+			},
+			Error: util.Ptr(register.ErrMissingIgnition),
+		},
+		register.OptionCase{
+			Name: "-c from process substitution",
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{Device: device, CloudConfigPath: register.PipePath(0)}.Args()
+			},
+			Invocation: register.Invocation{
+				Pipes: [][]byte{[]byte("#cloud-config\n")},
+			},
+			Error: util.Ptr(register.ErrMissingCloudinit),
+		},
+		register.OptionCase{
+			Name: "missing -c file",
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{Device: device, CloudConfigPath: "/nonexistent/user_data"}.Args()
+			},
+			Error: util.Ptr(register.ErrMissingCloudinit),
+		},
+	)
+}
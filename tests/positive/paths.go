@@ -0,0 +1,45 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Awkward paths",
+		Func: pathsTest,
+	})
+}
+
+// the disk file, configs and TMPDIR all live under the awkward path, which
+// catches missing quotes in the installer and the harness alike
+func pathsTest(t *testing.T, test register.Test) {
+	for _, kind := range register.PathKinds {
+		kind := kind
+		t.Run(kind.String(), func(t *testing.T) {
+			test := test.WithTempDir(test.CreateAwkwardDir(t, kind))
+			test.NewScenario().
+				WithIgnition(combinedIgnitionConfig).
+				WithCloudConfig(combinedCloudConfig).
+				ExpectIgnition(combinedIgnitionConfig).
+				ExpectCloudinit(combinedCloudConfig).
+				Run(t)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Install through an HTTP proxy",
+		Func: proxyTest,
+	})
+}
+
+// wget sends proxied requests with the whole URL, whose path is the same as
+// the mirror's, so the fixture server doubles as the proxy
+func proxyTest(t *testing.T, test register.Test) {
+	diskFile, loopDevice := test.CreateDevice(t)
+	defer test.CleanupDisk(t, diskFile, loopDevice)
+
+	proxy := test.StartFixtureServer(t, "127.0.0.1")
+	defer proxy.Close()
+
+	// .invalid never resolves, only the proxy can fetch from it
+	opts := register.InstallOptions{
+		Device:  loopDevice,
+		BaseURL: "http://release.invalid/" + register.DefaultBoard(),
+	}
+	test.RunCoreOSInstallEnv(t, map[string]string{
+		"http_proxy": proxy.URL,
+		"no_proxy":   "",
+	}, opts.Args()...)
+	proxy.AssertRequested(t, "/"+register.OEMImageName(""))
+
+	partitions := test.MountPartitions(t, diskFile, loopDevice)
+	defer test.UnmountPartitions(t, loopDevice, partitions)
+
+	test.DefaultChecks(t, register.MountPaths(partitions), diskFile, opts.Args())
+}
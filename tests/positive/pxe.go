@@ -0,0 +1,49 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Install from a PXE booted system",
+		Func: pxeTest,
+	})
+}
+
+func pxeTest(t *testing.T, test register.Test) {
+	test.RequireBoot(t)
+	key := test.GenerateSSHKey(t)
+
+	check := register.NewBootCheck()
+	check.SSHKeys = []string{key.Public}
+
+	diskFile := test.RunPXEInstall(t, check.Ignition(), register.InstallOptions{})
+
+	test.RunFirmwareMatrix(t, func(t *testing.T, firmware register.Firmware) {
+		vm := test.BootDisk(t, diskFile, register.BootOptions{Firmware: firmware})
+		defer test.ShutdownVM(t, vm)
+
+		test.ValidateBoot(t, vm, check)
+
+		ssh := test.WaitSSH(t, vm, key)
+		test.ValidateSystemRunning(t, ssh)
+		test.ValidateRemoteFile(t, ssh, check.Path, check.Contents)
+	})
+}
@@ -0,0 +1,48 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Slow target disk",
+		Func: slowDiskTest,
+	})
+}
+
+// a slow disk, like a busy SAN or a dying drive, makes the install take
+// longer but shouldn't change its result
+func slowDiskTest(t *testing.T, test register.Test) {
+	diskFile, loopDevice := test.CreateDevice(t)
+	device := test.CreateDelayedDevice(t, loopDevice, 10*time.Millisecond)
+	defer test.CleanupDisk(t, diskFile, device)
+
+	result := test.RunCoreOSInstall(t, register.InstallOptions{Device: device}.Args()...)
+
+	partitions := test.MountPartitions(t, diskFile, device)
+	defer test.UnmountPartitions(t, device, partitions)
+
+	v := test.NewValidations(t)
+	v.Run("default", func(t *testing.T) {
+		test.DefaultChecks(t, register.MountPaths(partitions), diskFile, result.Args)
+	})
+	v.Finish()
+}
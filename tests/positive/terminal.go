@@ -0,0 +1,53 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Terminal",
+		Func: terminalTest,
+	})
+}
+
+// automation runs the installer without a terminal and never answers a
+// prompt, an install that waits for input times out in either mode
+func terminalTest(t *testing.T, test register.Test) {
+	for _, mode := range register.TerminalModes {
+		mode := mode
+		t.Run(mode.String(), func(t *testing.T) {
+			s := test.NewScenario().
+				WithInvocation(register.Invocation{Terminal: mode})
+			if mode == register.NoTerminal {
+				s.Expect("plain output", func(t *testing.T, disk register.ScenarioDisk) {
+					test.ValidatePlainOutput(t, disk.Result)
+				})
+			} else {
+				s.Expect("success message", func(t *testing.T, disk register.ScenarioDisk) {
+					if !strings.Contains(register.PlainOutput(disk.Result.Stdout), "Success!") {
+						t.Fatalf("coreos-install didn't report success on the terminal: %q", disk.Result.Stdout)
+					}
+				})
+			}
+			s.Run(t)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Output transcripts",
+		Func: transcriptsTest,
+	})
+}
+
+// the installer's output is user facing, any change to it has to show up as
+// a reviewed update to testdata/transcripts
+func transcriptsTest(t *testing.T, test register.Test) {
+	for _, c := range []struct {
+		name string
+		args []string
+	}{
+		{"usage", []string{"-h"}},
+		{"no-device", nil},
+		{"bad-option", []string{"-x"}},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			result := test.TryCoreOSInstall(t, c.args...)
+			test.ValidateTranscript(t, c.name, result, nil)
+		})
+	}
+
+	t.Run("missing-ignition", func(t *testing.T) {
+		diskFile, loopDevice := test.CreateDevice(t)
+		defer test.CleanupDisk(t, diskFile, loopDevice)
+
+		missing := test.TempDir(t, "coreos-install-missing") + "/ignition.json"
+		result := test.TryCoreOSInstall(t, register.InstallOptions{Device: loopDevice, IgnitionPath: missing}.Args()...)
+		test.ValidateTranscript(t, "missing-ignition", result, nil)
+	})
+
+	t.Run("install", func(t *testing.T) {
+		test.NewScenario().
+			WithServer(register.Hermetic{
+				Check: func(t *testing.T, fixture *register.FixtureServer, result register.InstallResult) {
+					test.ValidateTranscript(t, "install", result, map[string]string{fixture.URL: "$MIRROR"})
+				},
+			}).
+			// no cloud-config, the installer's warning about validating it
+			// depends on the host having coreos-cloudinit
+			WithIgnition(combinedIgnitionConfig).
+			Run(t)
+	})
+}
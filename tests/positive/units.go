@@ -0,0 +1,76 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Shipped systemd units",
+		Func: unitsTest,
+	})
+}
+
+func unitsTest(t *testing.T, test register.Test) {
+	root := test.InstallCheckout(t)
+	units := test.LoadUnits(t, root)
+
+	v := test.NewValidations(t)
+	for _, u := range units {
+		u := u
+		v.Run(u.Name, func(t *testing.T) {
+			test.ValidateUnit(t, root, u)
+		})
+	}
+	v.Run("enabled units", func(t *testing.T) {
+		test.ValidateUnitLinks(t, root)
+	})
+	v.Run("run issuegen.service", func(t *testing.T) {
+		issuegen, ok := findUnit(units, "issuegen.service")
+		if !ok {
+			t.Fatalf("issuegen.service isn't installed")
+		}
+
+		result := test.RunUnit(t, root, issuegen, register.UnitRun{Scratch: []string{"/run"}})
+		if result.ExitCode != 0 {
+			t.Fatalf("issuegen.service failed with exit code %d: %s", result.ExitCode, result.Output)
+		}
+
+		issue, err := ioutil.ReadFile(filepath.Join(result.Scratch["/run"], "issue"))
+		if err != nil {
+			t.Fatalf("issuegen didn't write /run/issue: %v", err)
+		}
+		if !strings.Contains(string(issue), `This is \n`) {
+			t.Fatalf("unexpected /run/issue: %q", issue)
+		}
+	})
+	v.Finish()
+}
+
+func findUnit(units []register.Unit, name string) (register.Unit, bool) {
+	for _, u := range units {
+		if u.Name == name {
+			return u, true
+		}
+	}
+	return register.Unit{}, false
+}
@@ -0,0 +1,122 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Install over an earlier version",
+		Func: upgradeTest,
+	})
+}
+
+const (
+	oldIgnitionConfig = `{
+		"ignition": {
+			"version": "2.1.0"
+		},
+		"storage": {
+			"files": [{
+				"filesystem": "root",
+				"path": "/etc/provisioned",
+				"contents": {
+					"source": "data:,old"
+				},
+				"mode": 420
+			}]
+		}
+	}`
+	newIgnitionConfig = `{
+		"ignition": {
+			"version": "2.1.0"
+		},
+		"storage": {
+			"files": [{
+				"filesystem": "root",
+				"path": "/etc/provisioned",
+				"contents": {
+					"source": "data:,new"
+				},
+				"mode": 420
+			}]
+		}
+	}`
+)
+
+func upgradeTest(t *testing.T, test register.Test) {
+	diskFile, loopDevice := test.CreateDevice(t)
+	defer test.CleanupDisk(t, diskFile, loopDevice)
+
+	board := register.DefaultBoard()
+	base := "/" + board
+
+	// the last two versions in the mirror, installed oldest first
+	version := func(t *testing.T, fixture *register.FixtureServer, back int) string {
+		versions := fixture.Versions(t, base)
+		if len(versions) < 2 {
+			t.Skipf("the fixture mirror needs two versions of %s, it has %q", board, versions)
+		}
+		return versions[len(versions)-1-back]
+	}
+
+	test.RunCoreOSInstallHermeticWith(t, register.InstallOptions{
+		Device:          loopDevice,
+		IgnitionPath:    test.WriteFile(t, oldIgnitionConfig),
+		CloudConfigPath: test.WriteFile(t, "#cloud-config\n"),
+	}, register.Hermetic{
+		Setup: func(t *testing.T, fixture *register.FixtureServer, opts *register.InstallOptions) {
+			opts.Version = version(t, fixture, 1)
+		},
+	})
+	before := test.ListPartitions(t, diskFile)
+
+	var newVersion, imagePath string
+	result := test.RunCoreOSInstallHermeticWith(t, register.InstallOptions{
+		Device:       loopDevice,
+		IgnitionPath: test.WriteFile(t, newIgnitionConfig),
+	}, register.Hermetic{
+		Setup: func(t *testing.T, fixture *register.FixtureServer, opts *register.InstallOptions) {
+			newVersion = version(t, fixture, 0)
+			imagePath = fixture.ImagePath(base, newVersion, register.OEMImageName(""))
+			opts.Version = newVersion
+		},
+	})
+
+	partitions := test.MountPartitions(t, diskFile, loopDevice)
+	defer test.UnmountPartitions(t, loopDevice, partitions)
+
+	v := test.NewValidations(t)
+	v.Run("default", func(t *testing.T) {
+		test.DefaultChecks(t, register.MountPaths(partitions), diskFile, result.Args)
+	})
+	v.Run("release", func(t *testing.T) {
+		test.ValidateRelease(t, register.MountPaths(partitions), newVersion, board)
+	})
+	v.Run("partitions", func(t *testing.T) {
+		test.ValidateUpgrade(t, diskFile, before, imagePath)
+	})
+	v.Run("ignition replaced", func(t *testing.T) {
+		test.ValidateIgnition(t, partitions, newIgnitionConfig)
+	})
+	v.Run("cloudinit removed", func(t *testing.T) {
+		test.AssertNoCloudinit(t, partitions)
+	})
+	v.Finish()
+}
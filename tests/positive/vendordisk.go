@@ -0,0 +1,46 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Existing ESP",
+		Func: vendorDiskTest,
+	})
+}
+
+// machines that dual-boot or keep vendor tooling come with an ESP and data
+// partitions, which the install must replace cleanly while the partitions
+// are known to the kernel
+func vendorDiskTest(t *testing.T, test register.Test) {
+	var vendor register.VendorDisk
+	test.NewScenario().
+		WithDisk(func(t *testing.T, disk register.ScenarioDisk) {
+			vendor = test.CreateVendorDisk(t, disk.DiskFile, disk.LoopDevice)
+		}).
+		WithIgnition(combinedIgnitionConfig).
+		ExpectIgnition(combinedIgnitionConfig).
+		ExpectPartitions(register.RootPartition, register.OEMPartition).
+		Expect("vendor partitions", func(t *testing.T, disk register.ScenarioDisk) {
+			test.ValidateVendorDisk(t, disk.DiskFile, vendor)
+		}).
+		Run(t)
+}
@@ -0,0 +1,136 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Verbose install",
+		Func: verboseTest,
+		// the go port has no trace to check
+		ScriptOnly: true,
+	})
+	register.Register(register.Test{
+		Name:       "Verbose install with credentials in the base URL",
+		Func:       verboseCredentialsTest,
+		ScriptOnly: true,
+	})
+}
+
+func verboseTest(t *testing.T, test register.Test) {
+	diskFile, loopDevice := test.CreateDevice(t)
+	defer test.CleanupDisk(t, diskFile, loopDevice)
+
+	// configs often carry credentials, tracing must not print their contents
+	secret := "coreos-install-test-secret-token"
+	ignition_config := `{
+		"ignition": {
+			"version": "2.1.0",
+			"config": {
+				"append": [{
+					"source": "https://example.com/config.ign?token=` + secret + `"
+				}]
+			}
+		}
+	}`
+	ignition := test.WriteFile(t, ignition_config)
+
+	opts := register.InstallOptions{
+		Device:       loopDevice,
+		IgnitionPath: ignition,
+		Verbose:      true,
+	}
+
+	result := test.RunCoreOSInstall(t, opts.Args()...)
+
+	partitions := test.MountPartitions(t, diskFile, loopDevice)
+	defer test.UnmountPartitions(t, loopDevice, partitions)
+
+	v := test.NewValidations(t)
+	v.Run("verbose output", func(t *testing.T) {
+		test.ValidateVerboseOutput(t, result, []string{
+			`(?m)^\++ write_ignition`,
+			`Downloading, writing and verifying`,
+		}, []string{secret})
+	})
+	v.Run("ignition", func(t *testing.T) {
+		test.ValidateIgnition(t, partitions, ignition_config)
+	})
+	v.Finish()
+}
+
+// private mirrors take basic-auth credentials in -b, which the trace of
+// every URL built from it must not print, and which still have to reach
+// the mirror. The password needs percent-encoding in the URL.
+func verboseCredentialsTest(t *testing.T, test register.Test) {
+	diskFile, loopDevice := test.CreateDevice(t)
+	defer test.CleanupDisk(t, diskFile, loopDevice)
+
+	fixture := test.StartFixtureServer(t, "127.0.0.1")
+	defer fixture.Close()
+
+	secret := "coreos-install-test-secret/token"
+	fixture.RequireAuth("core", secret)
+	userinfo := url.UserPassword("core", secret).String()
+
+	opts := register.InstallOptions{
+		Device:  loopDevice,
+		BaseURL: strings.Replace(fixture.URL, "://", "://"+userinfo+"@", 1) + "/" + register.DefaultBoard(),
+		Verbose: true,
+	}
+	secrets := []string{secret, userinfo}
+
+	result := test.RunCoreOSInstall(t, opts.Args()...)
+	fixture.AssertRequested(t, "/"+register.OEMImageName(""))
+
+	partitions := test.MountPartitions(t, diskFile, loopDevice)
+	defer test.UnmountPartitions(t, loopDevice, partitions)
+
+	v := test.NewValidations(t)
+	v.Run("verbose output", func(t *testing.T) {
+		test.ValidateVerboseOutput(t, result, []string{
+			`(?m)^\++ wget `,
+			`Downloading, writing and verifying`,
+		}, secrets)
+	})
+	log := register.ArtifactPath(t, "coreos-install.log")
+	v.Run("install log", func(t *testing.T) {
+		if log == "" {
+			t.Skip("-artifact-dir is required to check the install log")
+		}
+		data, err := ioutil.ReadFile(log)
+		if err != nil {
+			t.Fatalf("couldn't read the install log: %v", err)
+		}
+		for _, s := range secrets {
+			if bytes.Contains(data, []byte(s)) {
+				t.Fatalf("install log leaked the credentials in -b")
+			}
+		}
+	})
+	v.Run("default checks", func(t *testing.T) {
+		test.DefaultChecks(t, register.MountPaths(partitions), diskFile, result.Args)
+	})
+	v.Finish()
+}
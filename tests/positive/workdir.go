@@ -0,0 +1,60 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package positive
+
+import (
+	"testing"
+
+	"github.com/coreos/init/tests/register"
+)
+
+func init() {
+	register.Register(register.Test{
+		Name: "Unusual working directories",
+		Func: workingDirTest,
+	})
+}
+
+func workingDirTest(t *testing.T, test register.Test) {
+	ignition := test.WriteFile(t, secondIgnitionConfig)
+
+	kinds := []struct {
+		name string
+		kind register.WorkingDirKind
+	}{
+		{"deleted", register.DeletedWorkingDir},
+		{"read-only", register.ReadOnlyWorkingDir},
+		{"with spaces", register.SpacedWorkingDir},
+	}
+
+	var cases []register.OptionCase
+	for _, k := range kinds {
+		dir := test.CreateWorkingDir(t, k.kind)
+		defer test.CleanupWorkingDir(t, dir)
+
+		cases = append(cases, register.OptionCase{
+			Name: k.name,
+			Opts: func(t *testing.T, device string) []string {
+				return register.InstallOptions{Device: device, IgnitionPath: ignition}.Args()
+			},
+			Invocation: dir.Apply(register.Invocation{}),
+			Validate: func(t *testing.T, diskFile string, partitions []register.Partition) {
+				test.ValidateIgnition(t, partitions, secondIgnitionConfig)
+			},
+		})
+	}
+
+	test.RunOptionCases(t, cases...)
+}
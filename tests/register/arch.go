@@ -0,0 +1,104 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"debug/elf"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// BoardMachines is the ELF machine type of each board's binaries
+var BoardMachines = map[string]elf.Machine{
+	"amd64-usr": elf.EM_X86_64,
+	"arm64-usr": elf.EM_AARCH64,
+}
+
+// ArchBinaries are checked on the USR partition, relative to /usr
+var ArchBinaries = []string{
+	"bin/bash",
+	"bin/ls",
+	"bin/update_engine",
+	"lib/systemd/systemd",
+}
+
+// resolves a path on the USR partition mounted at root, following symlinks
+// inside it rather than on the host
+func resolveUsr(root, p string) (string, error) {
+	for i := 0; i < 40; i++ {
+		full := filepath.Join(root, p)
+		info, err := os.Lstat(full)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			return full, err
+		}
+
+		target, err := os.Readlink(full)
+		if err != nil {
+			return "", err
+		}
+		if path.IsAbs(target) {
+			// the partition is mounted at /usr when booted
+			p = strings.TrimPrefix(path.Clean(target), "/usr")
+		} else {
+			p = path.Join(path.Dir(p), target)
+		}
+	}
+	return "", os.ErrInvalid
+}
+
+// ValidateArchitecture reads the ELF header of key binaries on the installed
+// USR partition, without running them, and asserts they were built for the
+// board. Binaries missing from the image are skipped, at least one has to
+// be found.
+func (test Test) ValidateArchitecture(t *testing.T, partitions []Partition, board string) {
+	expected, ok := BoardMachines[board]
+	if !ok {
+		t.Fatalf("don't know the machine type of board %s", board)
+	}
+
+	usr, ok := FindPartition(partitions, "USR-A")
+	if !ok || usr.MountPath == "" {
+		t.Fatalf("couldn't find a mounted USR-A partition")
+	}
+
+	checked := 0
+	for _, binary := range ArchBinaries {
+		resolved, err := resolveUsr(usr.MountPath, binary)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			t.Fatalf("couldn't resolve /usr/%s: %v", binary, err)
+		}
+
+		f, err := elf.Open(resolved)
+		if err != nil {
+			t.Fatalf("couldn't read /usr/%s as ELF: %v", binary, err)
+		}
+		machine := f.Machine
+		f.Close()
+
+		if machine != expected {
+			t.Fatalf("/usr/%s is built for %s, expected %s for %s", binary, machine, expected, board)
+		}
+		checked++
+	}
+
+	if checked == 0 {
+		t.Fatalf("none of %q were found on USR-A", ArchBinaries)
+	}
+}
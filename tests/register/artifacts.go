@@ -0,0 +1,46 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+var artifactDirFlag = flag.String("artifact-dir", config.ArtifactDir, "directory to save installer logs and other artifacts from each test in [$COREOS_TEST_ARTIFACT_DIR]")
+
+var unsafeArtifactChars = regexp.MustCompile(`[^A-Za-z0-9._/-]+`)
+
+// ArtifactPath returns where the test should save an artifact, or "" if
+// artifacts aren't being collected. Subtests get nested directories.
+func ArtifactPath(t *testing.T, name string) string {
+	if *artifactDirFlag == "" {
+		return ""
+	}
+
+	dir := artifactDir(t)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("couldn't create artifact dir %s: %v", dir, err)
+	}
+	return filepath.Join(dir, name)
+}
+
+// artifactDir is where the test's artifacts go, it isn't created
+func artifactDir(t *testing.T) string {
+	return filepath.Join(*artifactDirFlag, unsafeArtifactChars.ReplaceAllString(t.Name(), "_"))
+}
@@ -0,0 +1,119 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+var cgroupCounter int64
+
+// installCgroup is a cgroup v2 group the installer is started in
+type installCgroup struct {
+	path string
+	dir  *os.File
+}
+
+// createCgroup makes a cgroup limited to limit bytes of memory and no swap,
+// skipping the test if the host's cgroups can't do that
+func createCgroup(t *testing.T, limit int64) *installCgroup {
+	util.RequireHost(t, util.HostCgroupV2)
+
+	controls, err := ioutil.ReadFile(filepath.Join(cgroupRoot, "cgroup.subtree_control"))
+	if err != nil {
+		t.Skipf("couldn't read the cgroup controllers: %v", err)
+	}
+	if !bytes.Contains(controls, []byte("memory")) {
+		if err := ioutil.WriteFile(filepath.Join(cgroupRoot, "cgroup.subtree_control"), []byte("+memory"), 0644); err != nil {
+			t.Skipf("couldn't enable the memory controller: %v", err)
+		}
+	}
+
+	path := filepath.Join(cgroupRoot, fmt.Sprintf("coreos-install-%d-%d", os.Getpid(), atomic.AddInt64(&cgroupCounter, 1)))
+	if err := os.Mkdir(path, 0755); err != nil {
+		t.Fatalf("couldn't create cgroup: %v", err)
+	}
+
+	c := &installCgroup{path: path}
+	if err := ioutil.WriteFile(filepath.Join(path, "memory.max"), []byte(strconv.FormatInt(limit, 10)), 0644); err != nil {
+		c.remove(t)
+		t.Fatalf("couldn't limit cgroup memory: %v", err)
+	}
+	// without swap accounting there's no swap limit to set
+	ioutil.WriteFile(filepath.Join(path, "memory.swap.max"), []byte("0"), 0644)
+
+	c.dir, err = os.Open(path)
+	if err != nil {
+		c.remove(t)
+		t.Fatalf("couldn't open cgroup: %v", err)
+	}
+	return c
+}
+
+// apply starts cmd inside the cgroup
+func (c *installCgroup) apply(attr *syscall.SysProcAttr) {
+	attr.UseCgroupFD = true
+	attr.CgroupFD = int(c.dir.Fd())
+}
+
+// OOMKills counts the processes in the cgroup the OOM killer killed
+func (c *installCgroup) OOMKills(t *testing.T) int {
+	data, err := ioutil.ReadFile(filepath.Join(c.path, "memory.events"))
+	if err != nil {
+		t.Fatalf("couldn't read memory events: %v", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			kills, err := strconv.Atoi(fields[1])
+			if err != nil {
+				t.Fatalf("couldn't parse memory events: %v", err)
+			}
+			return kills
+		}
+	}
+	return 0
+}
+
+// remove deletes the cgroup once the installer and everything it spawned
+// exited
+func (c *installCgroup) remove(t *testing.T) {
+	if c.dir != nil {
+		c.dir.Close()
+	}
+	err := util.Retry(context.Background(), util.DefaultRetryPolicy, func() error {
+		return os.Remove(c.path)
+	})
+	if err != nil {
+		t.Errorf("couldn't remove cgroup: %v", err)
+	}
+}
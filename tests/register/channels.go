@@ -0,0 +1,116 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Channels can be mirrored under <fixture-dir>/<channel>/<board>/ next to
+// the default <board>/ mirror, which stands in for the stable channel
+var Channels = []string{"stable", "beta", "alpha"}
+
+var versionPattern = regexp.MustCompile(`^\d+\.\d+\.\d+$`)
+
+// ChannelBaseURL is what to pass to -b to install board from a channel's
+// mirror, skipping the test if the channel isn't mirrored
+func (f *FixtureServer) ChannelBaseURL(t *testing.T, channel, board string) string {
+	if _, err := os.Stat(filepath.Join(*fixtureDirFlag, channel, board)); err != nil {
+		t.Skipf("the fixture mirror doesn't have the %s channel for %s: %v", channel, board, err)
+	}
+	return f.URL + "/" + path.Join(channel, board)
+}
+
+// CurrentVersion resolves the current version of the mirror at the base
+// URL path, e.g. /amd64-usr, from its version.txt like coreos-install does
+// for -V current
+func (f *FixtureServer) CurrentVersion(t *testing.T, base string) string {
+	versionPath := filepath.Join(*fixtureDirFlag, filepath.FromSlash(base), "current", "version.txt")
+	data, err := ioutil.ReadFile(versionPath)
+	if err != nil {
+		t.Skipf("the fixture mirror doesn't have a current version at %s: %v", base, err)
+	}
+
+	version := ParseOSRelease(data)["COREOS_VERSION"]
+	if version == "" {
+		t.Fatalf("%s doesn't set COREOS_VERSION", versionPath)
+	}
+	return version
+}
+
+// Versions lists the versions mirrored at the base URL path, oldest first
+func (f *FixtureServer) Versions(t *testing.T, base string) []string {
+	entries, err := ioutil.ReadDir(filepath.Join(*fixtureDirFlag, filepath.FromSlash(base)))
+	if err != nil {
+		t.Skipf("couldn't list the fixture mirror at %s: %v", base, err)
+	}
+
+	var versions []string
+	for _, e := range entries {
+		if e.IsDir() && versionPattern.MatchString(e.Name()) {
+			versions = append(versions, e.Name())
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) < 0
+	})
+	return versions
+}
+
+// compares dotted versions numerically, both must match versionPattern
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range as {
+		if len(as[i]) != len(bs[i]) {
+			return len(as[i]) - len(bs[i])
+		}
+		if c := strings.Compare(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// AssertNotRequested fails if anything was downloaded from the fixture at
+// a path ending in suffix
+func (f *FixtureServer) AssertNotRequested(t *testing.T, suffix string) {
+	for _, r := range f.Requests() {
+		if strings.HasSuffix(r, suffix) {
+			t.Fatalf("%s was unexpectedly requested from the fixture", r)
+		}
+	}
+}
+
+// ValidateDownloads asserts the install fetched the image for version from
+// the base URL path, and only resolved version.txt if it was meant to
+func (f *FixtureServer) ValidateDownloads(t *testing.T, base, version string, resolved bool) {
+	versionTxt := path.Join(base, "current", "version.txt")
+	if resolved {
+		f.AssertRequested(t, versionTxt)
+	} else {
+		f.AssertNotRequested(t, versionTxt)
+	}
+
+	image := path.Join(base, version, OEMImageName(""))
+	f.AssertRequested(t, image)
+	f.AssertRequested(t, image+".sig")
+}
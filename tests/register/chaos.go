@@ -0,0 +1,98 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"flag"
+	"hash/fnv"
+	"math/rand"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/coreos/init/tests/util"
+)
+
+var chaosFlag = flag.Int64("chaos", config.Chaos, "inject one random fault into each test, seeded with this, and only require installs to succeed or fail cleanly, 0 disables [$COREOS_TEST_CHAOS]")
+
+// ChaosFault is a fault chaos mode can inject into a test
+type ChaosFault int
+
+const (
+	// the fixture server fails every image and signature download
+	ChaosServerError ChaosFault = iota
+	// every I/O to the target goes through dm-delay
+	ChaosSlowDisk
+	// the installer gets SIGTERM once it starts downloading or writing
+	ChaosTerminate
+)
+
+var ChaosFaults = []ChaosFault{ChaosServerError, ChaosSlowDisk, ChaosTerminate}
+
+func (f ChaosFault) String() string {
+	switch f {
+	case ChaosServerError:
+		return "server errors"
+	case ChaosSlowDisk:
+		return "slow disk"
+	case ChaosTerminate:
+		return "SIGTERM mid-install"
+	}
+	return "unknown fault"
+}
+
+const chaosDiskDelay = 20 * time.Millisecond
+
+// sent once the installer starts downloading or writing
+var chaosTerminate = Interrupt{Pattern: `(Downloading|Writing) `, Signal: syscall.SIGTERM}
+
+// chaosFault picks the test's fault from the seed and its name, so the same
+// seed injects the same fault into the same test
+func chaosFault(seed int64, name string) ChaosFault {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	r := rand.New(rand.NewSource(seed ^ int64(h.Sum64())))
+	return ChaosFaults[r.Intn(len(ChaosFaults))]
+}
+
+// injects returns whether chaos mode is injecting fault into the test
+func (test Test) injects(fault ChaosFault) bool {
+	return test.chaos != nil && *test.chaos == fault
+}
+
+// deviceArg is the last -d the installer is given
+func deviceArg(opts []string) string {
+	return flagArg(opts, "-d")
+}
+
+// validateChaosFailure checks an install that failed under chaos failed
+// cleanly, without hanging or leaving a partial install on the target, and
+// skips the rest of the test
+func (test Test) validateChaosFailure(t *testing.T, result InstallResult, device string, before *util.GPT) {
+	if result.TimedOut || result.Stalled {
+		t.Fatalf("chaos: install hung with %s under %s", ExitName(result), *test.chaos)
+	}
+
+	if device != "" {
+		if after, err := util.ReadGPT(device); err == nil && !reflect.DeepEqual(before, after) {
+			t.Fatalf("chaos: install failed with %s under %s but left a partition table on %s", ExitName(result), *test.chaos, device)
+		}
+	}
+	test.ValidateNoInstallerTempFiles(t)
+	test.ValidateTempCleanup(t, result)
+
+	t.Skipf("chaos: install failed cleanly with %s under %s", ExitName(result), *test.chaos)
+}
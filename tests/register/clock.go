@@ -0,0 +1,133 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// where distros install libfaketime
+var faketimeLibs = []string{
+	"/usr/lib/x86_64-linux-gnu/faketime/libfaketime.so.1",
+	"/usr/lib/aarch64-linux-gnu/faketime/libfaketime.so.1",
+	"/usr/lib64/faketime/libfaketime.so.1",
+	"/usr/lib/faketime/libfaketime.so.1",
+}
+
+// faketimeEnv adds libfaketime to env so the installer and everything it
+// runs sees the wall clock skewed by skew. Time namespaces can't do this,
+// they only offset the monotonic and boot clocks.
+func faketimeEnv(t *testing.T, env map[string]string, skew time.Duration) map[string]string {
+	var lib string
+	for _, path := range faketimeLibs {
+		if _, err := os.Stat(path); err == nil {
+			lib = path
+			break
+		}
+	}
+	if lib == "" {
+		t.Skip("libfaketime is required to skew the installer's clock")
+	}
+
+	skewed := map[string]string{
+		"LD_PRELOAD": lib,
+		"FAKETIME":   fmt.Sprintf("%+d", int64(skew/time.Second)),
+		// timeouts and sleeps still need to take real time
+		"FAKETIME_DONT_FAKE_MONOTONIC": "1",
+	}
+	for k, v := range env {
+		skewed[k] = v
+	}
+	t.Logf("skewing coreos-install's clock by %v", skew)
+	return skewed
+}
+
+// TLSTrust is a CA the installer's wget trusts, see StartTLSFixtureServer
+type TLSTrust struct {
+	CAFile string
+	// a wgetrc pointing at CAFile
+	WgetRC string
+}
+
+// Apply returns a copy of the invocation that trusts the CA
+func (trust TLSTrust) Apply(inv Invocation) Invocation {
+	env := map[string]string{"WGETRC": trust.WgetRC}
+	for k, v := range inv.Env {
+		env[k] = v
+	}
+	inv.Env = env
+	return inv
+}
+
+// StartTLSFixtureServer is StartFixtureServer over HTTPS, with a certificate
+// only valid from an hour before now until an hour after, so an installer
+// with a skewed clock has to reject it
+func (test Test) StartTLSFixtureServer(t *testing.T, addr string) (*FixtureServer, TLSTrust) {
+	cert, caPEM := selfSignedCert(t, addr, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+
+	dir := test.TempDir(t, "coreos-install-tls")
+	trust := TLSTrust{
+		CAFile: filepath.Join(dir, "ca.pem"),
+		WgetRC: filepath.Join(dir, "wgetrc"),
+	}
+	if err := ioutil.WriteFile(trust.CAFile, caPEM, 0644); err != nil {
+		t.Fatalf("couldn't write CA: %v", err)
+	}
+	if err := ioutil.WriteFile(trust.WgetRC, []byte("ca_certificate = "+trust.CAFile+"\n"), 0644); err != nil {
+		t.Fatalf("couldn't write wgetrc: %v", err)
+	}
+
+	fixture := startFixtureServer(t, addr, test.mirrorHandler(t), &tls.Config{Certificates: []tls.Certificate{cert}})
+	return fixture, trust
+}
+
+func selfSignedCert(t *testing.T, addr string, notBefore, notAfter time.Time) (tls.Certificate, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("couldn't generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: addr},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP(addr)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("couldn't create certificate: %v", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -0,0 +1,148 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+var compareInstallerFlag = flag.String("compare-installer", config.CompareInstaller, "also run every scenario with this coreos-install, e.g. the released one, and report how the disks and output differ from the installer under test [$COREOS_TEST_COMPARE_INSTALLER]")
+
+// WithInstaller returns a copy of the test that runs installer wherever an
+// Invocation doesn't name its Binary
+func (test Test) WithInstaller(installer string) Test {
+	test.installer = installer
+	return test
+}
+
+// InstallSnapshot is what an install printed and left on the disk, for
+// comparing two installers
+type InstallSnapshot struct {
+	Transcript string
+	Partitions []Partition
+	// the files on each mounted partition, by label
+	Trees map[string]util.Tree
+}
+
+// SnapshotInstall records the install's transcript, partition table and
+// the files on every mounted partition
+func (test Test) SnapshotInstall(t *testing.T, result InstallResult, diskFile string, partitions []Partition) InstallSnapshot {
+	snapshot := InstallSnapshot{
+		Transcript: Transcript(result, map[string]string{test.TempRoot(): "$TMPDIR"}),
+		Partitions: test.ListPartitions(t, diskFile),
+		Trees:      map[string]util.Tree{},
+	}
+	for _, p := range partitions {
+		if p.MountPath == "" {
+			continue
+		}
+		tree, err := util.WalkTree(p.MountPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		snapshot.Trees[p.Label] = tree
+	}
+	return snapshot
+}
+
+// DiffInstalls lists how the candidate install differs from the baseline:
+// its output, its partition table and the files on each partition
+func DiffInstalls(baseline, candidate InstallSnapshot) (diffs []string) {
+	if baseline.Transcript != candidate.Transcript {
+		diffs = append(diffs, "output:")
+		diffs = append(diffs, util.DiffLines(strings.Split(baseline.Transcript, "\n"), strings.Split(candidate.Transcript, "\n"))...)
+	}
+
+	tables := map[int][2]*Partition{}
+	for i := range baseline.Partitions {
+		p := &baseline.Partitions[i]
+		tables[p.Number] = [2]*Partition{p, nil}
+	}
+	for i := range candidate.Partitions {
+		p := &candidate.Partitions[i]
+		tables[p.Number] = [2]*Partition{tables[p.Number][0], p}
+	}
+	numbers := make([]int, 0, len(tables))
+	for n := range tables {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	for _, n := range numbers {
+		b, c := tables[n][0], tables[n][1]
+		switch {
+		case c == nil:
+			diffs = append(diffs, fmt.Sprintf("partition %d: - %s", n, describePartition(*b)))
+		case b == nil:
+			diffs = append(diffs, fmt.Sprintf("partition %d: + %s", n, describePartition(*c)))
+		case describePartition(*b) != describePartition(*c):
+			diffs = append(diffs, fmt.Sprintf("partition %d: %s => %s", n, describePartition(*b), describePartition(*c)))
+		}
+	}
+
+	labels := map[string]bool{}
+	for label := range baseline.Trees {
+		labels[label] = true
+	}
+	for label := range candidate.Trees {
+		labels[label] = true
+	}
+	sorted := make([]string, 0, len(labels))
+	for label := range labels {
+		sorted = append(sorted, label)
+	}
+	sort.Strings(sorted)
+	for _, label := range sorted {
+		for _, diff := range util.DiffTrees(baseline.Trees[label], candidate.Trees[label]) {
+			diffs = append(diffs, label+": "+diff)
+		}
+	}
+	return
+}
+
+func describePartition(p Partition) string {
+	return fmt.Sprintf("%s type %s sectors %d-%d attributes %#x", p.Label, p.TypeGUID, p.FirstSector, p.LastSector, p.Attributes)
+}
+
+// reportInstallDiff logs how the installer under test behaved differently
+// from -compare-installer and saves it to installer.diff. It's a review
+// aid, so deltas don't fail the test.
+func (test Test) reportInstallDiff(t *testing.T, baseline, candidate *InstallSnapshot) {
+	if baseline == nil || candidate == nil {
+		t.Logf("can't compare installers, an install didn't finish")
+		return
+	}
+
+	diffs := DiffInstalls(*baseline, *candidate)
+	if len(diffs) == 0 {
+		t.Logf("%s and the installer under test behaved the same", filepath.Base(*compareInstallerFlag))
+		return
+	}
+
+	report := strings.Join(diffs, "\n") + "\n"
+	t.Logf("the installer under test behaved differently from %s:\n%s", *compareInstallerFlag, report)
+	if path := ArtifactPath(t, "installer.diff"); path != "" {
+		if err := ioutil.WriteFile(path, []byte(report), 0644); err != nil {
+			t.Errorf("couldn't save installer.diff: %v", err)
+		}
+	}
+}
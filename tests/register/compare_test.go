@@ -0,0 +1,51 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+func TestDiffInstalls(t *testing.T) {
+	baseline := InstallSnapshot{
+		Transcript: "exit: 0\nInstalling\nSuccess!\n",
+		Partitions: []Partition{{Number: 1, Label: "EFI-SYSTEM", FirstSector: 4096, LastSector: 266239}, {Number: 6, Label: "OEM"}},
+		Trees:      map[string]util.Tree{"OEM": {{Path: "grub.cfg", Size: 10, SHA256: "a"}}},
+	}
+	candidate := InstallSnapshot{
+		Transcript: "exit: 0\nInstalling\nDone!\n",
+		Partitions: []Partition{{Number: 1, Label: "EFI-SYSTEM", FirstSector: 4096, LastSector: 266239}, {Number: 9, Label: "ROOT"}},
+		Trees:      map[string]util.Tree{"OEM": {{Path: "grub.cfg", Size: 10, SHA256: "a"}, {Path: "config.ign", Size: 2, SHA256: "b"}}},
+	}
+
+	if diffs := DiffInstalls(baseline, baseline); len(diffs) != 0 {
+		t.Errorf("identical installs differ: %q", diffs)
+	}
+
+	expected := []string{
+		"output:",
+		"-Success!",
+		"+Done!",
+		"partition 6: - " + describePartition(baseline.Partitions[1]),
+		"partition 9: + " + describePartition(candidate.Partitions[1]),
+		"OEM: + config.ign " + candidate.Trees["OEM"][1].String(),
+	}
+	if diffs := DiffInstalls(baseline, candidate); !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("diffs are %q, expected %q", diffs, expected)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+// ConcurrentInstall is one of the installs started by RunConcurrentInstalls
+type ConcurrentInstall struct {
+	Invocation Invocation
+	Opts       []string
+}
+
+// RunConcurrentInstalls starts all the installs at once and waits for them
+// to finish, failing the test if any of them did. Each install runs as a
+// parallel subtest so failures are reported against the right install.
+func (test Test) RunConcurrentInstalls(t *testing.T, installs ...ConcurrentInstall) []InstallResult {
+	results := make([]InstallResult, len(installs))
+	t.Run("concurrent", func(t *testing.T) {
+		for i := range installs {
+			i := i
+			t.Run(fmt.Sprintf("install %d", i), func(t *testing.T) {
+				t.Parallel()
+				results[i] = test.RunCoreOSInstallWith(t, installs[i].Invocation, installs[i].Opts...)
+			})
+		}
+	})
+
+	var failed []string
+	for i, r := range results {
+		if r.TimedOut || r.ExitCode != 0 || r.Binary == "" {
+			failed = append(failed, util.FormatCommand("coreos-install", installs[i].Opts...))
+		}
+	}
+
+	if len(failed) != 0 {
+		t.Fatalf("%d of %d concurrent installs failed: %s", len(failed), len(installs), strings.Join(failed, "; "))
+	}
+	return results
+}
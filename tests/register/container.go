@@ -0,0 +1,100 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/coreos/init/tests/util"
+)
+
+var (
+	containerRuntimeFlag = flag.String("container-runtime", config.ContainerRuntime, "docker or podman, used by tests that run coreos-install in a container [$COREOS_TEST_CONTAINER_RUNTIME]")
+	containerImageFlag   = flag.String("container-image", config.ContainerImage, "image with coreos-install's dependencies for container tests [$COREOS_TEST_CONTAINER_IMAGE]")
+)
+
+// containerInstallPath is where the installer under test is mounted
+const containerInstallPath = "/usr/local/bin/coreos-install"
+
+// Container runs the installer inside a privileged container with the
+// host's /dev, so the loop device and its partitions are visible
+type Container struct {
+	// docker or podman
+	Runtime string
+	Image   string
+
+	// host paths bind mounted at the same path, e.g. config files
+	Mounts []string
+
+	name string
+}
+
+// ContainerFromFlags returns the container configured on the command line,
+// skipping the test if there isn't one
+func (test Test) ContainerFromFlags(t *testing.T, mounts ...string) *Container {
+	if *containerRuntimeFlag == "" || *containerImageFlag == "" {
+		t.Skip("-container-runtime and -container-image are required to install from a container")
+	}
+
+	if _, err := exec.LookPath(*containerRuntimeFlag); err != nil {
+		t.Skipf("%s not found: %v", *containerRuntimeFlag, err)
+	}
+
+	return &Container{
+		Runtime: *containerRuntimeFlag,
+		Image:   *containerImageFlag,
+		Mounts:  mounts,
+	}
+}
+
+// command returns the runtime invocation that runs binary with opts inside
+// the container. Only env is passed through, not the test's environment.
+func (c *Container) command(binary string, env map[string]string, opts []string) (string, []string) {
+	c.name = fmt.Sprintf("coreos-install-%d-%d", os.Getpid(), time.Now().UnixNano())
+
+	args := []string{
+		"run", "--rm", "--privileged", "--net=host",
+		"--name", c.name,
+		"-v", "/dev:/dev",
+		"-v", binary + ":" + containerInstallPath + ":ro",
+	}
+
+	for _, m := range c.Mounts {
+		args = append(args, "-v", m+":"+m)
+	}
+
+	for k, v := range env {
+		args = append(args, "-e", k+"="+v)
+	}
+
+	args = append(args, c.Image, containerInstallPath)
+	return c.Runtime, append(args, opts...)
+}
+
+// remove cleans up the container if the runtime client was killed before
+// it could remove it
+func (c *Container) remove() {
+	if c.name != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), util.DefaultCommandTimeout)
+		defer cancel()
+		util.Command(ctx, c.Runtime, "rm", "-f", c.name).Run()
+	}
+}
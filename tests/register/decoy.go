@@ -0,0 +1,153 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+// DecoyDisk is another attached disk with ROOT and OEM filesystems, like a
+// previous install on a second drive, see CreateDecoyDisk
+type DecoyDisk struct {
+	DiskFile   string
+	LoopDevice string
+
+	partitions []Partition
+	// hashes of the partitions when they were created
+	hashes map[int]string
+}
+
+// labels the decoy's partitions and filesystems carry
+var decoyLabels = []string{"ROOT", "OEM"}
+
+const decoySize = 1024 * 1024 * 1024
+
+// CreateDecoyDisk attaches a disk whose partitions have the labels the
+// installer looks for. If near is set the decoy gets a loop device whose
+// name starts with near's, /dev/loop1 gets /dev/loop1X, so a glob for
+// near's partitions also matches the decoy's.
+func (test Test) CreateDecoyDisk(t *testing.T, near string) DecoyDisk {
+	util.RequireRoot(t)
+	util.RequireTools(t, "sgdisk>=1.0", "mkfs.ext4")
+	util.RequireHost(t, util.HostLoopDevices, util.HostLoopPartscan)
+
+	f := test.TempFile(t, "coreos-install-decoy")
+	f.Close()
+	decoy := DecoyDisk{DiskFile: f.Name(), hashes: map[int]string{}}
+	if err := os.Truncate(decoy.DiskFile, decoySize); err != nil {
+		t.Fatalf("failed to truncate decoy disk file: %v", err)
+	}
+
+	args := []string{"-o"}
+	for i, label := range decoyLabels {
+		n := strconv.Itoa(i + 1)
+		args = append(args, "-n", n+":0:+256M", "-t", n+":8300", "-c", n+":"+label)
+	}
+	util.MustRun(t, "sgdisk", append(args, decoy.DiskFile)...)
+
+	decoy.partitions = test.ListPartitions(t, decoy.DiskFile)
+	for _, p := range decoy.partitions {
+		offset := int64(p.FirstSector) * sectorSize
+		size := int64(p.LastSector-p.FirstSector+1) * sectorSize
+		util.MustRun(t, "mkfs.ext4", "-q", "-F", "-L", p.Label, "-E", fmt.Sprintf("offset=%d", offset), decoy.DiskFile, strconv.FormatInt(size/1024, 10))
+		decoy.hashes[p.Number] = test.HashPartition(t, decoy.DiskFile, p)
+	}
+
+	if near != "" {
+		decoy.LoopDevice = test.attachDecoyNear(t, decoy.DiskFile, near)
+	} else {
+		err := util.Retry(context.Background(), util.DefaultRetryPolicy, func() error {
+			var err error
+			decoy.LoopDevice, err = util.AttachLoop(decoy.DiskFile, util.LoopPartscan)
+			if e, ok := err.(*util.LoopError); ok && e.Errno != syscall.EBUSY {
+				return util.Permanent(err)
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatalf("couldn't attach decoy loop device: %v", err)
+		}
+	}
+	t.Logf("attached decoy disk with %v partitions at %s", decoyLabels, decoy.LoopDevice)
+	return decoy
+}
+
+// attachDecoyNear tries each /dev/loopNX for near's N
+func (test Test) attachDecoyNear(t *testing.T, diskFile, near string) string {
+	if underlying := test.underlying(near); underlying != "" {
+		near = underlying
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(near, "/dev/loop"))
+	if err != nil || n == 0 {
+		t.Skipf("no other loop device's name starts with %s", near)
+	}
+
+	for x := 0; x < 10; x++ {
+		device, err := util.AttachLoopAt(diskFile, util.LoopPartscan, n*10+x)
+		if e, ok := err.(*util.LoopError); ok && e.Errno == syscall.EBUSY {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("couldn't attach decoy loop device: %v", err)
+		}
+		return device
+	}
+	t.Skipf("every /dev/loop%dX is in use", n)
+	return ""
+}
+
+// CleanupDecoyDisk detaches the decoy and removes its disk file
+func (test Test) CleanupDecoyDisk(t *testing.T, decoy DecoyDisk) {
+	test.CleanupDisk(t, decoy.DiskFile, decoy.LoopDevice)
+}
+
+// ValidateDecoyUntouched asserts the install didn't write to the decoy,
+// like copying Ignition to its OEM or cloud-config to its ROOT
+func (test Test) ValidateDecoyUntouched(t *testing.T, decoy DecoyDisk) {
+	partitions := test.ListPartitions(t, decoy.DiskFile)
+	if len(partitions) != len(decoy.partitions) {
+		t.Fatalf("decoy disk has %d partitions, created with %d", len(partitions), len(decoy.partitions))
+	}
+	for i, p := range partitions {
+		if p != decoy.partitions[i] {
+			t.Fatalf("decoy partition %d changed: expected %+v, received %+v", p.Number, decoy.partitions[i], p)
+		}
+		if test.HashPartition(t, decoy.DiskFile, p) != decoy.hashes[p.Number] {
+			t.Fatalf("install wrote to the decoy's %s partition", p.Label)
+		}
+	}
+}
+
+// ValidatePartitionsOn asserts every partition was mapped from device, so
+// the validators read the installed disk and not whichever disk has the
+// label they look for
+func ValidatePartitionsOn(t *testing.T, partitions []Partition, device string) {
+	prefix := filepath.Join("/dev/mapper", filepath.Base(device))
+	for _, p := range partitions {
+		number := strings.TrimPrefix(strings.TrimPrefix(p.Device, prefix), "p")
+		if !strings.HasPrefix(p.Device, prefix) || number != strconv.Itoa(p.Number) {
+			t.Fatalf("partition %s is %s, not partition %d of %s", p.Label, p.Device, p.Number, device)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coreos/init/tests/util"
+)
+
+// DMTarget is one line of a device mapper table, in 512 byte sectors
+type DMTarget struct {
+	Start  uint64
+	Length uint64
+	Type   string
+	Args   string
+}
+
+func (d DMTarget) String() string {
+	return fmt.Sprintf("%d %d %s %s", d.Start, d.Length, d.Type, d.Args)
+}
+
+var dmCounter int64
+
+// mappedDevices remembers what each device mapper device the test created
+// sits on, so CleanupDisk can take the stack apart
+type mappedDevices struct {
+	mu    sync.Mutex
+	under map[string]string
+}
+
+// DeviceSectors is a block device's size in 512 byte sectors
+func DeviceSectors(t *testing.T, device string) uint64 {
+	out := util.MustRun(t, "blockdev", "--getsz", device)
+	sectors, err := strconv.ParseUint(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		t.Fatalf("couldn't parse the size of %s: %v", device, err)
+	}
+	return sectors
+}
+
+// CreateMappedDevice creates a device mapper device over device from the
+// table. coreos-install only installs to disks, loop devices and LVM
+// volumes, so the device gets an LVM- uuid for lsblk to report it as lvm.
+// CleanupDisk removes it along with the device under it.
+func (test Test) CreateMappedDevice(t *testing.T, device string, table []DMTarget) string {
+	util.RequireTools(t, "dmsetup", "blockdev")
+	util.RequireHost(t, util.HostDeviceMapper)
+
+	lines := make([]string, len(table))
+	for i, target := range table {
+		lines[i] = target.String()
+	}
+
+	name := fmt.Sprintf("coreos-install-%d-%d", os.Getpid(), atomic.AddInt64(&dmCounter, 1))
+	util.MustRun(t, "dmsetup", "create", name, "--uuid", "LVM-"+name, "--table", strings.Join(lines, ";"))
+
+	mapped := filepath.Join("/dev/mapper", name)
+	if test.mapped != nil {
+		test.mapped.mu.Lock()
+		test.mapped.under[mapped] = device
+		test.mapped.mu.Unlock()
+	}
+	return mapped
+}
+
+// CreateDelayedDevice layers dm-delay over device, delaying every read and
+// write by delay
+func (test Test) CreateDelayedDevice(t *testing.T, device string, delay time.Duration) string {
+	util.RequireHost(t, util.HostDMDelay)
+	return test.CreateMappedDevice(t, device, []DMTarget{{
+		Length: DeviceSectors(t, device),
+		Type:   "delay",
+		Args:   fmt.Sprintf("%s 0 %d", device, delay/time.Millisecond),
+	}})
+}
+
+// FlakeyMode is what dm-flakey does to I/O while the device is down
+type FlakeyMode string
+
+const (
+	// every read and write fails
+	FlakeyErrorAll FlakeyMode = ""
+	// writes fail, reads still work
+	FlakeyErrorWrites FlakeyMode = "error_writes"
+	// writes are silently thrown away
+	FlakeyDropWrites FlakeyMode = "drop_writes"
+)
+
+// CreateFlakeyDevice layers dm-flakey over device, which works for up and
+// then misbehaves according to mode for down, over and over. A zero up
+// leaves the device down for good. Intervals are rounded to seconds.
+func (test Test) CreateFlakeyDevice(t *testing.T, device string, up, down time.Duration, mode FlakeyMode) string {
+	util.RequireHost(t, util.HostDMFlakey)
+	args := fmt.Sprintf("%s 0 %d %d", device, up/time.Second, down/time.Second)
+	if mode != FlakeyErrorAll {
+		args += " 1 " + string(mode)
+	}
+
+	return test.CreateMappedDevice(t, device, []DMTarget{{
+		Length: DeviceSectors(t, device),
+		Type:   "flakey",
+		Args:   args,
+	}})
+}
+
+// SectorRange is a run of 512 byte sectors
+type SectorRange struct {
+	Start  uint64
+	Length uint64
+}
+
+// CreateErrorDevice maps device through unchanged except for the bad
+// ranges, where every read and write fails with EIO through dm-error
+func (test Test) CreateErrorDevice(t *testing.T, device string, bad ...SectorRange) string {
+	size := DeviceSectors(t, device)
+
+	sorted := append([]SectorRange(nil), bad...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	var table []DMTarget
+	var next uint64
+	for _, r := range sorted {
+		if r.Start < next || r.Start+r.Length > size {
+			t.Fatalf("bad sector range %d+%d overlaps another or is past the end of %s", r.Start, r.Length, device)
+		}
+		if r.Start > next {
+			table = append(table, DMTarget{Start: next, Length: r.Start - next, Type: "linear", Args: fmt.Sprintf("%s %d", device, next)})
+		}
+		table = append(table, DMTarget{Start: r.Start, Length: r.Length, Type: "error"})
+		next = r.Start + r.Length
+	}
+	if next < size {
+		table = append(table, DMTarget{Start: next, Length: size - next, Type: "linear", Args: fmt.Sprintf("%s %d", device, next)})
+	}
+	return test.CreateMappedDevice(t, device, table)
+}
+
+// RemoveMappedDevice removes a device created by CreateMappedDevice and
+// returns the device it was over
+func (test Test) RemoveMappedDevice(t *testing.T, mapped string) string {
+	name := filepath.Base(mapped)
+	if _, err := util.RunRetry(context.Background(), util.DefaultRetryPolicy, "dmsetup", "remove", name); err != nil {
+		// still open, e.g. by a killed install's stuck I/O, so fail
+		// everything still queued on it and remove it anyway
+		t.Logf("couldn't remove %s, forcing it: %v", name, err)
+		if _, err := util.RunE(context.Background(), "dmsetup", "remove", "--force", name); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if test.mapped == nil {
+		return ""
+	}
+	test.mapped.mu.Lock()
+	defer test.mapped.mu.Unlock()
+	device := test.mapped.under[mapped]
+	delete(test.mapped.under, mapped)
+	return device
+}
+
+// underlying returns the device under a mapped device, "" if it wasn't
+// created by CreateMappedDevice
+func (test Test) underlying(device string) string {
+	if test.mapped == nil {
+		return ""
+	}
+	test.mapped.mu.Lock()
+	defer test.mapped.mu.Unlock()
+	return test.mapped.under[device]
+}
@@ -0,0 +1,47 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+// InvocationVariant is a named way of starting the installer
+type InvocationVariant struct {
+	Name       string
+	Invocation Invocation
+}
+
+// EnvironmentVariants are environments that tend to break shell scripts
+// that were only ever run in the C locale with a full login environment
+var EnvironmentVariants = []InvocationVariant{
+	{
+		// case mapping of i/I differs, breaking case-insensitive matching
+		Name: "turkish locale",
+		Invocation: Invocation{
+			Env: map[string]string{"LC_ALL": "tr_TR.UTF-8"},
+		},
+	},
+	{
+		Name: "POSIXLY_CORRECT",
+		Invocation: Invocation{
+			Env: map[string]string{"POSIXLY_CORRECT": "1"},
+		},
+	},
+	{
+		// like a systemd unit or a cron job
+		Name: "minimal environment",
+		Invocation: Invocation{
+			ClearEnv: true,
+			Env:      map[string]string{"PATH": "/usr/sbin:/usr/bin:/sbin:/bin"},
+		},
+	},
+}
@@ -0,0 +1,186 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+var (
+	validateImageFlag        = flag.String("validate-image", config.ValidateImage, "disk image or block device with an install made by another tool, run through the same checks as the suite's installs by TestExistingImage [$COREOS_TEST_VALIDATE_IMAGE]")
+	validateImageVersionFlag = flag.String("validate-image-version", config.ValidateImageVersion, "the -V -validate-image was installed with, its VERSION_ID isn't checked if empty or current [$COREOS_TEST_VALIDATE_IMAGE_VERSION]")
+)
+
+// ExistingImage validates -validate-image instead of installing, so installs
+// produced by other tools get the same checks. It's run by TestExistingImage
+// rather than registered, the image isn't the installer under test.
+var ExistingImage = Test{
+	Name: "Existing image",
+	Func: existingImageTest,
+}
+
+func existingImageTest(t *testing.T, test Test) {
+	if *validateImageFlag == "" {
+		t.Skip("-validate-image is required to validate an existing install")
+	}
+	path := *validateImageFlag
+
+	device := test.AttachExistingImage(t, path)
+	defer test.DetachExistingImage(t, device)
+
+	partitions := test.MountExistingPartitions(t, path, device)
+	defer test.UnmountExistingPartitions(t, partitions)
+
+	test.ValidateExistingImage(t, path, partitions)
+}
+
+// AttachExistingImage attaches a read-only loop device with partition
+// scanning to the image, or on top of a block device, so nothing the checks
+// do can write to it and its partitions show up as the loop's
+func (test Test) AttachExistingImage(t *testing.T, path string) string {
+	util.RequireRoot(t)
+	util.RequireHost(t, util.HostLoopDevices, util.HostLoopPartscan)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("couldn't open the image to validate: %v", err)
+	}
+	if !info.Mode().IsRegular() && info.Mode()&os.ModeDevice == 0 {
+		t.Fatalf("%s is neither a disk image nor a block device", path)
+	}
+
+	var device string
+	err = util.Retry(context.Background(), util.DefaultRetryPolicy, func() error {
+		device, err = util.AttachLoop(path, util.LoopReadOnly|util.LoopPartscan)
+		if e, ok := err.(*util.LoopError); ok && e.Errno != syscall.EBUSY {
+			return util.Permanent(err)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("couldn't attach loop device: %v", err)
+	}
+	t.Logf("validating %s through %s", path, device)
+	return device
+}
+
+// DetachExistingImage detaches the loop device, leaving the image as it was
+func (test Test) DetachExistingImage(t *testing.T, device string) {
+	err := util.Retry(context.Background(), util.DefaultRetryPolicy, func() error {
+		return util.DetachLoop(device)
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// MountExistingPartitions mounts every partition in the image's GPT that
+// has a filesystem, read-only, from the loop's partition devices. Those
+// that don't mount are logged with why, see ValidateExistingMounts.
+func (test Test) MountExistingPartitions(t *testing.T, path, device string) []Partition {
+	util.RequireHost(t, util.HostVFAT)
+
+	partitions := test.ListPartitions(t, path)
+	if len(partitions) == 0 {
+		t.Fatalf("%s has no partitions", path)
+	}
+	for i, p := range partitions {
+		partitions[i].Device = fmt.Sprintf("%sp%d", device, p.Number)
+		mountPath, err := test.mountReadOnly(t, partitions[i].Device)
+		if err != nil {
+			t.Logf("couldn't mount %s partition %d: %v", p.Label, p.Number, err)
+			continue
+		}
+		partitions[i].MountPath = mountPath
+	}
+	return partitions
+}
+
+// ValidateExistingMounts asserts the partitions the checks read from were
+// mounted, rather than letting the checks pass without them. An ext4
+// journal that needs recovery can't be replayed on the read-only device.
+func (test Test) ValidateExistingMounts(t *testing.T, partitions []Partition) {
+	usr, ok := ActiveUsrPartition(partitions)
+	if !ok {
+		t.Fatalf("the image has no USR partition")
+	}
+
+	var unmounted []string
+	for _, label := range []string{espLabel, usr.Label, OEMPartition.Label, RootPartition.Label} {
+		p, ok := FindPartition(partitions, label)
+		if !ok {
+			unmounted = append(unmounted, label+" (missing)")
+		} else if p.MountPath == "" {
+			unmounted = append(unmounted, label)
+		}
+	}
+	if len(unmounted) != 0 {
+		t.Fatalf("couldn't mount %s, see the log for why; an image that wasn't shut down cleanly needs fsck first", strings.Join(unmounted, ", "))
+	}
+}
+
+func (test Test) UnmountExistingPartitions(t *testing.T, partitions []Partition) {
+	for _, p := range partitions {
+		if p.MountPath != "" {
+			test.UnmountPath(t, p.MountPath)
+			test.RemoveAll(t, p.MountPath)
+		}
+	}
+}
+
+// ValidateExistingImage runs every check that doesn't depend on how the
+// install was made. Unlike DefaultChecks the board is the image's own, not
+// the host's.
+func (test Test) ValidateExistingImage(t *testing.T, diskFile string, partitions []Partition) {
+	test.ValidateExistingMounts(t, partitions)
+	mountPaths := MountPaths(partitions)
+	board := installedRelease(t, mountPaths)["COREOS_BOARD"]
+	t.Logf("image is for %s", board)
+
+	v := test.NewValidations(t)
+	v.Run("release", func(t *testing.T) {
+		test.ValidateRelease(t, mountPaths, ExpectedVersion([]string{"-V", *validateImageVersionFlag}), "")
+	})
+	for _, spec := range []PartitionSpec{RootPartition, USRAPartition, OEMPartition} {
+		spec := spec
+		v.Run(spec.Label+" partition", func(t *testing.T) {
+			test.ValidatePartition(t, diskFile, spec)
+		})
+	}
+	v.Run("architecture", func(t *testing.T) {
+		test.ValidateArchitecture(t, partitions, board)
+	})
+	v.Run("kernels", func(t *testing.T) {
+		test.ValidateKernels(t, partitions, "")
+	})
+	v.Run("grub menu", func(t *testing.T) {
+		test.ValidateGrubMenu(t, mountPaths, GrubMenu{})
+	})
+	v.Run("first boot state", func(t *testing.T) {
+		test.ValidateFirstBootState(t, mountPaths)
+	})
+	v.Run("config permissions", func(t *testing.T) {
+		test.ValidateConfigPermissions(t, mountPaths)
+	})
+	v.Finish()
+}
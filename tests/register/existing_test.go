@@ -0,0 +1,29 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import "testing"
+
+func TestValidateExistingMounts(t *testing.T) {
+	// USR-B has no filesystem until the first update
+	Test{}.ValidateExistingMounts(t, []Partition{
+		{Label: espLabel, MountPath: "/mnt/esp"},
+		{Label: "BIOS-BOOT"},
+		{Label: "USR-A", Attributes: 1 << 48, MountPath: "/mnt/usr"},
+		{Label: "USR-B"},
+		{Label: "OEM", MountPath: "/mnt/oem"},
+		{Label: "ROOT", MountPath: "/mnt/root"},
+	})
+}
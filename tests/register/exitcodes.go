@@ -0,0 +1,149 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"fmt"
+	"regexp"
+	"syscall"
+	"testing"
+)
+
+// ExitStatus is an exit code the installer can return
+type ExitStatus int
+
+const (
+	ExitSuccess ExitStatus = 0
+	// every error the installer detects itself, see InstallerErrors for
+	// telling them apart
+	ExitFailure ExitStatus = 1
+	// bash couldn't run the installer or a command it needs
+	ExitNotExecutable   ExitStatus = 126
+	ExitCommandNotFound ExitStatus = 127
+)
+
+// ExitSignal is how bash reports being killed by sig
+func ExitSignal(sig syscall.Signal) ExitStatus {
+	return ExitStatus(128 + int(sig))
+}
+
+var exitStatusNames = map[ExitStatus]string{
+	ExitSuccess:         "EXIT_SUCCESS",
+	ExitFailure:         "EXIT_FAILURE",
+	ExitNotExecutable:   "EXIT_NOT_EXECUTABLE",
+	ExitCommandNotFound: "EXIT_COMMAND_NOT_FOUND",
+}
+
+func (s ExitStatus) String() string {
+	if name, ok := exitStatusNames[s]; ok {
+		return name
+	}
+	if s > 128 && s < 128+65 {
+		return fmt.Sprintf("EXIT_SIGNAL_%d (%v)", s-128, syscall.Signal(s-128))
+	}
+	return fmt.Sprintf("EXIT_UNKNOWN_%d", int(s))
+}
+
+// InstallerError is a failure the installer reports itself. They all exit
+// with ExitFailure so they're told apart by the message on stderr.
+type InstallerError struct {
+	Name   string
+	Status ExitStatus
+	// regexp matching the installer's message
+	Stderr string
+}
+
+var (
+	ErrBadOption            = InstallerError{"EXIT_BAD_OPTION", ExitFailure, `illegal option|option requires an argument`}
+	ErrNoDevice             = InstallerError{"EXIT_NO_DEVICE", ExitFailure, `No target block device provided`}
+	ErrBadDevice            = InstallerError{"EXIT_BAD_DEVICE", ExitFailure, `Target block device \(.*\) is not a full disk`}
+	ErrDeviceNotWritable    = InstallerError{"EXIT_DEVICE_NOT_WRITABLE", ExitFailure, `Target block device \(.*\) is not writable`}
+	ErrMissingCloudinit     = InstallerError{"EXIT_MISSING_CLOUDINIT", ExitFailure, `Cloud config file \(.*\) does not exist`}
+	ErrInvalidCloudinit     = InstallerError{"EXIT_INVALID_CLOUDINIT", ExitFailure, `Cloud config file \(.*\) is not valid`}
+	ErrMissingIgnition      = InstallerError{"EXIT_MISSING_IGNITION", ExitFailure, `Ignition config file \(.*\) does not exist`}
+	ErrUnreadableImage      = InstallerError{"EXIT_UNREADABLE_IMAGE", ExitFailure, `Could not read image file`}
+	ErrMissingWget          = InstallerError{"EXIT_MISSING_WGET", ExitFailure, `Missing wget!`}
+	ErrMissingGpg           = InstallerError{"EXIT_MISSING_GPG", ExitFailure, `Missing gpg!`}
+	ErrVersionUnavailable   = InstallerError{"EXIT_VERSION_UNAVAILABLE", ExitFailure, `version\.txt unavailable`}
+	ErrImageUnavailable     = InstallerError{"EXIT_IMAGE_UNAVAILABLE", ExitFailure, `Image URL unavailable`}
+	ErrSignatureUnavailable = InstallerError{"EXIT_SIGNATURE_UNAVAILABLE", ExitFailure, `Image signature unavailable`}
+	ErrDownloadFailed       = InstallerError{"EXIT_DOWNLOAD_FAILED", ExitFailure, `Download of .* did not complete`}
+	ErrWriteFailed          = InstallerError{"EXIT_WRITE_FAILED", ExitFailure, `Cannot expand .* to `}
+	ErrVerifyFailed         = InstallerError{"EXIT_VERIFY_FAILED", ExitFailure, `GPG signature verification failed`}
+	ErrRereadFailed         = InstallerError{"EXIT_REREAD_FAILED", ExitFailure, `Failed to reread partitions`}
+)
+
+// InstallerErrors is every documented failure, in the order the installer
+// checks for them
+var InstallerErrors = []InstallerError{
+	ErrBadOption,
+	ErrNoDevice,
+	ErrBadDevice,
+	ErrDeviceNotWritable,
+	ErrMissingCloudinit,
+	ErrInvalidCloudinit,
+	ErrMissingIgnition,
+	ErrUnreadableImage,
+	ErrMissingWget,
+	ErrMissingGpg,
+	ErrVersionUnavailable,
+	ErrImageUnavailable,
+	ErrSignatureUnavailable,
+	ErrDownloadFailed,
+	ErrWriteFailed,
+	ErrVerifyFailed,
+	ErrRereadFailed,
+}
+
+// Matches reports whether the result is this error
+func (e InstallerError) Matches(result InstallResult) bool {
+	return ExitStatus(result.ExitCode) == e.Status && regexp.MustCompile(e.Stderr).Match(result.Stderr)
+}
+
+// ExitName names how an install ended, the documented error if its message
+// was printed and the exit status otherwise
+func ExitName(result InstallResult) string {
+	if result.TimedOut {
+		return "TIMED_OUT"
+	}
+
+	if result.Stalled {
+		return "STALLED"
+	}
+
+	if ExitStatus(result.ExitCode) == ExitFailure {
+		for _, e := range InstallerErrors {
+			if e.Matches(result) {
+				return e.Name
+			}
+		}
+	}
+	return ExitStatus(result.ExitCode).String()
+}
+
+// ValidateExitStatus asserts the installer exited with the expected status
+func (test Test) ValidateExitStatus(t *testing.T, result InstallResult, expected ExitStatus) {
+	if ExitStatus(result.ExitCode) != expected || result.TimedOut {
+		t.Fatalf("install exited with %s, expected %s: %s", ExitName(result), expected, result.Stderr)
+	}
+}
+
+// ValidateInstallerError asserts the install failed with the expected
+// documented error
+func (test Test) ValidateInstallerError(t *testing.T, result InstallResult, expected InstallerError) {
+	if result.TimedOut || !expected.Matches(result) {
+		t.Fatalf("install exited with %s, expected %s: %s", ExitName(result), expected.Name, result.Stderr)
+	}
+}
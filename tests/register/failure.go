@@ -0,0 +1,94 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"testing"
+)
+
+// FailureCase is one row of a RunFailureCases table
+type FailureCase struct {
+	Name string
+
+	// builds the installer's args for the case's target device
+	Opts func(t *testing.T, device string) []string
+	// how the installer is started, e.g. from ShadowPath or
+	// UnprivilegedInvocation, nil runs it normally
+	Invocation func(t *testing.T) Invocation
+
+	// the documented error the install has to fail with
+	Error InstallerError
+}
+
+// RunFailureCases runs the installer once per case against a fresh device
+// and validates each failed the way ValidateFailedInstall expects
+func (test Test) RunFailureCases(t *testing.T, cases ...FailureCase) {
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			diskFile, loopDevice := test.CreateDevice(t)
+			defer test.CleanupDisk(t, diskFile, loopDevice)
+
+			var inv Invocation
+			if c.Invocation != nil {
+				inv = c.Invocation(t)
+			}
+
+			before := test.SnapshotDisk(t, diskFile)
+			result := test.TryCoreOSInstallWith(t, inv, c.Opts(t, loopDevice)...)
+			test.ValidateFailedInstall(t, result, c.Error, diskFile, loopDevice, before)
+		})
+	}
+}
+
+// ValidateFailedInstall asserts an install failed with the expected error
+// before the point of no return: the disk is untouched, nothing was left on
+// its OEM partition and the installer cleaned up after itself
+func (test Test) ValidateFailedInstall(t *testing.T, result InstallResult, expected InstallerError, diskFile, loopDevice string, before DiskSnapshot) {
+	test.ValidateInstallerError(t, result, expected)
+	test.ValidateDiskUntouched(t, diskFile, before)
+	test.ValidateNoPartialOEM(t, diskFile, loopDevice)
+	test.ValidateNoInstallerTempFiles(t)
+	test.ValidateTempCleanup(t, result)
+}
+
+// ValidateNoPartialOEM asserts a failed install didn't write its configs to
+// the OEM partition, if the disk has one
+func (test Test) ValidateNoPartialOEM(t *testing.T, diskFile, loopDevice string) {
+	if _, ok := FindPartition(test.ListPartitions(t, diskFile), "OEM"); !ok {
+		return
+	}
+
+	partitions := test.MountPartitions(t, diskFile, loopDevice)
+	defer test.UnmountPartitions(t, loopDevice, partitions)
+
+	test.AssertNoIgnition(t, partitions)
+	test.AssertNoCloudinit(t, partitions)
+}
+
+// ValidateFailedCleanly asserts an install that hit a fault the installer
+// can't know about in advance exited nonzero on its own, rather than
+// hanging or reporting success, and cleaned up after itself
+func (test Test) ValidateFailedCleanly(t *testing.T, result InstallResult) {
+	if result.TimedOut || result.Stalled {
+		t.Fatalf("install hung with %s: %s", ExitName(result), result.Stderr)
+	}
+
+	if result.ExitCode == 0 {
+		t.Fatalf("install succeeded despite the fault")
+	}
+	test.ValidateNoInstallerTempFiles(t)
+	test.ValidateTempCleanup(t, result)
+}
@@ -0,0 +1,65 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+type FileMeta struct {
+	Mode os.FileMode
+	Uid  uint32
+	Gid  uint32
+
+	// checked with getfattr when set
+	SELinuxContext string
+}
+
+// PrivateFileMeta is what the installer should produce for any config it
+// writes, it runs with umask 077 and configs can contain credentials
+var PrivateFileMeta = FileMeta{Mode: 0600, Uid: 0, Gid: 0}
+
+func (test Test) ValidateFileMeta(t *testing.T, path string, expected FileMeta) {
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("couldn't stat %s: %v", path, err)
+	}
+
+	if info.Mode().Perm() != expected.Mode.Perm() {
+		t.Fatalf("%s has wrong permissions: expected %v, received %v", path, expected.Mode.Perm(), info.Mode().Perm())
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		t.Fatalf("couldn't read ownership of %s", path)
+	}
+
+	if stat.Uid != expected.Uid || stat.Gid != expected.Gid {
+		t.Fatalf("%s has wrong ownership: expected %d:%d, received %d:%d", path, expected.Uid, expected.Gid, stat.Uid, stat.Gid)
+	}
+
+	if expected.SELinuxContext != "" {
+		out := util.MustRun(t, "getfattr", "--only-values", "-n", "security.selinux", path)
+		context := strings.TrimSpace(strings.TrimRight(string(out), "\x00"))
+		if context != expected.SELinuxContext {
+			t.Fatalf("%s has wrong SELinux context: expected %s, received %s", path, expected.SELinuxContext, context)
+		}
+	}
+}
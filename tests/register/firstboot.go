@@ -0,0 +1,80 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// FirstBootFlagFiles must exist on one of the installed partitions for the
+// system to run its first boot provisioning. grub looks for coreos/first_boot
+// on the EFI-SYSTEM partition to set coreos.first_boot=detected.
+var FirstBootFlagFiles = []string{
+	filepath.Join("coreos", "first_boot"),
+}
+
+// ValidateFirstBootState asserts none of the installed partitions carry host
+// state that should only be generated on first boot, and that the first boot
+// flag files are in place
+func (test Test) ValidateFirstBootState(t *testing.T, mountPaths []string, flagFiles ...string) {
+	for _, p := range mountPaths {
+		test.validateNoMachineID(t, p)
+		test.validateNoHostKeys(t, p)
+	}
+
+	for _, flag := range append(append([]string{}, FirstBootFlagFiles...), flagFiles...) {
+		found := false
+		for _, p := range mountPaths {
+			if fileExists(filepath.Join(p, flag)) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			t.Fatalf("first boot flag file %s not found on any partitions", flag)
+		}
+	}
+}
+
+// /etc/machine-id may exist but must be empty so systemd generates one
+func (test Test) validateNoMachineID(t *testing.T, mountPath string) {
+	path := filepath.Join(mountPath, "etc", "machine-id")
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		t.Fatalf("couldn't read %s: %v", path, err)
+	}
+
+	if strings.TrimSpace(string(data)) != "" {
+		t.Fatalf("/etc/machine-id isn't empty: received %s", data)
+	}
+}
+
+func (test Test) validateNoHostKeys(t *testing.T, mountPath string) {
+	keys, err := filepath.Glob(filepath.Join(mountPath, "etc", "ssh", "ssh_host_*key*"))
+	if err != nil {
+		t.Fatalf("couldn't search for ssh host keys: %v", err)
+	}
+
+	if len(keys) != 0 {
+		t.Fatalf("ssh host keys found on installed partition: %v", keys)
+	}
+}
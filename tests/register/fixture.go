@@ -0,0 +1,196 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"crypto/tls"
+	"flag"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var fixtureDirFlag = flag.String("fixture-dir", config.FixtureDir, "local mirror of release.core-os.net laid out as <board>/<version>/, served to installs that shouldn't use the network [$COREOS_TEST_FIXTURE_DIR]")
+
+// FixtureServer serves the local release mirror over HTTP and records what
+// was requested from it
+type FixtureServer struct {
+	// e.g. http://10.231.0.1:34567, or https:// if started with
+	// StartTLSFixtureServer
+	URL string
+
+	listener net.Listener
+	server   *http.Server
+
+	mu       sync.Mutex
+	requests []string
+	served   int64
+	// served in place of the mirror, see Serve and Handle
+	handlers map[string]http.Handler
+	// basic-auth credentials every request needs, see RequireAuth
+	user, password string
+}
+
+// StartFixtureServer serves -fixture-dir on addr, skipping the test if no
+// mirror was given
+func (test Test) StartFixtureServer(t *testing.T, addr string) *FixtureServer {
+	return startFixtureServer(t, addr, test.mirrorHandler(t), nil)
+}
+
+// mirrorHandler serves -fixture-dir, or fails the downloads in chaos mode
+func (test Test) mirrorHandler(t *testing.T) http.Handler {
+	if *fixtureDirFlag == "" {
+		t.Skip("-fixture-dir is required to install from a local mirror")
+	}
+	mirror := http.FileServer(http.Dir(*fixtureDirFlag))
+	if !test.injects(ChaosServerError) {
+		return mirror
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".bz2") || strings.HasSuffix(r.URL.Path, ".sig") {
+			http.Error(w, "chaos", http.StatusInternalServerError)
+			return
+		}
+		mirror.ServeHTTP(w, r)
+	})
+}
+
+// StartConfigServer serves only what's added with Serve and Handle, for
+// tests that don't need the mirror
+func (test Test) StartConfigServer(t *testing.T, addr string) *FixtureServer {
+	return startFixtureServer(t, addr, http.NotFoundHandler(), nil)
+}
+
+// startFixtureServer serves over HTTPS if tlsConfig is set
+func startFixtureServer(t *testing.T, addr string, files http.Handler, tlsConfig *tls.Config) *FixtureServer {
+	listener, err := net.Listen("tcp", net.JoinHostPort(addr, "0"))
+	if err != nil {
+		t.Fatalf("couldn't listen on %s: %v", addr, err)
+	}
+	scheme := "http"
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+		scheme = "https"
+	}
+
+	f := &FixtureServer{
+		URL:      scheme + "://" + listener.Addr().String(),
+		listener: listener,
+		handlers: map[string]http.Handler{},
+	}
+	f.server = &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+		handler, ok := f.handlers[r.URL.Path]
+		wantUser, wantPassword := f.user, f.password
+		f.mu.Unlock()
+
+		if wantUser != "" {
+			if user, password, _ := r.BasicAuth(); user != wantUser || password != wantPassword {
+				w.Header().Set("WWW-Authenticate", `Basic realm="fixtures"`)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+		}
+
+		counter := &countingWriter{ResponseWriter: w}
+		if ok {
+			handler.ServeHTTP(counter, r)
+		} else {
+			files.ServeHTTP(counter, r)
+		}
+
+		f.mu.Lock()
+		f.served += counter.n
+		f.mu.Unlock()
+	})}
+	go f.server.Serve(listener)
+
+	t.Logf("serving fixtures at %s", f.URL)
+	return f
+}
+
+// BaseURL is what to pass to -b to install board from the mirror
+func (f *FixtureServer) BaseURL(board string) string {
+	return f.URL + "/" + board
+}
+
+// RequireAuth turns away every request without these basic-auth
+// credentials, like a private mirror
+func (f *FixtureServer) RequireAuth(user, password string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.user, f.password = user, password
+}
+
+// Addr is the host:port the server listens on
+func (f *FixtureServer) Addr() string {
+	return f.listener.Addr().String()
+}
+
+// Serve adds a file that isn't in the mirror, like a boot script or a
+// config, at path
+func (f *FixtureServer) Serve(path string, data []byte) {
+	f.Handle(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+}
+
+// Handle serves path with handler instead of the mirror, e.g. to fail or
+// stall a download
+func (f *FixtureServer) Handle(path string, handler http.Handler) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handlers[path] = handler
+}
+
+// Requests returns the method and path of every request served so far
+func (f *FixtureServer) Requests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.requests...)
+}
+
+// BytesServed is the total size of every response body served so far
+func (f *FixtureServer) BytesServed() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.served
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (w *countingWriter) Write(data []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(data)
+	w.n += int64(n)
+	return n, err
+}
+
+// Flush lets handlers like Hold send part of a response before stalling
+func (w *countingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (f *FixtureServer) Close() {
+	f.server.Close()
+}
@@ -0,0 +1,245 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func get(t *testing.T, client *http.Client, url string) (int, string) {
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestFixtureHandlers(t *testing.T) {
+	f := Test{}.StartConfigServer(t, "127.0.0.1")
+	defer f.Close()
+
+	f.Serve("/config.ign", []byte("config"))
+	f.Handle("/missing.ign", StatusHandler(http.StatusNotFound))
+	f.Handle("/broken.ign", StatusHandler(http.StatusInternalServerError))
+	f.Handle("/slow.ign", SlowHandler([]byte("slow"), time.Hour))
+
+	for _, c := range []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/config.ign", http.StatusOK, "config"},
+		{"/missing.ign", http.StatusNotFound, "Not Found\n"},
+		{"/broken.ign", http.StatusInternalServerError, "Internal Server Error\n"},
+		// neither served nor in a mirror
+		{"/other.ign", http.StatusNotFound, "404 page not found\n"},
+	} {
+		status, body := get(t, http.DefaultClient, f.URL+c.path)
+		if status != c.status || body != c.body {
+			t.Errorf("%s: got %d %q, expected %d %q", c.path, status, body, c.status, c.body)
+		}
+	}
+
+	client := &http.Client{Timeout: 100 * time.Millisecond}
+	if _, err := client.Get(f.URL + "/slow.ign"); err == nil {
+		t.Errorf("slow handler responded before its delay")
+	}
+
+	expected := []string{"GET /config.ign", "GET /missing.ign", "GET /broken.ign", "GET /other.ign", "GET /slow.ign"}
+	if requests := f.Requests(); !reflect.DeepEqual(requests, expected) {
+		t.Errorf("recorded %q, expected %q", requests, expected)
+	}
+}
+
+func TestFixtureRequireAuth(t *testing.T) {
+	f := Test{}.StartConfigServer(t, "127.0.0.1")
+	defer f.Close()
+	f.Serve("/config.ign", []byte("config"))
+	f.RequireAuth("core", "s3cret/token")
+
+	for _, c := range []struct {
+		name           string
+		user, password string
+		status         int
+	}{
+		{"no credentials", "", "", http.StatusUnauthorized},
+		{"wrong password", "core", "s3cret", http.StatusUnauthorized},
+		{"wrong user", "other", "s3cret/token", http.StatusUnauthorized},
+		{"credentials", "core", "s3cret/token", http.StatusOK},
+	} {
+		req, err := http.NewRequest("GET", f.URL+"/config.ign", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.user != "" {
+			req.SetBasicAuth(c.user, c.password)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != c.status {
+			t.Errorf("%s: got %d, expected %d", c.name, resp.StatusCode, c.status)
+		}
+		// wget only sends credentials once it's challenged
+		if c.status == http.StatusUnauthorized && !strings.HasPrefix(resp.Header.Get("WWW-Authenticate"), "Basic ") {
+			t.Errorf("%s: no basic-auth challenge", c.name)
+		}
+	}
+}
+
+func TestFixtureChaosServerError(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"version.txt", "coreos_production_image.bin.bz2", "coreos_production_image.bin.bz2.sig"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func(previous string) { *fixtureDirFlag = previous }(*fixtureDirFlag)
+	*fixtureDirFlag = dir
+
+	for _, c := range []struct {
+		name  string
+		chaos *ChaosFault
+		// status of the image and its signature
+		status int
+	}{
+		{"without chaos", nil, http.StatusOK},
+		{"with ChaosServerError", chaosFaultPtr(ChaosServerError), http.StatusInternalServerError},
+		{"with another fault", chaosFaultPtr(ChaosSlowDisk), http.StatusOK},
+	} {
+		f := Test{chaos: c.chaos}.StartFixtureServer(t, "127.0.0.1")
+
+		if status, _ := get(t, http.DefaultClient, f.URL+"/version.txt"); status != http.StatusOK {
+			t.Errorf("%s: version.txt returned %d", c.name, status)
+		}
+		for _, name := range []string{"/coreos_production_image.bin.bz2", "/coreos_production_image.bin.bz2.sig"} {
+			if status, _ := get(t, http.DefaultClient, f.URL+name); status != c.status {
+				t.Errorf("%s: %s returned %d, expected %d", c.name, name, status, c.status)
+			}
+		}
+		f.Close()
+	}
+}
+
+func TestFixtureBytesServed(t *testing.T) {
+	f := Test{}.StartConfigServer(t, "127.0.0.1")
+	defer f.Close()
+
+	f.Serve("/a", []byte("12345"))
+	f.Serve("/b", []byte("678"))
+	get(t, http.DefaultClient, f.URL+"/a")
+	get(t, http.DefaultClient, f.URL+"/b")
+
+	if served := f.BytesServed(); served != 8 {
+		t.Errorf("served %d bytes, expected 8", served)
+	}
+}
+
+func TestFixtureHold(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "image"), []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func(previous string) { *fixtureDirFlag = previous }(*fixtureDirFlag)
+	*fixtureDirFlag = dir
+
+	f := Test{}.StartFixtureServer(t, "127.0.0.1")
+	defer f.Close()
+	held := f.Hold("GET", "/image", -1)
+
+	// other methods aren't held
+	resp, err := http.Head(f.URL + "/image")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("HEAD was held or failed: %v", err)
+	}
+	resp.Body.Close()
+
+	resp, err = http.Get(f.URL + "/image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	sent := make([]byte, 9)
+	if _, err := io.ReadFull(resp.Body, sent); err != nil || string(sent) != "012345678" {
+		t.Fatalf("got %q before the hold, expected all but the last byte: %v", sent, err)
+	}
+	select {
+	case <-held:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("held channel wasn't closed")
+	}
+
+	client := &http.Client{Timeout: 100 * time.Millisecond}
+	f.Hold("HEAD", "/image", 0)
+	if _, err := client.Head(f.URL + "/image"); err == nil {
+		t.Errorf("HEAD responded despite the hold")
+	}
+}
+
+func TestFixturePXE(t *testing.T) {
+	writeFiles := func(dir, prefix string) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range pxeArtifactFiles() {
+			if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(prefix+name), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	mirror, pxe := t.TempDir(), t.TempDir()
+	writeFiles(filepath.Join(mirror, "amd64-usr", "1.0.0"), "mirror ")
+	writeFiles(pxe, "pxe-dir ")
+
+	defer func(fixtureDir, pxeDir string) {
+		*fixtureDirFlag, *pxeDirFlag = fixtureDir, pxeDir
+	}(*fixtureDirFlag, *pxeDirFlag)
+	*fixtureDirFlag, *pxeDirFlag = mirror, pxe
+
+	f := Test{}.StartFixtureServer(t, "127.0.0.1")
+	defer f.Close()
+
+	for version, source := range map[string]string{"1.0.0": "mirror ", "current": "pxe-dir "} {
+		image := f.ServePXE(t, "amd64-usr", version)
+		for _, p := range []string{image.Kernel, image.Kernel + ".sig", image.Initrd, image.Initrd + ".sig"} {
+			if status, body := get(t, http.DefaultClient, f.URL+p); status != http.StatusOK || body != source+path.Base(p) {
+				t.Errorf("%s: got %d %q, expected the %sfile", p, status, body, source)
+			}
+		}
+	}
+}
+
+func chaosFaultPtr(fault ChaosFault) *ChaosFault {
+	return &fault
+}
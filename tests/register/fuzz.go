@@ -0,0 +1,174 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	// placeholders in fuzzed args for the test's device and Ignition config
+	FuzzDevice   = "$DEVICE"
+	FuzzIgnition = "$IGNITION"
+
+	// more args than this aren't any more interesting to the installer
+	fuzzMaxArgs = 32
+
+	// room for the partition tables, no image fits since there's no network
+	// to download one from and -f is never fuzzed
+	fuzzDiskSize = 64 * 1024 * 1024
+)
+
+// fuzzPathOptions are the installer's options that take a path, and the
+// only placeholder each may be given, none for "". Other paths could wipe
+// or read anything on the host, so inputs using them are skipped.
+var fuzzPathOptions = map[byte]string{
+	'd': FuzzDevice,
+	'i': FuzzIgnition,
+	'c': FuzzIgnition,
+	'f': "",
+	'k': "",
+	't': "",
+}
+
+// installerOptionArgs are the installer's getopts options that take a value
+const installerOptionArgs = "VBCdocitbkf"
+
+// PreWriteErrors are the documented errors the installer checks for before
+// it starts writing the disk
+var PreWriteErrors = []InstallerError{
+	ErrBadOption,
+	ErrNoDevice,
+	ErrBadDevice,
+	ErrDeviceNotWritable,
+	ErrMissingCloudinit,
+	ErrInvalidCloudinit,
+	ErrMissingIgnition,
+	ErrUnreadableImage,
+	ErrMissingWget,
+	ErrMissingGpg,
+	ErrVersionUnavailable,
+	ErrImageUnavailable,
+	ErrSignatureUnavailable,
+}
+
+// FuzzArgs splits fuzzed args on NUL bytes into the installer's argv
+func FuzzArgs(data string) []string {
+	args := strings.Split(data, "\x00")
+	if len(args) > fuzzMaxArgs {
+		args = args[:fuzzMaxArgs]
+	}
+	return args
+}
+
+// fuzzSafeArgs reports whether every path the args give the installer,
+// parsed the way getopts does, is a placeholder. Options can be stuck
+// together and to their values, e.g. -vd/dev/sda.
+func fuzzSafeArgs(args []string) bool {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || len(arg) < 2 || arg[0] != '-' {
+			// getopts stops at the first operand
+			return true
+		}
+
+		for j := 1; j < len(arg); j++ {
+			if !strings.Contains(installerOptionArgs, string(arg[j])) {
+				continue
+			}
+			value := arg[j+1:]
+			if value == "" {
+				if i+1 == len(args) {
+					// getopts reports the missing value
+					return true
+				}
+				i++
+				value = args[i]
+			}
+			if placeholder, ok := fuzzPathOptions[arg[j]]; ok && (placeholder == "" || value != placeholder) {
+				return false
+			}
+			break
+		}
+	}
+	return true
+}
+
+// FuzzInstall runs the installer with fuzzed args against a fresh device
+// from inside an empty network namespace, with FuzzDevice and FuzzIgnition
+// in args replaced by the device and a file holding ignition. Args giving
+// the installer any other path are skipped, see fuzzSafeArgs. It fails if
+// the installer hangs, if it succeeds with a changed disk that isn't a
+// valid install and if it fails before writing but touched the disk anyway.
+func (test Test) FuzzInstall(t *testing.T, args []string, ignition []byte) {
+	if !fuzzSafeArgs(args) {
+		t.Skip("the args give the installer a path other than the placeholders")
+	}
+
+	diskFile, loopDevice := test.CreateDeviceSize(t, fuzzDiskSize)
+	defer test.CleanupDisk(t, diskFile, loopDevice)
+
+	ignitionPath := test.WriteFile(t, string(ignition))
+	for i, arg := range args {
+		arg = strings.Replace(arg, FuzzDevice, loopDevice, -1)
+		args[i] = strings.Replace(arg, FuzzIgnition, ignitionPath, -1)
+	}
+
+	ns := test.CreateNetNS(t)
+	defer test.CleanupNetNS(t, ns)
+
+	before := test.SnapshotDisk(t, diskFile)
+	result := test.TryCoreOSInstallWith(t, Invocation{
+		NetNS:        ns,
+		Timeout:      5 * time.Minute,
+		StallTimeout: time.Minute,
+	}, args...)
+
+	if result.TimedOut || result.Stalled {
+		t.Fatalf("install hung with %s: %s", ExitName(result), result.Stderr)
+	}
+
+	if result.ExitCode != 0 {
+		for _, e := range PreWriteErrors {
+			if e.Matches(result) {
+				test.ValidateDiskUntouched(t, diskFile, before)
+			}
+		}
+		return
+	}
+	// e.g. -h
+	if test.SnapshotDisk(t, diskFile).Equal(before) {
+		return
+	}
+
+	for _, spec := range []PartitionSpec{RootPartition, USRAPartition, OEMPartition} {
+		test.ValidatePartition(t, diskFile, spec)
+	}
+
+	partitions := test.MountPartitions(t, diskFile, loopDevice)
+	defer test.UnmountPartitions(t, loopDevice, partitions)
+
+	oem, _ := FindPartition(partitions, "OEM")
+	installed, err := ioutil.ReadFile(filepath.Join(oem.MountPath, ignitionConfigPath))
+	// the ignition file is the only config the installer can be given
+	if err == nil && !bytes.Equal(installed, ignition) {
+		t.Fatalf("installed %s isn't the config the installer was given: received %q", ignitionConfigPath, installed)
+	}
+}
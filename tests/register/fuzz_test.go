@@ -0,0 +1,44 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import "testing"
+
+func TestFuzzSafeArgs(t *testing.T) {
+	for _, c := range []struct {
+		args []string
+		safe bool
+	}{
+		{[]string{"-d", FuzzDevice}, true},
+		{[]string{"-d", FuzzDevice, "-i", FuzzIgnition, "-o", "/dev/sda"}, true},
+		{[]string{"-vd" + FuzzDevice, "-c" + FuzzIgnition}, true},
+		{[]string{"-d", "/dev/sda"}, false},
+		{[]string{"-d/dev/loop0"}, false},
+		{[]string{"-nvd", "/dev/loop0"}, false},
+		{[]string{"-d", FuzzDevice, "-i", "/etc/shadow"}, false},
+		{[]string{"-d", FuzzDevice, "-f", FuzzDevice}, false},
+		{[]string{"-d", FuzzDevice, "-t", ""}, false},
+		// the value of -o, not an option
+		{[]string{"-o", "-d/dev/sda", "-d", FuzzDevice}, true},
+		// getopts stops at the first operand
+		{[]string{"-d", FuzzDevice, "x", "-d", "/dev/sda"}, true},
+		{[]string{"--", "-d", "/dev/sda"}, true},
+		{[]string{"-d"}, true},
+	} {
+		if safe := fuzzSafeArgs(c.args); safe != c.safe {
+			t.Errorf("%q: safe is %v, expected %v", c.args, safe, c.safe)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var (
+	grubVarPattern    = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)\}?`)
+	grubKernelPattern = regexp.MustCompile(`^linux(16|efi)?(\$\{?suf\}?)?$`)
+	grubInitrdPattern = regexp.MustCompile(`^initrd(16|efi)?(\$\{?suf\}?)?$`)
+
+	// the main grub.cfg lives on the EFI-SYSTEM partition and sources the
+	// grub.cfg written to the root of the OEM partition
+	grubMainConfigPath = filepath.Join("coreos", "grub", "grub.cfg")
+	grubOEMConfigPath  = "grub.cfg"
+)
+
+// DefaultKernelArgs are expected on every kernel line of the installed
+// grub.cfg. An arg without a value only checks that the key is present.
+var DefaultKernelArgs = []string{
+	"root=LABEL=ROOT",
+	"mount.usrflags=ro",
+	"console",
+}
+
+// the usr partition can be passed in a few ways depending on whether the
+// image uses dm-verity
+var usrKernelArgs = []string{"usr", "mount.usr", "verity.usr"}
+
+type KernelLine struct {
+	Command string
+	Path    string
+	Args    []string
+}
+
+type GrubConfig struct {
+	Vars    map[string]string
+	Kernels []KernelLine
+	Initrds []string
+	// the menu entries, whose kernel lines are also in Kernels
+	Entries []MenuEntry
+
+	// open { blocks, the menu entry's index or -1 for functions and
+	// submenus
+	blocks []int
+}
+
+// MenuEntry is a menuentry block of a grub.cfg
+type MenuEntry struct {
+	Title string
+	// --id, "" if it has none
+	ID      string
+	Kernels []KernelLine
+}
+
+// ParseGrubConfig evaluates the variable assignments and linux/linuxefi
+// lines of a grub.cfg. Both branches of conditionals are evaluated so the
+// last assignment of a variable wins. source commands are resolved through
+// include, which may return nil for files that aren't available.
+func ParseGrubConfig(data []byte, include func(path string) []byte) *GrubConfig {
+	cfg := &GrubConfig{Vars: map[string]string{}}
+	cfg.parse(data, include)
+	return cfg
+}
+
+func (cfg *GrubConfig) parse(data []byte, include func(path string) []byte) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		switch {
+		case fields[0] == "menuentry" && strings.HasSuffix(line, "{"):
+			cfg.Entries = append(cfg.Entries, parseMenuEntry(cfg, strings.TrimSuffix(line, "{")))
+			cfg.blocks = append(cfg.blocks, len(cfg.Entries)-1)
+		case strings.HasSuffix(line, "{"):
+			cfg.blocks = append(cfg.blocks, -1)
+		case line == "}" && len(cfg.blocks) != 0:
+			cfg.blocks = cfg.blocks[:len(cfg.blocks)-1]
+		case fields[0] == "set" && len(fields) > 1:
+			assignment := strings.TrimSpace(strings.TrimPrefix(line, "set"))
+			parts := strings.SplitN(assignment, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			cfg.Vars[parts[0]] = cfg.unquote(parts[1])
+		case fields[0] == "source" && len(fields) > 1 && include != nil:
+			if sourced := include(cfg.unquote(fields[1])); sourced != nil {
+				cfg.parse(sourced, include)
+			}
+		case grubKernelPattern.MatchString(fields[0]) && len(fields) > 1:
+			args := strings.Fields(cfg.expand(strings.Join(fields[2:], " ")))
+			kernel := KernelLine{
+				Command: cfg.expand(fields[0]),
+				Path:    fields[1],
+				Args:    args,
+			}
+			cfg.Kernels = append(cfg.Kernels, kernel)
+			if entry := cfg.currentEntry(); entry != nil {
+				entry.Kernels = append(entry.Kernels, kernel)
+			}
+		case grubInitrdPattern.MatchString(fields[0]) && len(fields) > 1:
+			for _, path := range fields[1:] {
+				cfg.Initrds = append(cfg.Initrds, cfg.expand(path))
+			}
+		}
+	}
+}
+
+// parseMenuEntry reads the title and --id from a menuentry line without its
+// opening brace
+func parseMenuEntry(cfg *GrubConfig, line string) MenuEntry {
+	line = strings.TrimSpace(strings.TrimPrefix(line, "menuentry"))
+
+	var entry MenuEntry
+	if len(line) != 0 && (line[0] == '"' || line[0] == '\'') {
+		if end := strings.IndexByte(line[1:], line[0]); end >= 0 {
+			entry.Title = cfg.unquote(line[:end+2])
+			line = line[end+2:]
+		}
+	}
+
+	fields := strings.Fields(line)
+	for i, field := range fields {
+		if entry.Title == "" && i == 0 {
+			entry.Title = field
+		} else if strings.HasPrefix(field, "--id=") {
+			entry.ID = cfg.unquote(strings.TrimPrefix(field, "--id="))
+		} else if field == "--id" && i+1 < len(fields) {
+			entry.ID = cfg.unquote(fields[i+1])
+		}
+	}
+	return entry
+}
+
+// currentEntry is the innermost menu entry being parsed, nil outside them
+func (cfg *GrubConfig) currentEntry() *MenuEntry {
+	for i := len(cfg.blocks) - 1; i >= 0; i-- {
+		if cfg.blocks[i] >= 0 {
+			return &cfg.Entries[cfg.blocks[i]]
+		}
+	}
+	return nil
+}
+
+// Entry finds a menu entry the way grub's default variable does, by --id,
+// title or index
+func (cfg *GrubConfig) Entry(name string) (MenuEntry, bool) {
+	for _, entry := range cfg.Entries {
+		if entry.ID == name || entry.Title == name {
+			return entry, true
+		}
+	}
+	if i, err := strconv.Atoi(name); err == nil && i >= 0 && i < len(cfg.Entries) {
+		return cfg.Entries[i], true
+	}
+	return MenuEntry{}, false
+}
+
+// DefaultEntry is the entry grub boots without interaction, the first if
+// default isn't set
+func (cfg *GrubConfig) DefaultEntry() (MenuEntry, bool) {
+	name, ok := cfg.Vars["default"]
+	if !ok {
+		name = "0"
+	}
+	return cfg.Entry(name)
+}
+
+// strips grub quoting from a word, single quoted words aren't expanded
+func (cfg *GrubConfig) unquote(word string) string {
+	if len(word) >= 2 && word[0] == '\'' && word[len(word)-1] == '\'' {
+		return word[1 : len(word)-1]
+	}
+	return cfg.expand(strings.Trim(word, `"`))
+}
+
+// expands known variables, unknown ones (usually set at boot time like
+// $usr_uuid) are left in place so they can still be asserted on
+func (cfg *GrubConfig) expand(s string) string {
+	return grubVarPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		name := grubVarPattern.FindStringSubmatch(ref)[1]
+		if value, ok := cfg.Vars[name]; ok {
+			return value
+		}
+		return ref
+	})
+}
+
+// AppendArgs returns the args the OEM grub.cfg appends to the kernel
+// command line
+func (cfg *GrubConfig) AppendArgs() []string {
+	return strings.Fields(cfg.Vars["linux_append"])
+}
+
+// HasKernelArg reports whether args contains arg. If arg has no value any
+// value for the key is accepted.
+func HasKernelArg(args []string, arg string) bool {
+	expected := strings.SplitN(arg, "=", 2)
+	for _, a := range args {
+		actual := strings.SplitN(a, "=", 2)
+		if actual[0] != expected[0] {
+			continue
+		}
+		if len(expected) == 1 || (len(actual) == 2 && actual[1] == expected[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithKernelArgs returns a copy of the test that also expects args on the
+// installed kernel command line
+func (test Test) WithKernelArgs(args ...string) Test {
+	test.KernelArgs = append(append([]string{}, test.KernelArgs...), args...)
+	return test
+}
+
+func readGrubConfig(t *testing.T, path string) []byte {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("couldn't read %s: %v", path, err)
+	}
+	return data
+}
+
+// InstalledKernelArgs finds the grub configs on the mounted partitions and
+// returns the args of every kernel line. If the main grub.cfg isn't found
+// only the args appended by the OEM grub.cfg are returned, as a single
+// line, and oemOnly is set.
+func (test Test) InstalledKernelArgs(t *testing.T, mountPaths []string) (lines []KernelLine, oemOnly bool) {
+	mainConfig, oemConfig := findGrubConfigs(t, mountPaths)
+	if mainConfig == nil && oemConfig == nil {
+		t.Fatalf("couldn't find grub.cfg")
+	}
+
+	if mainConfig == nil {
+		cfg := ParseGrubConfig(oemConfig, nil)
+		return []KernelLine{{Command: "OEM grub.cfg", Args: cfg.AppendArgs()}}, true
+	}
+
+	cfg := parseInstalledGrubConfig(mainConfig, oemConfig)
+	if len(cfg.Kernels) == 0 {
+		t.Fatalf("couldn't find any linux lines in grub.cfg")
+	}
+	return cfg.Kernels, false
+}
+
+// InstalledGrubConfig parses the main grub.cfg on the mounted partitions
+// with the OEM grub.cfg it sources
+func (test Test) InstalledGrubConfig(t *testing.T, mountPaths []string) *GrubConfig {
+	mainConfig, oemConfig := findGrubConfigs(t, mountPaths)
+	if mainConfig == nil {
+		t.Fatalf("couldn't find %s", grubMainConfigPath)
+	}
+	return parseInstalledGrubConfig(mainConfig, oemConfig)
+}
+
+func findGrubConfigs(t *testing.T, mountPaths []string) (mainConfig, oemConfig []byte) {
+	for _, p := range mountPaths {
+		if path := filepath.Join(p, grubMainConfigPath); fileExists(path) {
+			mainConfig = readGrubConfig(t, path)
+		} else if path := filepath.Join(p, grubOEMConfigPath); fileExists(path) {
+			oemConfig = readGrubConfig(t, path)
+		}
+	}
+	return
+}
+
+func parseInstalledGrubConfig(mainConfig, oemConfig []byte) *GrubConfig {
+	return ParseGrubConfig(mainConfig, func(path string) []byte {
+		if filepath.Base(path) == grubOEMConfigPath {
+			return oemConfig
+		}
+		return nil
+	})
+}
+
+// ValidateKernelArgs asserts that every expected arg ends up on the kernel
+// command line, see InstalledKernelArgs
+func (test Test) ValidateKernelArgs(t *testing.T, mountPaths []string, expected []string) {
+	kernels, oemOnly := test.InstalledKernelArgs(t, mountPaths)
+	if oemOnly {
+		for _, arg := range expected {
+			if !HasKernelArg(kernels[0].Args, arg) {
+				t.Fatalf("OEM grub.cfg is missing kernel arg %q: received %q", arg, kernels[0].Args)
+			}
+		}
+		return
+	}
+
+	for _, kernel := range kernels {
+		for _, arg := range append(append([]string{}, DefaultKernelArgs...), expected...) {
+			if !HasKernelArg(kernel.Args, arg) {
+				t.Fatalf("%s %s is missing kernel arg %q: received %q", kernel.Command, kernel.Path, arg, kernel.Args)
+			}
+		}
+
+		usrFound := false
+		for _, arg := range usrKernelArgs {
+			usrFound = usrFound || HasKernelArg(kernel.Args, arg)
+		}
+		if !usrFound {
+			t.Fatalf("%s %s doesn't set the usr partition: received %q", kernel.Command, kernel.Path, kernel.Args)
+		}
+	}
+}
+
+// KernelArgValues returns every value given for key, in order. The kernel
+// and Ignition use the last one.
+func KernelArgValues(args []string, key string) (values []string) {
+	for _, a := range args {
+		parts := strings.SplitN(a, "=", 2)
+		if parts[0] == key && len(parts) == 2 {
+			values = append(values, parts[1])
+		}
+	}
+	return
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return !os.IsNotExist(err)
+}
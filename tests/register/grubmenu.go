@@ -0,0 +1,132 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// DefaultGrubEntry is the --id of the entry the image boots by default,
+// which picks USR-A or USR-B with gptprio
+const DefaultGrubEntry = "coreos"
+
+// GrubMenu is what ValidateGrubMenu expects of the installed menu
+type GrubMenu struct {
+	// --id or title of the default entry, DefaultGrubEntry if empty
+	Default string
+	// args every kernel line of every entry must have, like those an
+	// install option adds through linux_append
+	KernelArgs []string
+	// console= values of every kernel line in order, not checked if nil
+	Consoles []string
+}
+
+// ValidateGrubMenu asserts the installed grub.cfg boots the expected
+// default entry, can fall back to either usr partition, and passes the
+// expected args and consoles on every kernel line
+func (test Test) ValidateGrubMenu(t *testing.T, mountPaths []string, expected GrubMenu) {
+	cfg := test.InstalledGrubConfig(t, mountPaths)
+
+	name := expected.Default
+	if name == "" {
+		name = DefaultGrubEntry
+	}
+	if cfg.Vars["default"] != name {
+		t.Fatalf("grub.cfg defaults to %q, expected %q", cfg.Vars["default"], name)
+	}
+	def, ok := cfg.DefaultEntry()
+	if !ok {
+		t.Fatalf("grub.cfg has no menu entry %q to default to", name)
+	}
+	if len(def.Kernels) == 0 {
+		t.Fatalf("default entry %q has no kernel lines", def.Title)
+	}
+
+	// the default entry boots whichever usr partition gptprio picks, and
+	// there's an entry for each to fall back to by hand
+	for _, usr := range []string{"a", "b"} {
+		kernel := "vmlinuz-" + usr
+		if !bootsKernel(def, kernel) {
+			t.Fatalf("default entry %q never boots %s: %+v", def.Title, kernel, def.Kernels)
+		}
+		if _, ok := onlyBooting(cfg, kernel); !ok {
+			t.Fatalf("grub.cfg has no entry booting only %s to fall back to", kernel)
+		}
+	}
+	a, _ := onlyBooting(cfg, "vmlinuz-a")
+	b, _ := onlyBooting(cfg, "vmlinuz-b")
+	if reflect.DeepEqual(usrArgs(a.Kernels[0].Args), usrArgs(b.Kernels[0].Args)) {
+		t.Fatalf("entries %q and %q mount the same usr partition: %q", a.Title, b.Title, usrArgs(a.Kernels[0].Args))
+	}
+
+	for _, entry := range cfg.Entries {
+		for _, kernel := range entry.Kernels {
+			for _, arg := range expected.KernelArgs {
+				if !HasKernelArg(kernel.Args, arg) {
+					t.Fatalf("entry %q %s is missing kernel arg %q: received %q", entry.Title, kernel.Path, arg, kernel.Args)
+				}
+			}
+
+			if expected.Consoles == nil {
+				continue
+			}
+			if consoles := KernelArgValues(kernel.Args, "console"); !reflect.DeepEqual(consoles, expected.Consoles) {
+				t.Fatalf("entry %q %s has consoles %q, expected %q", entry.Title, kernel.Path, consoles, expected.Consoles)
+			}
+		}
+	}
+}
+
+func bootsKernel(entry MenuEntry, kernel string) bool {
+	for _, k := range entry.Kernels {
+		if path.Base(k.Path) == kernel {
+			return true
+		}
+	}
+	return false
+}
+
+// onlyBooting finds an entry whose every kernel line boots kernel
+func onlyBooting(cfg *GrubConfig, kernel string) (MenuEntry, bool) {
+	for _, entry := range cfg.Entries {
+		if len(entry.Kernels) == 0 {
+			continue
+		}
+		only := true
+		for _, k := range entry.Kernels {
+			only = only && path.Base(k.Path) == kernel
+		}
+		if only {
+			return entry, true
+		}
+	}
+	return MenuEntry{}, false
+}
+
+// usrArgs are the args that pick the usr partition
+func usrArgs(args []string) []string {
+	var usr []string
+	for _, arg := range args {
+		for _, key := range usrKernelArgs {
+			if strings.HasPrefix(arg, key+"=") {
+				usr = append(usr, arg)
+			}
+		}
+	}
+	return usr
+}
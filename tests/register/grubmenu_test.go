@@ -0,0 +1,106 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// a trimmed down grub.cfg from the image, and the OEM grub.cfg the
+// installer writes for -i
+const (
+	testMainGrubConfig = `set default="coreos"
+set timeout=1
+set linux_console="console=ttyS0,115200n8 console=tty0"
+set linux_append=""
+
+if [ -f "($root)/grub.cfg" ]; then
+    source "($root)/grub.cfg"
+fi
+
+function gptprio {
+    gptprio.next -d usr_device -u usr_uuid
+}
+
+menuentry "CoreOS default" --id=coreos {
+    gptprio
+    if [ "$usr_uuid" = "7130c94a-213a-4e5a-8e26-6cce9662f132" ]; then
+        linux$suf /coreos/vmlinuz-a mount.usr=PARTUUID=$usr_uuid root=LABEL=ROOT mount.usrflags=ro $linux_console $linux_append
+    else
+        linux$suf /coreos/vmlinuz-b mount.usr=PARTUUID=$usr_uuid root=LABEL=ROOT mount.usrflags=ro $linux_console $linux_append
+    fi
+}
+
+menuentry "CoreOS USR-A" --id=coreos-a {
+    linux$suf /coreos/vmlinuz-a mount.usr=PARTLABEL=USR-A root=LABEL=ROOT mount.usrflags=ro $linux_console $linux_append
+}
+
+menuentry "CoreOS USR-B" --id=coreos-b {
+    linux$suf /coreos/vmlinuz-b mount.usr=PARTLABEL=USR-B root=LABEL=ROOT mount.usrflags=ro $linux_console $linux_append
+}
+`
+	testOEMGrubConfig = `set linux_append="$linux_append coreos.config.url=oem:///coreos-install.json"
+`
+)
+
+func writeTestGrubConfigs(t *testing.T, main string) []string {
+	esp, oem := filepath.Join(t.TempDir(), "esp"), filepath.Join(t.TempDir(), "oem")
+	if err := os.MkdirAll(filepath.Join(esp, filepath.Dir(grubMainConfigPath)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(esp, grubMainConfigPath), []byte(main), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(oem, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(oem, grubOEMConfigPath), []byte(testOEMGrubConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return []string{esp, oem}
+}
+
+func TestGrubMenuEntries(t *testing.T) {
+	cfg := Test{}.InstalledGrubConfig(t, writeTestGrubConfigs(t, testMainGrubConfig))
+
+	if len(cfg.Entries) != 3 {
+		t.Fatalf("parsed %d entries, expected 3: %+v", len(cfg.Entries), cfg.Entries)
+	}
+	def, ok := cfg.DefaultEntry()
+	if !ok || def.Title != "CoreOS default" || len(def.Kernels) != 2 {
+		t.Fatalf("default entry is %+v", def)
+	}
+	for _, name := range []string{"coreos-b", "CoreOS USR-B", "2"} {
+		if entry, ok := cfg.Entry(name); !ok || entry.ID != "coreos-b" {
+			t.Errorf("entry %q is %+v", name, entry)
+		}
+	}
+	if _, ok := cfg.Entry("3"); ok {
+		t.Errorf("found an entry past the last one")
+	}
+	if len(cfg.Kernels) != 4 {
+		t.Errorf("parsed %d kernel lines, expected 4", len(cfg.Kernels))
+	}
+}
+
+func TestValidateGrubMenu(t *testing.T) {
+	Test{}.ValidateGrubMenu(t, writeTestGrubConfigs(t, testMainGrubConfig), GrubMenu{
+		KernelArgs: []string{"coreos.config.url=oem:///coreos-install.json", "root=LABEL=ROOT"},
+		Consoles:   []string{"ttyS0,115200n8", "tty0"},
+	})
+}
@@ -0,0 +1,99 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"net"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// RunCoreOSInstallHermetic installs from the local fixture mirror, setting
+// BaseURL itself, from inside a network namespace that can only reach the
+// mirror. Every connect() is traced and the test fails if the installer
+// tried to reach anything else, so ignoring -b anywhere is caught even
+// though the namespace would have stopped the connection.
+func (test Test) RunCoreOSInstallHermetic(t *testing.T, opts InstallOptions) InstallResult {
+	return test.RunCoreOSInstallHermeticWith(t, opts, Hermetic{})
+}
+
+// Hermetic hooks into RunCoreOSInstallHermeticWith around the install
+type Hermetic struct {
+	// called once the fixture is serving, e.g. to skip if the mirror is
+	// missing an image or to point -b somewhere else in the mirror
+	Setup func(t *testing.T, fixture *FixtureServer, opts *InstallOptions)
+	// called after the install, e.g. to check what was downloaded
+	Check func(t *testing.T, fixture *FixtureServer, result InstallResult)
+}
+
+// RunCoreOSInstallHermeticWith is RunCoreOSInstallHermetic with hooks
+func (test Test) RunCoreOSInstallHermeticWith(t *testing.T, opts InstallOptions, hooks Hermetic) InstallResult {
+	if _, err := exec.LookPath("strace"); err != nil {
+		t.Skipf("strace is required to trace the installer's connections: %v", err)
+	}
+
+	ns := test.CreateNetNS(t)
+	defer test.CleanupNetNS(t, ns)
+
+	fixture := test.StartFixtureServer(t, ns.HostAddr)
+	defer fixture.Close()
+
+	board := opts.Board
+	if board == "" {
+		board = DefaultBoard()
+	}
+	opts.BaseURL = fixture.BaseURL(board)
+	if hooks.Setup != nil {
+		hooks.Setup(t, fixture, &opts)
+	}
+
+	// keep the trace with the test's artifacts if they're being collected
+	tracePath := ArtifactPath(t, "connect.trace")
+	if tracePath == "" {
+		tracePath = filepath.Join(test.TempDir(t, "coreos-install-trace"), "connect.trace")
+	}
+
+	inv := Invocation{
+		NetNS:  ns,
+		Strace: &Strace{Filter: "trace=connect", Output: tracePath},
+	}
+	result := test.RunCoreOSInstallWith(t, inv, opts.Args()...)
+
+	host, port, err := net.SplitHostPort(fixture.Addr())
+	if err != nil {
+		t.Fatalf("couldn't parse fixture address %s: %v", fixture.Addr(), err)
+	}
+
+	for _, c := range test.TracedConnections(t, inv.Strace.Output) {
+		if c.Addr != host || strconv.Itoa(c.Port) != port {
+			t.Fatalf("coreos-install connected to %s port %d instead of only the fixture at %s", c.Addr, c.Port, fixture.Addr())
+		}
+	}
+
+	result.Usage.BytesDownloaded = fixture.BytesServed()
+	t.Logf("coreos-install downloaded %d bytes from the fixture", result.Usage.BytesDownloaded)
+	saveUsage(t, result.Usage)
+
+	if len(fixture.Requests()) == 0 {
+		t.Fatalf("coreos-install didn't download anything from the fixture at %s", opts.BaseURL)
+	}
+
+	if hooks.Check != nil {
+		hooks.Check(t, fixture, result)
+	}
+	return result
+}
@@ -0,0 +1,104 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+// ValidateIdempotentInstall installs onto one fresh device once and onto
+// another twice in a row with the same options, then fails if the disks
+// differ in their partition tables or the files on their partitions.
+// validate, if set, is run against the disk that was installed twice.
+func (test Test) ValidateIdempotentInstall(t *testing.T, opts func(t *testing.T, device string) []string, validate func(t *testing.T, diskFile string, partitions []Partition)) {
+	onceDisk, onceLoop := test.CreateDevice(t)
+	defer test.CleanupDisk(t, onceDisk, onceLoop)
+	test.RunCoreOSInstall(t, opts(t, onceLoop)...)
+
+	twiceDisk, twiceLoop := test.CreateDevice(t)
+	defer test.CleanupDisk(t, twiceDisk, twiceLoop)
+	twiceOpts := opts(t, twiceLoop)
+	test.RunCoreOSInstall(t, twiceOpts...)
+	test.RunCoreOSInstall(t, twiceOpts...)
+
+	onceTable := test.ListPartitions(t, onceDisk)
+	twiceTable := test.ListPartitions(t, twiceDisk)
+	if len(onceTable) != len(twiceTable) {
+		t.Fatalf("installing twice left %d partitions, installing once left %d", len(twiceTable), len(onceTable))
+	}
+	for i := range onceTable {
+		if onceTable[i] != twiceTable[i] {
+			t.Fatalf("partition %d differs after installing twice: expected %+v, received %+v", onceTable[i].Number, onceTable[i], twiceTable[i])
+		}
+	}
+
+	once := test.MountPartitions(t, onceDisk, onceLoop)
+	defer test.UnmountPartitions(t, onceLoop, once)
+	twice := test.MountPartitions(t, twiceDisk, twiceLoop)
+	defer test.UnmountPartitions(t, twiceLoop, twice)
+
+	for _, p := range once {
+		q, ok := FindPartition(twice, p.Label)
+		if !ok || (p.MountPath == "") != (q.MountPath == "") {
+			t.Fatalf("%s is mounted after installing once but not twice", p.Label)
+		}
+		if p.MountPath == "" {
+			continue
+		}
+
+		onceTree, err := util.WalkTree(p.MountPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		twiceTree, err := util.WalkTree(q.MountPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if diffs := util.DiffTrees(onceTree, twiceTree); len(diffs) != 0 {
+			test.SaveTree(t, p.Label+"-once", onceTree)
+			test.SaveTree(t, p.Label+"-twice", twiceTree)
+			t.Fatalf("%s differs after installing twice:\n%s", p.Label, strings.Join(diffs, "\n"))
+		}
+	}
+
+	if validate != nil {
+		validate(t, twiceDisk, twice)
+	}
+}
+
+// SaveTree writes a manifest to the test's artifacts, if they're being
+// collected
+func (test Test) SaveTree(t *testing.T, name string, tree util.Tree) {
+	path := ArtifactPath(t, name+".tree")
+	if path == "" {
+		return
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Errorf("couldn't save %s: %v", name, err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := tree.WriteTo(f); err != nil {
+		t.Errorf("couldn't save %s: %v", name, err)
+	}
+}
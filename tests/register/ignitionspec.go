@@ -0,0 +1,147 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// IgnitionSpec is a version of the Ignition config spec the installer has
+// to carry through to first boot untouched
+type IgnitionSpec struct {
+	Version string
+	// writes /etc/ignition-spec with the spec's own syntax for files
+	Config string
+}
+
+var IgnitionSpecs = []IgnitionSpec{
+	{
+		Version: "2.0.0",
+		Config: `{
+			"ignition": {"version": "2.0.0"},
+			"storage": {"files": [{
+				"filesystem": "root",
+				"path": "/etc/ignition-spec",
+				"contents": {"source": "data:,2.0.0"},
+				"mode": 420,
+				"user": {"id": 0}
+			}]}
+		}`,
+	},
+	{
+		Version: "2.1.0",
+		Config: `{
+			"ignition": {"version": "2.1.0"},
+			"storage": {"files": [{
+				"filesystem": "root",
+				"path": "/etc/ignition-spec",
+				"contents": {"source": "data:,2.1.0"},
+				"mode": 420,
+				"user": {"name": "root"}
+			}]}
+		}`,
+	},
+	{
+		Version: "2.2.0",
+		Config: `{
+			"ignition": {"version": "2.2.0"},
+			"storage": {"files": [{
+				"filesystem": "root",
+				"path": "/etc/ignition-spec",
+				"contents": {"source": "data:,2.2.0"},
+				"mode": 420,
+				"append": false
+			}]}
+		}`,
+	},
+	{
+		Version: "3.0.0",
+		Config: `{
+			"ignition": {"version": "3.0.0"},
+			"storage": {"files": [{
+				"path": "/etc/ignition-spec",
+				"contents": {"source": "data:,3.0.0"},
+				"mode": 420,
+				"overwrite": true
+			}]}
+		}`,
+	},
+}
+
+// ignitionConfig is the part of every spec version that's checked
+type ignitionConfig struct {
+	Ignition struct {
+		Version string `json:"version"`
+	} `json:"ignition"`
+	Storage struct {
+		Files []map[string]interface{} `json:"files"`
+	} `json:"storage"`
+}
+
+// Check reports why config isn't a config for the spec: the version has to
+// match and files have to name their filesystem in 2.x and can't in 3.x
+func (s IgnitionSpec) Check(config []byte) error {
+	var c ignitionConfig
+	if err := json.Unmarshal(config, &c); err != nil {
+		return fmt.Errorf("couldn't parse Ignition config: %v", err)
+	}
+
+	if c.Ignition.Version != s.Version {
+		return fmt.Errorf("expected spec version %s, received %q", s.Version, c.Ignition.Version)
+	}
+
+	for i, f := range c.Storage.Files {
+		_, hasFilesystem := f["filesystem"]
+		if s.Version[0] == '2' && !hasFilesystem {
+			return fmt.Errorf("storage.files[%d] has no filesystem, which spec %s requires", i, s.Version)
+		}
+		if s.Version[0] != '2' && hasFilesystem {
+			return fmt.Errorf("storage.files[%d] has a filesystem, which spec %s dropped", i, s.Version)
+		}
+	}
+	return nil
+}
+
+// ValidateIgnitionSpec asserts the installed Ignition config is still a
+// config for the spec
+func (test Test) ValidateIgnitionSpec(t *testing.T, partitions []Partition, spec IgnitionSpec) {
+	oem, ok := FindPartition(partitions, "OEM")
+	if !ok || oem.MountPath == "" {
+		t.Fatalf("couldn't find a mounted OEM partition")
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(oem.MountPath, ignitionConfigPath))
+	if err != nil {
+		t.Fatalf("couldn't read %s on OEM: %v", ignitionConfigPath, err)
+	}
+
+	if err := spec.Check(data); err != nil {
+		t.Fatalf("%s on OEM isn't a spec %s config: %v", ignitionConfigPath, spec.Version, err)
+	}
+}
+
+// RunIgnitionSpecMatrix runs f once per spec version as a subtest
+func (test Test) RunIgnitionSpecMatrix(t *testing.T, f func(t *testing.T, spec IgnitionSpec)) {
+	for _, spec := range IgnitionSpecs {
+		spec := spec
+		t.Run("spec "+spec.Version, func(t *testing.T) {
+			f(t, spec)
+		})
+	}
+}
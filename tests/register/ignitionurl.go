@@ -0,0 +1,55 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// ErrIgnitionURLUnavailable is what an installer that takes -i as a URL is
+// expected to fail with when the config can't be downloaded, worded like
+// its image URL errors
+var ErrIgnitionURLUnavailable = InstallerError{"EXIT_IGNITION_URL_UNAVAILABLE", ExitFailure, `Ignition config URL unavailable: `}
+
+var ignitionURLUsage = regexp.MustCompile(`(?m)^\s*-i\s.*\bURL\b`)
+
+// IgnitionURLSupported reports whether the installer's usage documents -i
+// taking a URL
+func (test Test) IgnitionURLSupported(t *testing.T) bool {
+	result := test.TryCoreOSInstallWith(t, Invocation{}, "-h")
+	return ignitionURLUsage.Match(result.Stdout)
+}
+
+// SlowHandler waits delay before serving data, or gives up when the client
+// does
+func SlowHandler(data []byte, delay time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+			w.Write(data)
+		case <-r.Context().Done():
+		}
+	})
+}
+
+// StatusHandler fails every request with code
+func StatusHandler(code int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, http.StatusText(code), code)
+	})
+}
@@ -0,0 +1,492 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/coreos/init/tests/util"
+)
+
+type InstallResult struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+	Duration time.Duration
+	TimedOut bool
+	// the Invocation's Interrupt was sent
+	Interrupted bool
+	// killed after printing nothing for the Invocation's StallTimeout
+	Stalled bool
+	// how long each phase of the install took, from its output
+	Phases []PhaseTiming
+
+	// the installer that was run, and its args
+	Binary string
+	Args   []string
+
+	// processes the OOM killer killed, with the Invocation's MemoryLimit
+	OOMKills int
+
+	// what the install cost, see ResourceUsage
+	Usage ResourceUsage
+
+	// temp files the installer created and didn't remove, see
+	// ValidateTempCleanup
+	TempLeaks []LeakedFile
+}
+
+// DefaultInstallTimeout is long enough to download and write a full image
+// on a slow mirror, COREOS_TEST_INSTALL_TIMEOUT overrides it
+var DefaultInstallTimeout = config.InstallTimeout
+
+func WhichCoreosInstall(t *testing.T) string {
+	out, err := exec.Command("which", "coreos-install").CombinedOutput()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Dir(string(out))
+}
+
+var (
+	coreosInstallFlag  = flag.String("coreos-install", config.Installer, "path to the coreos-install to test, defaults to the one in this checkout [$COREOS_TEST_INSTALLER]")
+	implementationFlag = flag.String("implementation", config.Implementation, "test the checkout's script or its go port [$COREOS_TEST_IMPLEMENTATION]")
+)
+
+// the tests run from their package directory, so the checkout's bin is one
+// or two levels up
+var checkoutInstallPaths = []string{
+	filepath.Join("..", "bin", "coreos-install"),
+	filepath.Join("..", "..", "bin", "coreos-install"),
+}
+
+// CoreosInstallPath resolves the installer under test. The -coreos-install
+// flag wins, then the script from this checkout so the code under review is
+// tested, or its go port with -implementation go, and finally whatever is in
+// PATH.
+func CoreosInstallPath(t *testing.T) string {
+	if *coreosInstallFlag != "" {
+		path, err := filepath.Abs(*coreosInstallFlag)
+		if err != nil {
+			t.Fatalf("couldn't resolve -coreos-install %s: %v", *coreosInstallFlag, err)
+		}
+		return path
+	}
+
+	if *implementationFlag == "go" {
+		return goInstallPath(t)
+	}
+
+	for _, p := range checkoutInstallPaths {
+		if info, err := os.Stat(p); err == nil && info.Mode().IsRegular() {
+			path, err := filepath.Abs(p)
+			if err != nil {
+				t.Fatalf("couldn't resolve %s: %v", p, err)
+			}
+			return path
+		}
+	}
+
+	path, err := exec.LookPath("coreos-install")
+	if err != nil {
+		t.Fatalf("couldn't find coreos-install: %v", err)
+	}
+	return path
+}
+
+var (
+	goInstallOnce sync.Once
+	goInstall     string
+	goInstallErr  error
+)
+
+// goInstallPath builds the go port from this checkout once for the whole
+// suite
+func goInstallPath(t *testing.T) string {
+	goInstallOnce.Do(func() {
+		// not the test's TMPDIR, the binary outlives the test that built it
+		dir, err := ioutil.TempDir(config.TempDir, "coreos-install-go")
+		if err != nil {
+			goInstallErr = err
+			return
+		}
+
+		goInstall = filepath.Join(dir, "coreos-install")
+		cmd := exec.Command("go", "build", "-o", goInstall, "./cmd/coreos-install")
+		cmd.Dir = CheckoutDir(t)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			goInstallErr = fmt.Errorf("%v: %s", err, out)
+		}
+	})
+
+	if goInstallErr != nil {
+		t.Fatalf("couldn't build the go coreos-install: %v", goInstallErr)
+	}
+	return goInstall
+}
+
+// Invocation controls how the installer process is started, the zero value
+// runs coreos-install from PATH with the test's environment
+type Invocation struct {
+	// installer to run, defaults to CoreosInstallPath
+	Binary string
+
+	// set in the installer's environment only, on top of the test's own
+	Env map[string]string
+	// start from an empty environment instead of the test's, only Env is
+	// passed to the installer
+	ClearEnv bool
+
+	// the installer and everything it spawned are killed after this long,
+	// defaults to DefaultInstallTimeout
+	Timeout time.Duration
+
+	// run the installer inside a container instead of on the host
+	Container *Container
+
+	// working directory, defaults to the test's
+	Dir string
+	// remove Dir once the installer has started in it
+	DeleteDir bool
+
+	// run the installer as another user, see UnprivilegedInvocation
+	Credential *syscall.Credential
+
+	// fed to the installer's stdin, for configs passed as "-"
+	Stdin io.Reader
+	// how stdin and the controlling terminal are set up, can't be combined
+	// with Stdin
+	Terminal TerminalMode
+	// passed as extra file descriptors starting at 3, like bash process
+	// substitution, see PipePath
+	Pipes [][]byte
+
+	// signal the installer once it reaches a phase
+	Interrupt *Interrupt
+	// kill the installer if it prints nothing for this long
+	StallTimeout time.Duration
+
+	// skew the installer's wall clock by this much with libfaketime,
+	// skipped if it isn't installed
+	ClockSkew time.Duration
+
+	// run the installer in a cgroup limited to this many bytes of memory
+	// and no swap, skipped on hosts without cgroup v2
+	MemoryLimit int64
+
+	// run the installer inside a network namespace, see CreateNetNS
+	NetNS *NetNS
+	// run the installer in a private mount namespace that has these in
+	// /run/systemd/network, see CreateNetworkUnits
+	NetworkUnits *NetworkUnits
+	// trace the installer's system calls
+	Strace *Strace
+}
+
+// PipePath is where the installer can read Pipes[i]
+func PipePath(i int) string {
+	return fmt.Sprintf("/dev/fd/%d", 3+i)
+}
+
+func (inv Invocation) environ() []string {
+	env := os.Environ()
+	if inv.ClearEnv {
+		env = nil
+	}
+
+	if len(inv.Env) == 0 {
+		return env
+	}
+
+	var merged []string
+	for _, kv := range env {
+		if _, ok := inv.Env[strings.SplitN(kv, "=", 2)[0]]; !ok {
+			merged = append(merged, kv)
+		}
+	}
+
+	for k, v := range inv.Env {
+		merged = append(merged, k+"="+v)
+	}
+	return merged
+}
+
+// RunCoreOSInstall runs the installer and fails the test if it doesn't
+// succeed
+func (test Test) RunCoreOSInstall(t *testing.T, opts ...string) InstallResult {
+	return test.RunCoreOSInstallWith(t, Invocation{}, opts...)
+}
+
+// RunCoreOSInstallEnv runs the installer with extra environment variables,
+// e.g. http_proxy or LANG, without changing the test's own environment
+func (test Test) RunCoreOSInstallEnv(t *testing.T, env map[string]string, opts ...string) InstallResult {
+	return test.RunCoreOSInstallWith(t, Invocation{Env: env}, opts...)
+}
+
+func (test Test) RunCoreOSInstallWith(t *testing.T, inv Invocation, opts ...string) InstallResult {
+	if test.chaos != nil {
+		device := deviceArg(opts)
+		before, _ := util.ReadGPT(device)
+		result := test.TryCoreOSInstallWith(t, inv, opts...)
+		if result.TimedOut || result.Stalled || result.ExitCode != 0 {
+			test.validateChaosFailure(t, result, device, before)
+		}
+		return result
+	}
+
+	result := test.TryCoreOSInstallWith(t, inv, opts...)
+	if result.TimedOut || result.Stalled || result.ExitCode != 0 {
+		t.Fatalf("%s failed with %s", util.FormatCommand("coreos-install", opts...), ExitName(result))
+	}
+	test.ValidateTempCleanup(t, result)
+	return result
+}
+
+// TryCoreOSInstall runs the installer and returns its output and exit code
+// without failing the test, for tests that expect the install to fail
+func (test Test) TryCoreOSInstall(t *testing.T, opts ...string) InstallResult {
+	return test.TryCoreOSInstallWith(t, Invocation{}, opts...)
+}
+
+func (test Test) TryCoreOSInstallWith(t *testing.T, inv Invocation, opts ...string) InstallResult {
+	if test.injects(ChaosTerminate) && inv.Interrupt == nil {
+		inv.Interrupt = &chaosTerminate
+	}
+
+	// an empty TMPDIR of its own, so anything left in it afterwards was
+	// leaked by this install, unless the installer runs in a container that
+	// doesn't have it or from an empty environment
+	if _, ok := inv.Env["TMPDIR"]; !ok && inv.Container == nil && !inv.ClearEnv {
+		env := map[string]string{"TMPDIR": test.TempDir(t, "coreos-install-tmp")}
+		for k, v := range inv.Env {
+			env[k] = v
+		}
+		inv.Env = env
+	}
+	tmpDir := inv.Env["TMPDIR"]
+
+	if inv.ClockSkew != 0 {
+		inv.Env = faketimeEnv(t, inv.Env, inv.ClockSkew)
+	}
+
+	timeout := inv.Timeout
+	if timeout == 0 {
+		timeout = DefaultInstallTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	binary := inv.Binary
+	if binary == "" {
+		binary = test.installer
+	}
+	if binary == "" {
+		binary = CoreosInstallPath(t)
+	}
+	t.Logf("running %s", util.FormatCommand(binary, opts...))
+
+	name, args := binary, opts
+	if inv.DeleteDir {
+		name, args = "/bin/sh", append([]string{"-c", `rmdir "$PWD" && exec "$0" "$@"`, binary}, opts...)
+	}
+
+	if debug := debugStrace(t); debug != nil {
+		if inv.Strace != nil {
+			// strace can't attach to a process that's already traced
+			t.Logf("not applying -strace, the test traces the install itself")
+		} else {
+			inv.Strace = debug
+		}
+	}
+	if inv.Strace != nil {
+		t.Logf("tracing coreos-install to %s", inv.Strace.Output)
+		name, args = inv.Strace.command(name, args)
+	}
+	stopCapture := test.debugCapture(t, &inv, opts)
+	defer stopCapture()
+	if inv.NetNS != nil {
+		name, args = inv.NetNS.command(name, args)
+	}
+	if inv.NetworkUnits != nil {
+		name, args = inv.NetworkUnits.command(name, args)
+	}
+
+	env := inv.environ()
+	if inv.Container != nil {
+		name, args = inv.Container.command(binary, inv.Env, opts)
+		env = os.Environ()
+		defer inv.Container.remove()
+	}
+
+	var stdout, stderr bytes.Buffer
+	// the installer spawns wget, gpg and dd, util.Command runs it in its
+	// own process group so they can all be killed together
+	cmd := util.Command(ctx, name, args...)
+	cmd.Env = env
+	cmd.SysProcAttr.Credential = inv.Credential
+	cmd.Dir = inv.Dir
+
+	var cgroup *installCgroup
+	if inv.MemoryLimit != 0 {
+		cgroup = createCgroup(t, inv.MemoryLimit)
+		defer cgroup.remove(t)
+		cgroup.apply(cmd.SysProcAttr)
+	}
+
+	start := time.Now()
+	timer := newPhaseTimer(start)
+	log := newInstallLog(t, start)
+	defer log.Close()
+
+	stdoutLog, stderrLog := log.Writer("stdout"), log.Writer("stderr")
+	defer stdoutLog.Flush()
+	defer stderrLog.Flush()
+
+	stdoutWriters := []io.Writer{&stdout, stdoutLog, timer}
+	stderrWriters := []io.Writer{&stderr, stderrLog}
+
+	var watcher *interrupter
+	if inv.Interrupt != nil {
+		watcher = newInterrupter(ctx, inv.Interrupt, func() {
+			t.Logf("sending %v to coreos-install", inv.Interrupt.Signal)
+			syscall.Kill(-cmd.Process.Pid, inv.Interrupt.Signal)
+		})
+		stdoutWriters = append(stdoutWriters, watcher)
+		stderrWriters = append(stderrWriters, watcher)
+	}
+
+	var stalled atomic.Bool
+	if inv.StallTimeout != 0 {
+		progress := util.NewExpecter()
+		stdoutWriters = append(stdoutWriters, progress)
+		stderrWriters = append(stderrWriters, progress)
+
+		go func() {
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if progress.Idle() > inv.StallTimeout {
+						stalled.Store(true)
+						cancel()
+						return
+					}
+				}
+			}
+		}()
+	}
+	cmd.Stdout = io.MultiWriter(stdoutWriters...)
+	cmd.Stderr = io.MultiWriter(stderrWriters...)
+
+	cmd.Stdin = inv.Stdin
+	for i, data := range inv.Pipes {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("couldn't create pipe %d: %v", i, err)
+		}
+		defer r.Close()
+
+		go func(data []byte) {
+			w.Write(data)
+			w.Close()
+		}(data)
+		cmd.ExtraFiles = append(cmd.ExtraFiles, r)
+	}
+
+	finishTerminal := setupTerminal(t, inv.Terminal, cmd)
+
+	var tempBefore TempSnapshot
+	if inv.Container == nil {
+		tempBefore = snapshotTemp(tmpDir)
+	}
+
+	monitor := startUsageMonitor(t, deviceArg(opts), tmpDir)
+	err := cmd.Start()
+	if err == nil {
+		if watcher != nil {
+			util.WaitExited(cmd.Process.Pid)
+			watcher.Exited()
+		}
+		err = cmd.Wait()
+	}
+	// reap anything the installer left behind
+	util.Reap(cmd)
+	finishTerminal()
+	usage := monitor.Stop(t)
+
+	result := InstallResult{
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+		Duration: time.Since(start),
+		TimedOut: ctx.Err() == context.DeadlineExceeded,
+		Binary:   binary,
+		Args:     opts,
+		Usage:    usage,
+	}
+	if tempBefore != nil {
+		result.TempLeaks = tempBefore.Leaks(snapshotTemp(tmpDir))
+	}
+	result.Phases = timer.Phases(result.Duration)
+
+	if result.TimedOut {
+		t.Logf("coreos-install timed out after %v", timeout)
+	}
+
+	if watcher != nil {
+		result.Interrupted = watcher.Stop()
+	}
+
+	if cgroup != nil {
+		result.OOMKills = cgroup.OOMKills(t)
+		if result.OOMKills != 0 {
+			t.Logf("the OOM killer killed %d processes in coreos-install's %d byte cgroup", result.OOMKills, inv.MemoryLimit)
+		}
+	}
+
+	result.Stalled = stalled.Load()
+	if result.Stalled {
+		t.Logf("coreos-install printed nothing for %v and was killed", inv.StallTimeout)
+	}
+
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		result.ExitCode = exitErr.ExitCode()
+	} else if err != nil && !result.TimedOut && !result.Stalled {
+		t.Fatalf("couldn't run coreos-install: %v", err)
+	}
+
+	t.Logf("coreos-install finished in %v with exit code %d", result.Duration, result.ExitCode)
+	logPhases(t, result.Phases)
+	logUsage(t, result.Usage)
+	return result
+}
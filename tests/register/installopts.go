@@ -0,0 +1,92 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+// InstallOptions are coreos-install's flags, empty fields are left out
+type InstallOptions struct {
+	// -d
+	Device string
+	// -V
+	Version string
+	// -B
+	Board string
+	// -C
+	Channel string
+	// -o
+	OEM string
+	// -c
+	CloudConfigPath string
+	// -i
+	IgnitionPath string
+	// -b
+	BaseURL string
+	// -k
+	KeyFile string
+	// -f
+	ImageFile string
+	// -n
+	CopyNetwork bool
+	// -v
+	Verbose bool
+
+	// appended after everything else as is, for repeated or malformed
+	// flags
+	Extra []string
+}
+
+// Args renders the options as the installer's argv, in the order its usage
+// lists them
+func (o InstallOptions) Args() []string {
+	var args []string
+	for _, f := range []struct {
+		flag  string
+		value string
+	}{
+		{"-d", o.Device},
+		{"-V", o.Version},
+		{"-B", o.Board},
+		{"-C", o.Channel},
+		{"-o", o.OEM},
+		{"-c", o.CloudConfigPath},
+		{"-i", o.IgnitionPath},
+		{"-b", o.BaseURL},
+		{"-k", o.KeyFile},
+		{"-f", o.ImageFile},
+	} {
+		if f.value != "" {
+			args = append(args, f.flag, f.value)
+		}
+	}
+
+	if o.CopyNetwork {
+		args = append(args, "-n")
+	}
+	if o.Verbose {
+		args = append(args, "-v")
+	}
+	return append(args, o.Extra...)
+}
+
+// flagArg is the value of the last occurrence of flag in args, like the
+// installer's getopts reads it
+func flagArg(args []string, flag string) string {
+	value := ""
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == flag {
+			value = args[i+1]
+		}
+	}
+	return value
+}
@@ -0,0 +1,195 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+// Interrupt sends Signal to the installer's process group, like a terminal
+// would for ctrl-c, once its output matches Pattern
+type Interrupt struct {
+	Pattern string
+	Signal  syscall.Signal
+	// if set, the signal also waits for Ready to be closed, e.g. by Hold
+	// once the installer is stuck in the middle of a download, so it can't
+	// land after the phase the output announced is already over
+	Ready <-chan struct{}
+}
+
+// interrupter watches the installer's output for the interrupt pattern
+type interrupter struct {
+	*util.Expecter
+	done chan struct{}
+
+	// the signal is only sent before the installer exited, see Exited
+	mu     sync.Mutex
+	fired  bool
+	exited bool
+}
+
+func newInterrupter(ctx context.Context, i *Interrupt, fire func()) *interrupter {
+	w := &interrupter{
+		Expecter: util.NewExpecter(),
+		done:     make(chan struct{}),
+	}
+
+	go func() {
+		defer close(w.done)
+		if _, err := w.ExpectContext(ctx, regexp.MustCompile(i.Pattern)); err != nil {
+			return
+		}
+		if i.Ready != nil {
+			select {
+			case <-i.Ready:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if !w.exited {
+			w.fired = true
+			fire()
+		}
+	}()
+	return w
+}
+
+// Exited stops the signal from being sent, call it once the installer has
+// exited but before it's reaped, while its process group can't belong to
+// anything else yet
+func (w *interrupter) Exited() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.exited = true
+}
+
+// Stop waits for the watcher once the installer exited, and reports whether
+// the signal was sent
+func (w *interrupter) Stop() bool {
+	w.Close()
+	<-w.done
+	return w.fired
+}
+
+// ValidateNoInstallerTempFiles asserts the installer cleaned up its work
+// directory, its trap removes it on both success and failure
+func (test Test) ValidateNoInstallerTempFiles(t *testing.T) {
+	validateNoTempFilesIn(t, test.TempRoot())
+	// and in each install's own TMPDIR
+	dirs, _ := filepath.Glob(filepath.Join(test.TempRoot(), "coreos-install-tmp*"))
+	for _, dir := range dirs {
+		validateNoTempFilesIn(t, dir)
+	}
+}
+
+func validateNoTempFilesIn(t *testing.T, tmpDir string) {
+	leftovers, err := filepath.Glob(filepath.Join(tmpDir, "coreos-install.*"))
+	if err != nil {
+		t.Fatalf("couldn't search %s: %v", tmpDir, err)
+	}
+
+	if len(leftovers) != 0 {
+		t.Fatalf("installer left temp files behind: %v", leftovers)
+	}
+}
+
+// Hold stalls method requests for the mirror's file at path once n bytes of
+// it were sent, counting back from its end if n is negative, until the
+// client gives up. Other requests for it are served as usual. The returned
+// channel is closed once a request is being held.
+func (f *FixtureServer) Hold(method, path string, n int64) <-chan struct{} {
+	file := filepath.Join(*fixtureDirFlag, filepath.FromSlash(path))
+	held := make(chan struct{})
+	var once sync.Once
+
+	f.Handle(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			http.ServeFile(w, r, file)
+			return
+		}
+
+		data, err := os.Open(file)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		defer data.Close()
+		info, err := data.Stat()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		sent := n
+		if sent < 0 {
+			sent += info.Size()
+		}
+		// nothing at all is sent for n == 0, not even the headers, which
+		// is all a HEAD request needs
+		if sent > 0 {
+			w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+			io.CopyN(w, data, sent)
+			w.(http.Flusher).Flush()
+		}
+
+		once.Do(func() { close(held) })
+		<-r.Context().Done()
+	}))
+	return held
+}
+
+// ValidateWiped asserts the partition table the installer wrote was wiped,
+// which it does with wipefs when it is interrupted after it started writing
+// the disk. It zeroes the end of the disk before writing the image, so only
+// the start of the disk shows whether anything was wiped.
+func (test Test) ValidateWiped(t *testing.T, diskFile string) {
+	f, err := os.Open(diskFile)
+	if err != nil {
+		t.Fatalf("couldn't open disk file: %v", err)
+	}
+	defer f.Close()
+
+	start := make([]byte, 2*sectorSize)
+	if _, err := f.ReadAt(start, 0); err != nil {
+		t.Fatalf("couldn't read the start of the disk: %v", err)
+	}
+
+	// wipefs only erases the signatures, the rest of the protective MBR
+	// and GPT header are left behind
+	if bytes.Equal(start, make([]byte, len(start))) {
+		t.Fatalf("the image was never written to the start of the disk")
+	}
+	if bytes.Equal(start[510:512], []byte{0x55, 0xaa}) {
+		t.Fatalf("protective MBR signature wasn't wiped")
+	}
+	if bytes.Equal(start[sectorSize:sectorSize+8], []byte("EFI PART")) {
+		t.Fatalf("primary GPT header wasn't wiped")
+	}
+}
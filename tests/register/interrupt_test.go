@@ -0,0 +1,54 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestInterrupterAfterExit(t *testing.T) {
+	for _, exited := range []bool{false, true} {
+		sent := false
+		w := newInterrupter(context.Background(), &Interrupt{Pattern: "Downloading", Signal: syscall.SIGTERM}, func() { sent = true })
+		if exited {
+			w.Exited()
+		}
+		w.Write([]byte("Downloading the signature\n"))
+
+		// the output may still match after the installer exited, but
+		// its process group could be reused by then
+		if fired := w.Stop(); fired != !exited || sent != !exited {
+			t.Errorf("exited %v: fired %v and sent %v", exited, fired, sent)
+		}
+	}
+}
+
+func TestValidateWiped(t *testing.T) {
+	// a protective MBR and GPT header with their signatures erased the way
+	// wipefs does, and the zeroed end of the disk
+	disk := make([]byte, 1<<20)
+	disk[450] = 0xee
+	copy(disk[sectorSize+8:], []byte{0x00, 0x00, 0x01, 0x00, 0x5c})
+
+	diskFile := filepath.Join(t.TempDir(), "disk")
+	if err := ioutil.WriteFile(diskFile, disk, 0644); err != nil {
+		t.Fatal(err)
+	}
+	Test{}.ValidateWiped(t, diskFile)
+}
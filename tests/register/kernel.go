@@ -0,0 +1,179 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// grub paths can be prefixed with a device, e.g. ($root)/coreos/vmlinuz-a
+var grubDevicePattern = regexp.MustCompile(`^\([^)]*\)`)
+
+// KernelVersion reads the version string embedded in an x86 bzImage setup
+// header. ok is false for images without one, like arm64 Image files.
+func KernelVersion(path string) (version string, ok bool, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", false, err
+	}
+
+	// the setup header starts with "HdrS" at 0x202 and has a pointer to
+	// the version string, relative to 0x200, at 0x20e
+	if len(data) < 0x210 || !bytes.Equal(data[0x202:0x206], []byte("HdrS")) {
+		return "", false, nil
+	}
+
+	offset := int(binary.LittleEndian.Uint16(data[0x20e:0x210])) + 0x200
+	if offset >= len(data) {
+		return "", false, fmt.Errorf("version string offset %d is past the end of %s", offset, path)
+	}
+
+	end := bytes.IndexByte(data[offset:], 0)
+	if end < 0 {
+		return "", false, fmt.Errorf("version string in %s isn't terminated", path)
+	}
+
+	fields := strings.Fields(string(data[offset : offset+end]))
+	if len(fields) == 0 {
+		return "", false, fmt.Errorf("version string in %s is empty", path)
+	}
+	return fields[0], true, nil
+}
+
+// usrSlot is the gptprio slot of a USR partition, "a" for USR-A
+func usrSlot(p Partition) string {
+	return strings.ToLower(strings.TrimPrefix(p.Label, "USR-"))
+}
+
+// ActiveUsrPartition is the USR partition gptprio boots first, the one with
+// the highest priority in its GPT attributes, USR-A on a fresh install
+func ActiveUsrPartition(partitions []Partition) (Partition, bool) {
+	var active Partition
+	found := false
+	for _, label := range []string{USRAPartition.Label, "USR-B"} {
+		p, ok := FindPartition(partitions, label)
+		if ok && (!found || gptPriority(p.Attributes) > gptPriority(active.Attributes)) {
+			active, found = p, true
+		}
+	}
+	return active, found
+}
+
+// gptPriority is the gptprio priority in bits 48-51 of a partition's
+// attributes
+func gptPriority(attributes uint64) uint64 {
+	return attributes >> 48 & 0xf
+}
+
+// ValidateKernels locates the kernel grub.cfg boots for the active USR
+// partition, and any initrd for it, on the ESP and asserts they exist. The
+// inactive slot's kernel is only written by an update, so it isn't
+// required. The kernel's version is cross-checked against the kernel the
+// USR partition ships, and the USR partition's os-release against version
+// unless it's empty.
+func (test Test) ValidateKernels(t *testing.T, partitions []Partition, version string) {
+	esp, ok := FindPartition(partitions, espLabel)
+	if !ok || esp.MountPath == "" {
+		t.Fatalf("couldn't find a mounted %s partition", espLabel)
+	}
+
+	grubPath := filepath.Join(esp.MountPath, grubMainConfigPath)
+	if !fileExists(grubPath) {
+		t.Fatalf("couldn't find %s on %s", grubMainConfigPath, espLabel)
+	}
+
+	cfg := ParseGrubConfig(readGrubConfig(t, grubPath), nil)
+	if len(cfg.Kernels) == 0 {
+		t.Fatalf("couldn't find any linux lines in grub.cfg")
+	}
+
+	usr, ok := ActiveUsrPartition(partitions)
+	if !ok || usr.MountPath == "" {
+		t.Fatalf("couldn't find a mounted active USR partition")
+	}
+	if version != "" {
+		if installed := installedRelease(t, []string{usr.MountPath})["VERSION_ID"]; installed != version {
+			t.Fatalf("%s has version %s, expected %s", usr.Label, installed, version)
+		}
+	}
+
+	name := "vmlinuz-" + usrSlot(usr)
+	var kernel *KernelLine
+	for i, k := range cfg.Kernels {
+		if path.Base(k.Path) == name {
+			kernel = &cfg.Kernels[i]
+			break
+		}
+	}
+	if kernel == nil {
+		t.Fatalf("grub.cfg never boots %s for %s", name, usr.Label)
+	}
+
+	kernelPath := filepath.Join(esp.MountPath, grubDevicePattern.ReplaceAllString(kernel.Path, ""))
+	if !fileExists(kernelPath) {
+		t.Fatalf("kernel %s for %s doesn't exist", kernel.Path, usr.Label)
+	}
+	test.validateKernelVersion(t, kernelPath, usr)
+
+	other := map[string]string{"a": "b", "b": "a"}[usrSlot(usr)]
+	for _, initrd := range cfg.Initrds {
+		// the other slot's is written along with its kernel
+		if strings.HasSuffix(path.Base(initrd), "-"+other) {
+			continue
+		}
+		p := filepath.Join(esp.MountPath, grubDevicePattern.ReplaceAllString(initrd, ""))
+		if !fileExists(p) {
+			t.Fatalf("initrd %s referenced by grub.cfg doesn't exist", initrd)
+		}
+	}
+}
+
+// validateKernelVersion checks the kernel on the ESP is the one the USR
+// partition ships in /usr/boot, or has modules there if it ships none
+func (test Test) validateKernelVersion(t *testing.T, kernelPath string, usr Partition) {
+	version, ok, err := KernelVersion(kernelPath)
+	if err != nil {
+		t.Fatalf("couldn't read version of kernel %s: %v", kernelPath, err)
+	} else if !ok {
+		t.Logf("kernel %s has no version header, skipping version check", kernelPath)
+		return
+	}
+
+	shipped, err := resolveUsr(usr.MountPath, filepath.Join("boot", "vmlinuz"))
+	if err == nil {
+		expected, ok, err := KernelVersion(shipped)
+		if err != nil {
+			t.Fatalf("couldn't read version of /usr/boot/vmlinuz on %s: %v", usr.Label, err)
+		}
+		if ok && version != expected {
+			t.Fatalf("kernel %s is version %s, but %s ships %s", kernelPath, version, usr.Label, expected)
+		}
+		return
+	}
+
+	modules := filepath.Join(usr.MountPath, "lib", "modules", version)
+	if _, err := os.Stat(modules); os.IsNotExist(err) {
+		t.Fatalf("kernel %s is version %s but /usr/lib/modules/%s doesn't exist on %s", kernelPath, version, version, usr.Label)
+	}
+}
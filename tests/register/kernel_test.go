@@ -0,0 +1,79 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestKernel writes a bzImage setup header carrying version
+func writeTestKernel(t *testing.T, path, version string) {
+	data := make([]byte, 0x400)
+	copy(data[0x202:], "HdrS")
+	binary.LittleEndian.PutUint16(data[0x20e:], 0x100)
+	copy(data[0x300:], version+" (builder@localhost) #1 SMP")
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateKernels(t *testing.T) {
+	esp := writeTestGrubConfigs(t, testMainGrubConfig)[0]
+	usr := t.TempDir()
+	writeTestKernel(t, filepath.Join(esp, "coreos", "vmlinuz-a"), "4.14.32-coreos")
+	writeTestKernel(t, filepath.Join(usr, "boot", "vmlinuz-4.14.32-coreos"), "4.14.32-coreos")
+	if err := os.Symlink("/usr/boot/vmlinuz-4.14.32-coreos", filepath.Join(usr, "boot", "vmlinuz")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(usr, "lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(usr, "lib", "os-release"), []byte("VERSION_ID=1688.5.3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// as installed, only USR-A has a priority and vmlinuz-b doesn't exist
+	partitions := []Partition{
+		{Label: espLabel, MountPath: esp},
+		{Label: "USR-A", Attributes: 1<<56 | 1<<48, MountPath: usr},
+		{Label: "USR-B"},
+	}
+	Test{}.ValidateKernels(t, partitions, "1688.5.3")
+}
+
+func TestActiveUsrPartition(t *testing.T) {
+	for _, c := range []struct {
+		name       string
+		partitions []Partition
+		active     string
+	}{
+		{"fresh install", []Partition{{Label: "USR-A", Attributes: 1<<56 | 1<<48}, {Label: "USR-B"}}, "USR-A"},
+		{"updated", []Partition{{Label: "USR-A", Attributes: 1 << 48}, {Label: "USR-B", Attributes: 2 << 48}}, "USR-B"},
+		{"no priorities", []Partition{{Label: "USR-A"}, {Label: "USR-B"}}, "USR-A"},
+		{"no USR-B", []Partition{{Label: "ROOT", Attributes: 3 << 48}, {Label: "USR-A"}}, "USR-A"},
+	} {
+		if p, _ := ActiveUsrPartition(c.partitions); p.Label != c.active {
+			t.Errorf("%s: active partition is %q, expected %s", c.name, p.Label, c.active)
+		}
+	}
+}
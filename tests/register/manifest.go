@@ -0,0 +1,165 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+type ExpectedFile struct {
+	// partition label the file must be on
+	Label string
+	// relative to the root of the partition
+	Path string
+
+	// hex encoded, optional
+	SHA256 string
+	// optional, compared with Compare or byte for byte if Compare is nil,
+	// e.g. with util.JSONEqual to ignore formatting
+	Contents string
+	Compare  func(expected, actual []byte) error
+	// optional
+	Meta *FileMeta
+}
+
+// Manifest is a list of files expected on the installed partitions
+type Manifest []ExpectedFile
+
+func HashString(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidateManifest checks every expected file in a single pass over the
+// mounted partitions
+func (test Test) ValidateManifest(t *testing.T, partitions []Partition, manifest Manifest) {
+	found := make([]bool, len(manifest))
+	for _, p := range partitions {
+		if p.MountPath == "" {
+			continue
+		}
+
+		for i, expected := range manifest {
+			if expected.Label != p.Label {
+				continue
+			}
+
+			path := filepath.Join(p.MountPath, expected.Path)
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				t.Fatalf("couldn't find %s on %s", expected.Path, expected.Label)
+			}
+			found[i] = true
+
+			if expected.SHA256 != "" {
+				actual, err := util.HashFile(path, util.SHA256)
+				if err != nil {
+					t.Fatalf("couldn't hash %s on %s: %v", expected.Path, expected.Label, err)
+				}
+
+				if actual != expected.SHA256 {
+					t.Fatalf("%s on %s doesn't match: expected sha256 %s, received %s", expected.Path, expected.Label, expected.SHA256, actual)
+				}
+			}
+
+			if expected.Contents != "" {
+				test.validateContents(t, path, expected)
+			}
+
+			if expected.Meta != nil {
+				test.ValidateFileMeta(t, path, *expected.Meta)
+			}
+		}
+	}
+
+	for i, expected := range manifest {
+		if !found[i] {
+			t.Fatalf("couldn't find a mounted %s partition for %s", expected.Label, expected.Path)
+		}
+	}
+}
+
+func (test Test) validateContents(t *testing.T, path string, expected ExpectedFile) {
+	actual, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("couldn't read %s on %s: %v", expected.Path, expected.Label, err)
+	}
+
+	if expected.Compare == nil {
+		if string(actual) != expected.Contents {
+			t.Fatalf("%s on %s doesn't match: expected %q, received %q", expected.Path, expected.Label, expected.Contents, actual)
+		}
+		return
+	}
+
+	if err := expected.Compare([]byte(expected.Contents), actual); err != nil {
+		t.Fatalf("%s on %s doesn't match: %v", expected.Path, expected.Label, err)
+	}
+}
+
+// AssertAbsent fails if any of the files exist on a mounted partition with
+// the matching label, or if no partition with that label is mounted to
+// check, only Label and Path are used
+func (test Test) AssertAbsent(t *testing.T, partitions []Partition, files ...ExpectedFile) {
+	for _, f := range files {
+		checked := false
+		for _, p := range partitions {
+			if p.MountPath == "" || p.Label != f.Label {
+				continue
+			}
+			checked = true
+
+			if fileExists(filepath.Join(p.MountPath, f.Path)) {
+				t.Fatalf("%s unexpectedly found on %s", f.Path, f.Label)
+			}
+		}
+
+		if !checked {
+			t.Fatalf("couldn't find a mounted %s partition to check for %s", f.Label, f.Path)
+		}
+	}
+}
+
+// AssertNoIgnition checks an install without -i didn't write an Ignition
+// config or point the kernel at one
+func (test Test) AssertNoIgnition(t *testing.T, partitions []Partition) {
+	test.AssertAbsent(t, partitions, ExpectedFile{Label: "OEM", Path: ignitionConfigPath})
+
+	oem, ok := FindPartition(partitions, "OEM")
+	if !ok || oem.MountPath == "" {
+		return
+	}
+
+	grubPath := filepath.Join(oem.MountPath, grubOEMConfigPath)
+	if !fileExists(grubPath) {
+		return
+	}
+
+	cfg := ParseGrubConfig(readGrubConfig(t, grubPath), nil)
+	if HasKernelArg(cfg.AppendArgs(), "coreos.config.url") {
+		t.Fatalf("OEM grub.cfg unexpectedly sets coreos.config.url: received %q", cfg.Vars["linux_append"])
+	}
+}
+
+// AssertNoCloudinit checks an install without -c didn't write user_data
+func (test Test) AssertNoCloudinit(t *testing.T, partitions []Partition) {
+	test.AssertAbsent(t, partitions, ExpectedFile{Label: "ROOT", Path: cloudinitPath})
+}
@@ -0,0 +1,177 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreos/init/tests/util"
+)
+
+// MockInstaller is how a fake coreos-install behaves, for testing the
+// harness itself without root, loop devices or images
+type MockInstaller struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	// how long it runs before exiting
+	Delay time.Duration
+
+	// written at the start of the -d device, if one was given
+	DeviceData string
+	// written to these paths relative to TMPDIR
+	Files map[string]string
+	// leaves a coreos-install.XXXXXXXXXX dir behind in TMPDIR like an
+	// installer that didn't clean up
+	LeaveTempDir bool
+}
+
+// Mock is a fake installer created by CreateMockInstaller
+type Mock struct {
+	// the script to run in place of coreos-install
+	Path string
+
+	calls string
+}
+
+// MockCall is one run of a Mock
+type MockCall struct {
+	Args []string
+	Env  map[string]string
+}
+
+// CreateMockInstaller writes a fake coreos-install that records every call
+// and behaves as m says
+func (test Test) CreateMockInstaller(t *testing.T, m MockInstaller) *Mock {
+	dir := test.TempDir(t, "coreos-install-mock")
+	mock := &Mock{
+		Path:  filepath.Join(dir, "coreos-install"),
+		calls: filepath.Join(dir, "calls"),
+	}
+	if err := os.Mkdir(mock.calls, 0755); err != nil {
+		t.Fatalf("couldn't create mock calls dir: %v", err)
+	}
+
+	// everything the script prints or writes is kept in files next to it,
+	// so nothing has to be escaped but their paths
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("couldn't write mock %s: %v", name, err)
+		}
+		return util.ShellQuote(path)
+	}
+
+	var script bytes.Buffer
+	fmt.Fprintf(&script, `#!/bin/bash
+set -e
+call=$(mktemp -d %s/$(date +%%s%%N).XXXX)
+printf '%%s\0' "$@" > "$call/args"
+env -0 > "$call/env"
+
+DEVICE=
+while getopts ":V:B:C:d:o:c:i:t:b:k:f:nvh" OPTION; do
+    if [ "$OPTION" = d ]; then DEVICE="$OPTARG"; fi
+done
+: ${TMPDIR:=/tmp}
+`, util.ShellQuote(mock.calls))
+
+	if m.LeaveTempDir {
+		fmt.Fprintln(&script, `mktemp -d --tmpdir coreos-install.XXXXXXXXXX >/dev/null`)
+	}
+
+	names := make([]string, 0, len(m.Files))
+	for name := range m.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		src := write(fmt.Sprintf("file%d", i), m.Files[name])
+		dst := util.ShellQuote(name)
+		fmt.Fprintf(&script, "mkdir -p \"$TMPDIR/$(dirname %s)\"\ncp %s \"$TMPDIR\"/%s\n", dst, src, dst)
+	}
+
+	if m.DeviceData != "" {
+		src := write("device", m.DeviceData)
+		fmt.Fprintf(&script, "[ -z \"$DEVICE\" ] || dd if=%s of=\"$DEVICE\" conv=notrunc status=none\n", src)
+	}
+
+	fmt.Fprintf(&script, "cat %s\ncat %s >&2\n", write("stdout", m.Stdout), write("stderr", m.Stderr))
+	if m.Delay != 0 {
+		fmt.Fprintf(&script, "sleep %.3f\n", m.Delay.Seconds())
+	}
+	fmt.Fprintf(&script, "exit %d\n", m.ExitCode)
+
+	if err := ioutil.WriteFile(mock.Path, script.Bytes(), 0755); err != nil {
+		t.Fatalf("couldn't write mock installer: %v", err)
+	}
+	return mock
+}
+
+// Apply returns a copy of the invocation that runs the mock
+func (mock *Mock) Apply(inv Invocation) Invocation {
+	inv.Binary = mock.Path
+	return inv
+}
+
+// Calls returns the mock's runs so far, oldest first
+func (mock *Mock) Calls(t *testing.T) []MockCall {
+	entries, err := ioutil.ReadDir(mock.calls)
+	if err != nil {
+		t.Fatalf("couldn't list mock calls: %v", err)
+	}
+
+	// named by start time, so sorted oldest first
+	var calls []MockCall
+	for _, entry := range entries {
+		dir := filepath.Join(mock.calls, entry.Name())
+		args, err := ioutil.ReadFile(filepath.Join(dir, "args"))
+		if err != nil {
+			t.Fatalf("couldn't read mock call: %v", err)
+		}
+		env, err := ioutil.ReadFile(filepath.Join(dir, "env"))
+		if err != nil {
+			t.Fatalf("couldn't read mock call: %v", err)
+		}
+
+		call := MockCall{Args: splitNUL(args), Env: map[string]string{}}
+		for _, kv := range splitNUL(env) {
+			parts := strings.SplitN(kv, "=", 2)
+			if len(parts) == 2 {
+				call.Env[parts[0]] = parts[1]
+			}
+		}
+		calls = append(calls, call)
+	}
+	return calls
+}
+
+// splitNUL splits NUL terminated strings
+func splitNUL(data []byte) []string {
+	fields := []string{}
+	for _, field := range bytes.Split(data, []byte{0}) {
+		fields = append(fields, string(field))
+	}
+	// drop what follows the last terminator
+	return fields[:len(fields)-1]
+}
@@ -0,0 +1,111 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+var netnsCount uint32
+
+// NetNS is a network namespace joined to the host by a single veth pair
+// with no default route, so processes inside it can only reach HostAddr
+type NetNS struct {
+	Name string
+	// the host's end of the veth pair
+	HostAddr string
+	// the namespace's end
+	NSAddr string
+
+	hostLink string
+	// the source NATed to the host's network, see CreateRoutedNetNS
+	masquerade string
+}
+
+// CreateNetNS creates an isolated network namespace for the installer, each
+// one gets its own /30 so concurrent tests don't collide
+func (test Test) CreateNetNS(t *testing.T) *NetNS {
+	n := atomic.AddUint32(&netnsCount, 1) - 1
+	pid := os.Getpid() % 10000
+	prefix := fmt.Sprintf("10.231.%d.%d", (n/64)%256, (n%64)*4)
+
+	ns := &NetNS{
+		Name:     fmt.Sprintf("coreos-install-%d-%d", pid, n),
+		HostAddr: fmt.Sprintf("10.231.%d.%d", (n/64)%256, (n%64)*4+1),
+		NSAddr:   fmt.Sprintf("10.231.%d.%d", (n/64)%256, (n%64)*4+2),
+		hostLink: fmt.Sprintf("ci%d-%dh", pid, n),
+	}
+	nsLink := fmt.Sprintf("ci%d-%dn", pid, n)
+	t.Logf("creating network namespace %s on %s/30", ns.Name, prefix)
+
+	util.MustRun(t, "ip", "netns", "add", ns.Name)
+	util.MustRun(t, "ip", "link", "add", ns.hostLink, "type", "veth", "peer", "name", nsLink, "netns", ns.Name)
+	util.MustRun(t, "ip", "addr", "add", ns.HostAddr+"/30", "dev", ns.hostLink)
+	util.MustRun(t, "ip", "link", "set", ns.hostLink, "up")
+	util.MustRun(t, "ip", "-n", ns.Name, "addr", "add", ns.NSAddr+"/30", "dev", nsLink)
+	util.MustRun(t, "ip", "-n", ns.Name, "link", "set", nsLink, "up")
+	util.MustRun(t, "ip", "-n", ns.Name, "link", "set", "lo", "up")
+	return ns
+}
+
+// CreateRoutedNetNS is CreateNetNS with a default route through the host,
+// NATed onto its network, for installs that need the internet but should
+// still be confined to a namespace, e.g. to capture their traffic
+func (test Test) CreateRoutedNetNS(t *testing.T) *NetNS {
+	ns := test.CreateNetNS(t)
+	ns.masquerade = ns.NSAddr + "/32"
+
+	util.MustRun(t, "ip", "-n", ns.Name, "route", "add", "default", "via", ns.HostAddr)
+	if err := ioutil.WriteFile("/proc/sys/net/ipv4/ip_forward", []byte("1"), 0644); err != nil {
+		test.CleanupNetNS(t, ns)
+		t.Fatalf("couldn't enable forwarding: %v", err)
+	}
+	util.MustRun(t, "iptables", "-t", "nat", "-A", "POSTROUTING", "-s", ns.masquerade, "-j", "MASQUERADE")
+
+	// a stub resolver on the host's loopback can't be reached from the
+	// namespace, give it resolved's upstream servers instead
+	if upstream, err := ioutil.ReadFile("/run/systemd/resolve/resolv.conf"); err == nil {
+		dir := filepath.Join("/etc/netns", ns.Name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("couldn't create %s: %v", dir, err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "resolv.conf"), upstream, 0644); err != nil {
+			t.Fatalf("couldn't write namespace resolv.conf: %v", err)
+		}
+	}
+	return ns
+}
+
+// CleanupNetNS removes the namespace, which takes its end of the veth pair
+// and so the host's end with it
+func (test Test) CleanupNetNS(t *testing.T, ns *NetNS) {
+	if ns.masquerade != "" {
+		util.MustRun(t, "iptables", "-t", "nat", "-D", "POSTROUTING", "-s", ns.masquerade, "-j", "MASQUERADE")
+		test.RemoveAll(t, filepath.Join("/etc/netns", ns.Name))
+	}
+	util.MustRun(t, "ip", "netns", "delete", ns.Name)
+}
+
+// command runs name inside the namespace
+func (ns *NetNS) command(name string, args []string) (string, []string) {
+	return "ip", append([]string{"netns", "exec", ns.Name, name}, args...)
+}
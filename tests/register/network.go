@@ -0,0 +1,149 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+const (
+	// coreos-install -n copies everything from here
+	hostNetworkDir = "/run/systemd/network"
+	// to here on the ROOT partition
+	rootNetworkDir = "etc/systemd/network"
+)
+
+// NetworkUnits are fake networkd units the installer sees in
+// /run/systemd/network, pass them in Invocation.NetworkUnits
+type NetworkUnits struct {
+	// file name -> expected contents
+	Files map[string]string
+
+	// mounted over /run/systemd/network for the installer only
+	dir       string
+	createdIn bool
+}
+
+// CreateNetworkUnits writes fake networkd units to a temp dir, which the
+// installer gets in place of /run/systemd/network so the host's networkd
+// never sees them. resolv.conf is a symlink, like the one networkd
+// generates.
+func (test Test) CreateNetworkUnits(t *testing.T) NetworkUnits {
+	util.RequireTools(t, "unshare", "mount")
+
+	units := NetworkUnits{
+		Files: map[string]string{
+			"zz-coreos-install-test.network": "[Match]\nName=coreos-install-test0\n\n[Network]\nDHCP=yes\n",
+			"zz-coreos-install-test.netdev":  "[NetDev]\nName=coreos-install-test0\nKind=dummy\n",
+			"resolv.conf":                    "nameserver 192.0.2.53\n",
+		},
+		dir: test.TempDir(t, "coreos-install-network"),
+	}
+	resolvDir := test.TempDir(t, "coreos-install-resolv")
+
+	for name, data := range units.Files {
+		path := filepath.Join(units.dir, name)
+		if name == "resolv.conf" {
+			path = filepath.Join(resolvDir, name)
+		}
+
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("couldn't write %s: %v", path, err)
+		}
+		// cp --preserve keeps the mode, so it can't depend on the umask
+		if err := os.Chmod(path, 0644); err != nil {
+			t.Fatalf("couldn't chmod %s: %v", path, err)
+		}
+	}
+
+	resolvPath := filepath.Join(units.dir, "resolv.conf")
+	if err := os.Symlink(filepath.Join(resolvDir, "resolv.conf"), resolvPath); err != nil {
+		t.Fatalf("couldn't link %s: %v", resolvPath, err)
+	}
+
+	// the mount needs somewhere to go, only the empty dir is left behind
+	// on the host until CleanupNetworkUnits
+	if _, err := os.Stat(hostNetworkDir); os.IsNotExist(err) {
+		if err := os.MkdirAll(hostNetworkDir, 0755); err != nil {
+			t.Fatalf("couldn't create %s: %v", hostNetworkDir, err)
+		}
+		units.createdIn = true
+	}
+
+	return units
+}
+
+func (test Test) CleanupNetworkUnits(t *testing.T, units NetworkUnits) {
+	if units.createdIn {
+		test.RemoveAll(t, hostNetworkDir)
+	}
+}
+
+// command runs name in a private mount namespace with the units mounted
+// over /run/systemd/network
+func (units *NetworkUnits) command(name string, args []string) (string, []string) {
+	script := `mount --bind "$0" ` + hostNetworkDir + ` && exec "$@"`
+	return "unshare", append([]string{"--mount", "--propagation", "private", "/bin/sh", "-c", script, units.dir, name}, args...)
+}
+
+// ValidateNetworkUnits asserts the units from CreateNetworkUnits were copied
+// to /etc/systemd/network on the ROOT partition with their permissions and
+// with resolv.conf dereferenced into a regular file
+func (test Test) ValidateNetworkUnits(t *testing.T, mountPaths []string, units NetworkUnits) {
+	networkDir := ""
+	for _, p := range mountPaths {
+		dir := filepath.Join(p, rootNetworkDir)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			networkDir = dir
+			break
+		}
+	}
+
+	if networkDir == "" {
+		t.Fatalf("couldn't find /%s on any partitions", rootNetworkDir)
+	}
+
+	for name, expected := range units.Files {
+		path := filepath.Join(networkDir, name)
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			t.Fatalf("%s wasn't copied to /%s", name, rootNetworkDir)
+		} else if err != nil {
+			t.Fatalf("couldn't stat %s: %v", path, err)
+		}
+
+		if !info.Mode().IsRegular() {
+			t.Fatalf("/%s/%s isn't a regular file: %v", rootNetworkDir, name, info.Mode())
+		}
+
+		if info.Mode().Perm() != 0644 {
+			t.Fatalf("/%s/%s has wrong permissions: expected %v, received %v", rootNetworkDir, name, os.FileMode(0644), info.Mode().Perm())
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("couldn't read %s: %v", path, err)
+		}
+
+		if string(data) != expected {
+			t.Fatalf("/%s/%s doesn't match: expected %s, received %s", rootNetworkDir, name, expected, data)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestNetworkUnitsPrivate(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("mounting over /run/systemd/network needs root")
+	}
+
+	// the units have to come out 0644 whatever the umask
+	defer syscall.Umask(syscall.Umask(077))
+	test := Test{}.WithTempDir(t.TempDir())
+	units := test.CreateNetworkUnits(t)
+	defer test.CleanupNetworkUnits(t, units)
+
+	name, args := units.command("cat", []string{
+		filepath.Join(hostNetworkDir, "zz-coreos-install-test.network"),
+		filepath.Join(hostNetworkDir, "resolv.conf"),
+	})
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("couldn't read the units in the namespace: %v: %s", err, out)
+	}
+	if expected := units.Files["zz-coreos-install-test.network"] + units.Files["resolv.conf"]; string(out) != expected {
+		t.Fatalf("expected %q, received %q", expected, out)
+	}
+
+	for name := range units.Files {
+		path := filepath.Join(hostNetworkDir, name)
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("%s was written to the host", path)
+		}
+
+		info, err := os.Stat(filepath.Join(units.dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0644 {
+			t.Errorf("%s: expected %v, received %v", name, os.FileMode(0644), info.Mode().Perm())
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+// OEM is a platform coreos-install can install an OEM image for with -o
+type OEM struct {
+	// what's passed to -o and set as ID in oem-release
+	ID string
+	// whether the image's OEM partition ships a cloud-config.yml
+	CloudConfig bool
+}
+
+// OEMs are the targets covered by the OEM tests, each needs
+// coreos_production_<id>_image.bin.bz2 in the fixture mirror
+var OEMs = []OEM{
+	{ID: "azure", CloudConfig: true},
+	{ID: "gce", CloudConfig: true},
+	{ID: "packet", CloudConfig: true},
+}
+
+const (
+	oemReleasePath     = "oem-release"
+	oemCloudConfigPath = "cloud-config.yml"
+)
+
+// OEMImageName is the image coreos-install downloads for an OEM, or the
+// generic image if oem is empty
+func OEMImageName(oem string) string {
+	if oem == "" {
+		return "coreos_production_image.bin.bz2"
+	}
+	return "coreos_production_" + oem + "_image.bin.bz2"
+}
+
+// RequireImage skips the test if the mirror doesn't have the image and its
+// signature for board and version
+func (f *FixtureServer) RequireImage(t *testing.T, board, version, image string) {
+	for _, name := range []string{image, image + ".sig"} {
+		path := filepath.Join(*fixtureDirFlag, board, version, name)
+		if _, err := os.Stat(path); err != nil {
+			t.Skipf("the fixture mirror doesn't have %s/%s/%s: %v", board, version, name, err)
+		}
+	}
+}
+
+// AssertRequested fails if nothing was downloaded from the fixture at a
+// path ending in suffix
+func (f *FixtureServer) AssertRequested(t *testing.T, suffix string) {
+	requests := f.Requests()
+	for _, r := range requests {
+		if strings.HasSuffix(r, suffix) {
+			return
+		}
+	}
+	t.Fatalf("%s wasn't requested from the fixture: received %q", suffix, requests)
+}
+
+// ValidateOEM asserts the OEM partition came from the OEM's image: its
+// oem-release has the OEM's ID, it ships a cloud-config if the OEM does and
+// the kernel command line sets coreos.oem.id
+func (test Test) ValidateOEM(t *testing.T, partitions []Partition, oem OEM) {
+	p, ok := FindPartition(partitions, "OEM")
+	if !ok || p.MountPath == "" {
+		t.Fatalf("couldn't find a mounted OEM partition")
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(p.MountPath, oemReleasePath))
+	if err != nil {
+		t.Fatalf("couldn't read %s on OEM: %v", oemReleasePath, err)
+	}
+	if id := ParseOSRelease(data)["ID"]; id != oem.ID {
+		t.Fatalf("%s on OEM has the wrong ID: expected %s, received %s", oemReleasePath, oem.ID, id)
+	}
+
+	cloudConfig := filepath.Join(p.MountPath, oemCloudConfigPath)
+	if oem.CloudConfig {
+		data, err := ioutil.ReadFile(cloudConfig)
+		if err != nil {
+			t.Fatalf("couldn't read %s on OEM: %v", oemCloudConfigPath, err)
+		}
+		if !strings.HasPrefix(string(data), "#cloud-config") {
+			t.Fatalf("%s on OEM isn't a cloud-config: received %q", oemCloudConfigPath, data)
+		}
+		if _, err := util.ParseYAML(data); err != nil {
+			t.Fatalf("couldn't parse %s on OEM: %v", oemCloudConfigPath, err)
+		}
+		test.validateCloudinitSyntax(t, cloudConfig)
+	} else if fileExists(cloudConfig) {
+		t.Fatalf("%s unexpectedly found on OEM", oemCloudConfigPath)
+	}
+
+	test.ValidateKernelArgs(t, MountPaths(partitions), []string{"coreos.oem.id=" + oem.ID})
+}
+
+// AssertNoOEM checks an install without -o used the generic image, which
+// has an empty OEM partition
+func (test Test) AssertNoOEM(t *testing.T, partitions []Partition) {
+	test.AssertAbsent(t, partitions,
+		ExpectedFile{Label: "OEM", Path: oemReleasePath},
+		ExpectedFile{Label: "OEM", Path: oemCloudConfigPath},
+	)
+}
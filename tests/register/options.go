@@ -0,0 +1,68 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"testing"
+)
+
+// OptionCase is one row of a RunOptionCases table
+type OptionCase struct {
+	Name string
+
+	// builds the installer's args for the case's target device, files
+	// created here should be cleaned up with t.Cleanup
+	Opts func(t *testing.T, device string) []string
+	// how the installer is started, e.g. with configs on stdin
+	Invocation Invocation
+
+	// the documented error the install should fail with, nil means it
+	// should succeed
+	Error *InstallerError
+
+	// checks the result of a successful install
+	Validate func(t *testing.T, diskFile string, partitions []Partition)
+}
+
+// RunOptionCases runs the installer once per case against a fresh device.
+// Failing cases must exit with the expected code and message without
+// touching the disk, succeeding cases are mounted and validated.
+func (test Test) RunOptionCases(t *testing.T, cases ...OptionCase) {
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			diskFile, loopDevice := test.CreateDevice(t)
+			defer test.CleanupDisk(t, diskFile, loopDevice)
+
+			before := test.SnapshotDisk(t, diskFile)
+			result := test.TryCoreOSInstallWith(t, c.Invocation, c.Opts(t, loopDevice)...)
+
+			if c.Error == nil {
+				test.ValidateExitStatus(t, result, ExitSuccess)
+			} else {
+				test.ValidateFailedInstall(t, result, *c.Error, diskFile, loopDevice, before)
+				return
+			}
+
+			partitions := test.MountPartitions(t, diskFile, loopDevice)
+			defer test.UnmountPartitions(t, loopDevice, partitions)
+
+			test.DefaultChecks(t, MountPaths(partitions), diskFile, result.Args)
+			if c.Validate != nil {
+				c.Validate(t, diskFile, partitions)
+			}
+		})
+	}
+}
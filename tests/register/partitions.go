@@ -0,0 +1,208 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+type Partition struct {
+	Number      int
+	Label       string
+	TypeGUID    string
+	GUID        string
+	Attributes  uint64
+	FirstSector uint64
+	LastSector  uint64
+	Device      string
+
+	// empty if the partition couldn't be mounted
+	MountPath string
+}
+
+// MountPartitions maps every partition on the loop device and mounts the
+// ones that have a filesystem
+func (test Test) MountPartitions(t *testing.T, diskFile, loopDevice string) []Partition {
+	// without vfat the EFI system partition looks like it has no
+	// filesystem, and checks of grub.cfg fail far from the cause
+	util.RequireHost(t, util.HostVFAT)
+	table := map[int]Partition{}
+	for _, p := range test.ListPartitions(t, diskFile) {
+		table[p.Number] = p
+	}
+
+	var partitions []Partition
+	for _, device := range test.CreateDeviceMappers(t, loopDevice) {
+		number, err := strconv.Atoi(util.RegexpSearch(t, "partition number", "p(?P<number>\\d+)$", []byte(device)))
+		if err != nil {
+			t.Fatalf("couldn't parse partition number of %s: %v", device, err)
+		}
+
+		p, ok := table[number]
+		if !ok {
+			t.Fatalf("%s isn't in the partition table", device)
+		}
+		p.Device = device
+		p.MountPath = test.MountDeviceMapper(t, device)
+		partitions = append(partitions, p)
+	}
+	return partitions
+}
+
+func (test Test) UnmountPartitions(t *testing.T, loopDevice string, partitions []Partition) {
+	for _, p := range partitions {
+		if p.MountPath != "" {
+			test.UnmountPath(t, p.MountPath)
+			test.RemoveAll(t, p.MountPath)
+		}
+	}
+	test.RemoveDeviceMappers(t, loopDevice)
+}
+
+// MountPaths returns the mount points of the mounted partitions
+func MountPaths(partitions []Partition) (paths []string) {
+	for _, p := range partitions {
+		if p.MountPath != "" {
+			paths = append(paths, p.MountPath)
+		}
+	}
+	return
+}
+
+func FindPartition(partitions []Partition, label string) (Partition, bool) {
+	for _, p := range partitions {
+		if p.Label == label {
+			return p, true
+		}
+	}
+	return Partition{}, false
+}
+
+// PartitionSpec describes how to find a partition on the installed disk.
+// Number is only checked when the test runs with StrictPartitionNumbers.
+type PartitionSpec struct {
+	Label    string
+	TypeGUID string
+	Number   int
+}
+
+var (
+	RootPartition = PartitionSpec{
+		Label:    "ROOT",
+		TypeGUID: "3884DD41-8582-4404-B9A8-E9B84F2DF50E",
+		Number:   9,
+	}
+	USRAPartition = PartitionSpec{
+		Label:    "USR-A",
+		TypeGUID: "5DFBF5F4-2848-4BAC-AA5E-0D9A20B745A6",
+		Number:   3,
+	}
+	OEMPartition = PartitionSpec{
+		Label:    "OEM",
+		TypeGUID: "0FC63DAF-8483-4772-8E79-3D69D8477DE4",
+		Number:   6,
+	}
+)
+
+// ListPartitions reads every partition in the disk's GPT
+func (test Test) ListPartitions(t *testing.T, diskFile string) (partitions []Partition) {
+	gpt, err := util.ReadGPT(diskFile)
+	if err != nil {
+		// a wiped or empty disk has no partitions, which callers decide about
+		t.Logf("couldn't read partition table: %v", err)
+		return nil
+	}
+
+	for _, p := range gpt.Partitions {
+		partitions = append(partitions, Partition{
+			Number:      p.Number,
+			Label:       p.Name,
+			TypeGUID:    p.TypeGUID,
+			GUID:        p.GUID,
+			Attributes:  p.Attributes,
+			FirstSector: p.FirstLBA,
+			LastSector:  p.LastLBA,
+		})
+	}
+	return
+}
+
+// LocatePartition finds a partition by label, falling back to the type GUID
+// if no partition has the label and the GUID is unique on the disk
+func (test Test) LocatePartition(t *testing.T, diskFile string, spec PartitionSpec) Partition {
+	partitions := test.ListPartitions(t, diskFile)
+	if p, ok := FindPartition(partitions, spec.Label); ok {
+		return p
+	}
+
+	matches := util.Filter(partitions, func(p Partition) bool {
+		return spec.TypeGUID != "" && strings.EqualFold(p.TypeGUID, spec.TypeGUID)
+	})
+
+	if len(matches) != 1 {
+		t.Fatalf("couldn't locate partition %s: %d partitions have type %s", spec.Label, len(matches), spec.TypeGUID)
+	}
+	return matches[0]
+}
+
+// ValidatePartition asserts a partition matching spec exists with the right
+// label and type, and with the right number in strict mode
+func (test Test) ValidatePartition(t *testing.T, diskFile string, spec PartitionSpec) {
+	p := test.LocatePartition(t, diskFile, spec)
+
+	if p.Label != spec.Label {
+		t.Fatalf("label on partition %d did not match. expected %s, received %s", p.Number, spec.Label, p.Label)
+	}
+
+	if spec.TypeGUID != "" && !strings.EqualFold(p.TypeGUID, spec.TypeGUID) {
+		t.Fatalf("type of partition %s did not match. expected %s, received %s", spec.Label, spec.TypeGUID, p.TypeGUID)
+	}
+
+	if test.StrictPartitionNumbers && p.Number != spec.Number {
+		t.Fatalf("partition %s has the wrong number. expected %d, received %d", spec.Label, spec.Number, p.Number)
+	}
+}
+
+// HashPartition hashes the partition's bytes straight from the disk file,
+// logging progress since partitions can be gigabytes
+func (test Test) HashPartition(t *testing.T, diskFile string, p Partition) string {
+	offset := int64(p.FirstSector) * sectorSize
+	length := int64(p.LastSector-p.FirstSector+1) * sectorSize
+
+	sum, err := util.HashRange(diskFile, offset, length, util.SHA256, func(done, total int64) {
+		t.Logf("hashed %d of %d MiB of %s", done>>20, total>>20, p.Label)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sum
+}
+
+// InstalledLayout installs to a scratch disk and returns where the image
+// put its partitions, for tests that target them on another disk. It's
+// cached per installer, see CachedFixture.
+func (test Test) InstalledLayout(t *testing.T) []Partition {
+	return CachedFixture(t, "installed layout "+test.installerDigest(t), func() []Partition {
+		diskFile, loopDevice := test.CreateDevice(t)
+		defer test.CleanupDisk(t, diskFile, loopDevice)
+
+		test.RunCoreOSInstall(t, InstallOptions{Device: loopDevice}.Args()...)
+		return test.ListPartitions(t, diskFile)
+	})
+}
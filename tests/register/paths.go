@@ -0,0 +1,79 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// PathKind is a quirk of a path the installer and harness have to quote and
+// handle correctly
+type PathKind int
+
+const (
+	SpacedPath PathKind = iota
+	UnicodePath
+	// close to PATH_MAX, with room left for the installer's temp files and
+	// mount points under it
+	LongPath
+)
+
+var PathKinds = []PathKind{SpacedPath, UnicodePath, LongPath}
+
+func (k PathKind) String() string {
+	switch k {
+	case SpacedPath:
+		return "spaces"
+	case UnicodePath:
+		return "unicode"
+	case LongPath:
+		return "long"
+	}
+	return "unknown"
+}
+
+const (
+	// leaves 512 bytes of PATH_MAX for what goes under the dir
+	longPathLength = 4096 - 512
+	// NAME_MAX
+	longPathComponent = 255
+)
+
+// CreateAwkwardDir creates a dir in TMPDIR with kind's quirk in its path
+func (test Test) CreateAwkwardDir(t *testing.T, kind PathKind) string {
+	switch kind {
+	case SpacedPath:
+		return test.TempDir(t, "coreos install with  spaces ")
+	case UnicodePath:
+		return test.TempDir(t, "coreos-install-ünïcödé-☃-日本語-")
+	}
+
+	dir := test.TempDir(t, "coreos-install-long")
+	path := dir
+	for i := 0; len(path) < longPathLength-1; i++ {
+		length := longPathComponent
+		if remaining := longPathLength - len(path) - 1; remaining < length {
+			length = remaining
+		}
+		path = filepath.Join(path, strings.Repeat(string(rune('a'+i%26)), length))
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		t.Fatalf("couldn't create long path: %v", err)
+	}
+	return path
+}
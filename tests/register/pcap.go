@@ -0,0 +1,129 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"bufio"
+	"flag"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+var pcapFlag = flag.Bool("pcap", config.Pcap, "capture every install's traffic with tcpdump into the artifact dir, installs that don't already run in a network namespace are run in one routed to the host's network [$COREOS_TEST_PCAP]")
+
+const captureStartTimeout = 10 * time.Second
+
+// capture is tcpdump writing a pcap of one interface's traffic
+type capture struct {
+	cmd  *exec.Cmd
+	done chan error
+}
+
+// debugCapture starts capturing the install's traffic if -pcap was given,
+// putting it in a routed namespace if it doesn't have one. The returned
+// func stops the capture and removes the namespace.
+func (test Test) debugCapture(t *testing.T, inv *Invocation, opts []string) (stop func()) {
+	if !*pcapFlag || inv.Container != nil {
+		return func() {}
+	}
+
+	if _, err := exec.LookPath("tcpdump"); err != nil {
+		t.Fatalf("-pcap was given but tcpdump isn't installed: %v", err)
+	}
+	output := ArtifactPath(t, "coreos-install.pcap")
+	if output == "" {
+		t.Fatalf("-pcap requires -artifact-dir to save the capture in")
+	}
+
+	// a namespace can't reach the host's loopback, installs pointed at it
+	// are captured on every interface instead
+	iface := "any"
+	var ns *NetNS
+	if inv.NetNS != nil {
+		iface = inv.NetNS.hostLink
+	} else if !usesLoopback(opts) {
+		ns = test.CreateRoutedNetNS(t)
+		inv.NetNS = ns
+		iface = ns.hostLink
+	}
+
+	c := startCapture(t, iface, output)
+	return func() {
+		c.Stop(t)
+		if ns != nil {
+			test.CleanupNetNS(t, ns)
+		}
+	}
+}
+
+func usesLoopback(opts []string) bool {
+	for _, opt := range opts {
+		if strings.Contains(opt, "://127.") || strings.Contains(opt, "://localhost") {
+			return true
+		}
+	}
+	return false
+}
+
+// startCapture runs tcpdump on iface until Stop, returning once it's
+// capturing
+func startCapture(t *testing.T, iface, output string) *capture {
+	cmd := exec.Command("tcpdump", "-i", iface, "-n", "-U", "-w", output)
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		t.Fatalf("couldn't capture tcpdump's output: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("couldn't start tcpdump: %v", err)
+	}
+
+	c := &capture{cmd: cmd, done: make(chan error, 1)}
+	listening := make(chan struct{})
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			if strings.HasPrefix(scanner.Text(), "listening on ") {
+				close(listening)
+			}
+		}
+		c.done <- cmd.Wait()
+	}()
+
+	select {
+	case <-listening:
+	case err := <-c.done:
+		t.Fatalf("tcpdump exited before capturing: %v", err)
+	case <-time.After(captureStartTimeout):
+		cmd.Process.Kill()
+		t.Fatalf("tcpdump didn't start capturing on %s within %v", iface, captureStartTimeout)
+	}
+	t.Logf("capturing traffic on %s to %s", iface, output)
+	return c
+}
+
+// Stop ends the capture, letting tcpdump flush the pcap
+func (c *capture) Stop(t *testing.T) {
+	c.cmd.Process.Signal(syscall.SIGINT)
+	select {
+	case <-c.done:
+	case <-time.After(captureStartTimeout):
+		c.cmd.Process.Kill()
+		<-c.done
+		t.Errorf("tcpdump didn't exit after SIGINT")
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// configs the installer writes that can contain credentials, relative to
+// the root of their partition
+var sensitiveConfigPaths = []string{ignitionConfigPath, cloudinitPath, grubOEMConfigPath}
+
+// ValidateConfigPermissions asserts every config the installer wrote, and
+// every dir leading to it, is owned by root and not world-writable. Anyone
+// who can rewrite them controls the next boot.
+func (test Test) ValidateConfigPermissions(t *testing.T, mountPaths []string) {
+	if problems := configPermissionProblems(mountPaths); len(problems) != 0 {
+		t.Fatalf("installed configs are insecure:\n%s", strings.Join(problems, "\n"))
+	}
+}
+
+func configPermissionProblems(mountPaths []string) (problems []string) {
+	for _, root := range mountPaths {
+		for _, rel := range sensitiveConfigPaths {
+			path := filepath.Join(root, rel)
+			if _, err := os.Lstat(path); os.IsNotExist(err) {
+				continue
+			}
+
+			// the file and each dir up to the partition's root
+			for p := path; ; p = filepath.Dir(p) {
+				if problem := insecurePermissions(p); problem != "" {
+					problems = append(problems, problem)
+				}
+				if p == root {
+					break
+				}
+			}
+		}
+	}
+	return
+}
+
+func insecurePermissions(path string) string {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return fmt.Sprintf("couldn't stat %s: %v", path, err)
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return fmt.Sprintf("%s is a symlink", path)
+	}
+	if info.Mode().Perm()&0002 != 0 && info.Mode()&os.ModeSticky == 0 {
+		return fmt.Sprintf("%s is world-writable: %v", path, info.Mode())
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); !ok || stat.Uid != 0 {
+		return fmt.Sprintf("%s isn't owned by root", path)
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigPermissionProblems(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("the configs have to be owned by root")
+	}
+
+	for _, c := range []struct {
+		name    string
+		mode    os.FileMode
+		dirMode os.FileMode
+		problem string
+	}{
+		{"private", 0600, 0755, ""},
+		{"world-readable", 0644, 0755, ""},
+		{"world-writable file", 0666, 0755, "user_data is world-writable"},
+		{"world-writable dir", 0600, 0777, "coreos-install is world-writable"},
+		{"sticky dir", 0600, 0777 | os.ModeSticky, ""},
+	} {
+		root := t.TempDir()
+		path := filepath.Join(root, cloudinitPath)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte("#cloud-config\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		// chmod, umask doesn't apply
+		if err := os.Chmod(path, c.mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(filepath.Dir(path), c.dirMode); err != nil {
+			t.Fatal(err)
+		}
+
+		problems := configPermissionProblems([]string{root})
+		switch {
+		case c.problem == "" && len(problems) != 0:
+			t.Errorf("%s: unexpected problems %q", c.name, problems)
+		case c.problem != "" && (len(problems) != 1 || !strings.Contains(problems[0], c.problem)):
+			t.Errorf("%s: problems %q, expected %q", c.name, problems, c.problem)
+		}
+	}
+}
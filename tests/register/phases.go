@@ -0,0 +1,126 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"bytes"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Output that marks the phases of an install
+const (
+	PhaseVersion   = `Current version of CoreOS Container Linux`
+	PhaseSignature = `Downloading the signature for`
+	PhaseWrite     = `Downloading, writing and verifying`
+	PhaseFileWrite = `Writing .*\.\.\.`
+	PhaseCloudinit = `Installing cloud-config`
+	PhaseNetwork   = `Copying network units`
+	PhaseIgnition  = `Installing Ignition config`
+	PhaseSuccess   = `Success! `
+)
+
+// phaseMarkers start a new phase when a line matches, the previous phase
+// ends there. The installer starts in the version phase.
+var phaseMarkers = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"signature", regexp.MustCompile(PhaseSignature)},
+	{"download", regexp.MustCompile(PhaseWrite)},
+	{"write", regexp.MustCompile(PhaseFileWrite)},
+	{"postprocess", regexp.MustCompile(PhaseCloudinit)},
+	{"postprocess", regexp.MustCompile(PhaseNetwork)},
+	{"postprocess", regexp.MustCompile(PhaseIgnition)},
+	{"done", regexp.MustCompile(PhaseSuccess)},
+}
+
+type PhaseTiming struct {
+	Name     string
+	Start    time.Duration
+	Duration time.Duration
+}
+
+// phaseTimer timestamps the installer's output lines as they are written
+// to split the install into phases
+type phaseTimer struct {
+	mu      sync.Mutex
+	start   time.Time
+	partial []byte
+	phases  []PhaseTiming
+}
+
+func newPhaseTimer(start time.Time) *phaseTimer {
+	return &phaseTimer{
+		start:  start,
+		phases: []PhaseTiming{{Name: "version"}},
+	}
+}
+
+func (p *phaseTimer) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Since(p.start)
+	p.partial = append(p.partial, data...)
+	for {
+		i := bytes.IndexByte(p.partial, '\n')
+		if i < 0 {
+			break
+		}
+		p.line(p.partial[:i], now)
+		p.partial = p.partial[i+1:]
+	}
+	return len(data), nil
+}
+
+func (p *phaseTimer) line(line []byte, now time.Duration) {
+	for _, marker := range phaseMarkers {
+		if !marker.pattern.Match(line) {
+			continue
+		}
+
+		current := &p.phases[len(p.phases)-1]
+		if current.Name == marker.name {
+			return
+		}
+		current.Duration = now - current.Start
+		p.phases = append(p.phases, PhaseTiming{Name: marker.name, Start: now})
+		return
+	}
+}
+
+// Phases returns the completed phases, the final "done" marker and any
+// phase cut short by the installer exiting are closed at end
+func (p *phaseTimer) Phases(end time.Duration) []PhaseTiming {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	phases := append([]PhaseTiming{}, p.phases...)
+	last := &phases[len(phases)-1]
+	if last.Name == "done" {
+		return phases[:len(phases)-1]
+	}
+	last.Duration = end - last.Start
+	return phases
+}
+
+func logPhases(t *testing.T, phases []PhaseTiming) {
+	for _, phase := range phases {
+		t.Logf("phase %s took %v", phase.Name, phase.Duration)
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"testing"
+)
+
+// ValidateConfigPrecedence asserts an install given both -i and -c boots
+// with Ignition: every kernel line sets coreos.config.url to the installed
+// Ignition config exactly once and nothing points first boot at the
+// cloud-config, which coreos-cloudinit reads from user_data instead
+func (test Test) ValidateConfigPrecedence(t *testing.T, partitions []Partition) {
+	kernels, _ := test.InstalledKernelArgs(t, MountPaths(partitions))
+	for _, kernel := range kernels {
+		urls := KernelArgValues(kernel.Args, "coreos.config.url")
+		if len(urls) != 1 || urls[0] != test.ignitionURL() {
+			t.Fatalf("%s %s should set coreos.config.url=%s once: received %q", kernel.Command, kernel.Path, test.ignitionURL(), urls)
+		}
+
+		if HasKernelArg(kernel.Args, "cloud-config-url") {
+			t.Fatalf("%s %s unexpectedly sets cloud-config-url: received %q", kernel.Command, kernel.Path, kernel.Args)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+// UnprivilegedInvocation runs the installer as nobody. The installer is
+// copied to a temp dir nobody can execute it from, outside the test's
+// TMPDIR which only root can enter.
+func (test Test) UnprivilegedInvocation(t *testing.T) Invocation {
+	nobody, err := user.Lookup("nobody")
+	if err != nil {
+		t.Skipf("couldn't find the nobody user: %v", err)
+	}
+
+	uid, err := strconv.ParseUint(nobody.Uid, 10, 32)
+	if err != nil {
+		t.Fatalf("couldn't parse uid %s: %v", nobody.Uid, err)
+	}
+
+	gid, err := strconv.ParseUint(nobody.Gid, 10, 32)
+	if err != nil {
+		t.Fatalf("couldn't parse gid %s: %v", nobody.Gid, err)
+	}
+
+	dir, err := ioutil.TempDir(config.TempDir, "coreos-install-unprivileged")
+	if err != nil {
+		t.Fatalf("couldn't create temp dir: %v", err)
+	}
+	test.tempManager().Track(dir)
+	if err := os.Chmod(dir, 0755); err != nil {
+		t.Fatalf("couldn't chmod %s: %v", dir, err)
+	}
+
+	binary := filepath.Join(dir, "coreos-install")
+	if err := copyFile(CoreosInstallPath(t), binary, 0755); err != nil {
+		t.Fatalf("couldn't copy coreos-install: %v", err)
+	}
+
+	credential := &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}
+	// otherwise the install fails before the installer's own checks
+	check := exec.Command("/bin/sh", "-c", `test -x "$0"`, binary)
+	check.SysProcAttr = &syscall.SysProcAttr{Credential: credential}
+	if err := check.Run(); err != nil {
+		t.Fatalf("nobody can't execute %s, check the permissions of %s: %v", binary, config.TempDir, err)
+	}
+
+	return Invocation{
+		Binary:     binary,
+		Dir:        "/",
+		Env:        map[string]string{"TMPDIR": "/tmp"},
+		Credential: credential,
+	}
+}
+
+// DiskSnapshot holds the regions of a disk the installer writes first, the
+// partition tables and the wiped tail, to check a failed install didn't
+// touch the disk
+type DiskSnapshot struct {
+	head []byte
+	tail []byte
+}
+
+const snapshotBytes = 1024 * 1024
+
+func (test Test) SnapshotDisk(t *testing.T, diskFile string) DiskSnapshot {
+	f, err := os.Open(diskFile)
+	if err != nil {
+		t.Fatalf("couldn't open disk file: %v", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatalf("couldn't stat disk file: %v", err)
+	}
+
+	snapshot := DiskSnapshot{
+		head: make([]byte, snapshotBytes),
+		tail: make([]byte, snapshotBytes),
+	}
+
+	if _, err := f.ReadAt(snapshot.head, 0); err != nil {
+		t.Fatalf("couldn't read start of disk: %v", err)
+	}
+
+	if _, err := f.ReadAt(snapshot.tail, info.Size()-snapshotBytes); err != nil {
+		t.Fatalf("couldn't read end of disk: %v", err)
+	}
+	return snapshot
+}
+
+// Equal reports whether both snapshots have the same contents
+func (s DiskSnapshot) Equal(other DiskSnapshot) bool {
+	return bytes.Equal(s.head, other.head) && bytes.Equal(s.tail, other.tail)
+}
+
+// ValidateDiskUntouched asserts the disk still matches the snapshot
+func (test Test) ValidateDiskUntouched(t *testing.T, diskFile string, before DiskSnapshot) {
+	after := test.SnapshotDisk(t, diskFile)
+
+	if !bytes.Equal(before.head, after.head) {
+		t.Fatalf("start of the disk was modified")
+	}
+
+	if !bytes.Equal(before.tail, after.tail) {
+		t.Fatalf("end of the disk was modified")
+	}
+}
+
+// AssertPermissionDenied checks an unprivileged install failed up front
+// because it couldn't write the device
+func (test Test) AssertPermissionDenied(t *testing.T, result InstallResult) {
+	test.ValidateInstallerError(t, result, ErrDeviceNotWritable)
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,38 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+func TestUnprivilegedInvocation(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("root is required to run the installer as nobody")
+	}
+
+	// like Run's TMPDIR, only root can enter it
+	test := Test{temp: util.NewTempManagerIn(t.TempDir())}
+	defer test.temp.Close()
+
+	result := test.TryCoreOSInstallWith(t, test.UnprivilegedInvocation(t), "-h")
+	if output := string(result.Stdout) + string(result.Stderr); !strings.Contains(output, "Usage:") {
+		t.Errorf("the installer didn't run as nobody, exit code %d: %s", result.ExitCode, output)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+// the host as seen from a qemu user network
+const qemuHostAddr = "10.0.2.2"
+
+// paths the PXE booted system installs from
+const (
+	pxeInstallerPath = "/opt/bin/coreos-install"
+	pxeScriptPath    = "/opt/bin/coreos-install-pxe"
+	pxeIgnitionPath  = "/var/tmp/coreos-install.ign"
+)
+
+const pxeInstallMarker = "coreos-install-pxe-result"
+
+// RunPXEInstall PXE boots Container Linux from the fixture mirror in a VM
+// and runs the installer under test inside it against a blank virtual
+// disk, like provisioning bare metal. The installed system gets ignition.
+// Device, BaseURL and IgnitionPath are set in opts. It returns the disk
+// file, ready for BootDisk.
+func (test Test) RunPXEInstall(t *testing.T, ignition string, opts InstallOptions) string {
+	test.RequireBoot(t)
+
+	fixture := test.StartFixtureServer(t, "127.0.0.1")
+	defer fixture.Close()
+
+	_, port, err := net.SplitHostPort(fixture.Addr())
+	if err != nil {
+		t.Fatalf("couldn't parse fixture address %s: %v", fixture.Addr(), err)
+	}
+	base := fmt.Sprintf("http://%s:%s", qemuHostAddr, port)
+
+	board := opts.Board
+	if board == "" {
+		board = DefaultBoard()
+	}
+	opts.Device = "/dev/vda"
+	opts.BaseURL = base + "/" + board
+	opts.IgnitionPath = pxeIgnitionPath
+
+	installer, err := ioutil.ReadFile(CoreosInstallPath(t))
+	if err != nil {
+		t.Fatalf("couldn't read coreos-install: %v", err)
+	}
+
+	image := fixture.ServePXE(t, board, "current")
+	test.VerifyPXEImage(t, fixture, image)
+
+	fixture.Serve("/pxe.ign", []byte(pxeIgnition(installer, ignition, opts)))
+	fixture.Serve("/pxe.ipxe", []byte(fmt.Sprintf(`#!ipxe
+kernel %[1]s%[2]s initrd=%[4]s coreos.first_boot=1 coreos.config.url=%[1]s/pxe.ign console=ttyS0,115200n8
+initrd %[1]s%[3]s
+boot
+`, base, image.Kernel, image.Initrd, PXEInitrd)))
+
+	disk := test.TempFile(t, "coreos-install-pxe-disk")
+	disk.Close()
+	if err := os.Truncate(disk.Name(), 10*1024*1024*1024); err != nil {
+		t.Fatalf("failed to truncate disk file: %v", err)
+	}
+
+	vm := test.BootDisk(t, disk.Name(), BootOptions{
+		Writable: true,
+		NetBoot:  base + "/pxe.ipxe",
+	})
+	defer test.ShutdownVM(t, vm)
+
+	matches, err := vm.Console.Expect(DefaultInstallTimeout, regexp.MustCompile(pxeInstallMarker+` status=(\d+)`))
+	if err != nil {
+		vm.fatal(t, err)
+	}
+	if matches[1] != "0" {
+		vm.fatal(t, fmt.Errorf("coreos-install exited %s in the PXE booted system", matches[1]))
+	}
+	fixture.ValidatePXEDownloads(t, image)
+	return disk.Name()
+}
+
+// pxeIgnition is the PXE booted system's config, it writes the installer
+// and the installed system's config and installs from a unit
+func pxeIgnition(installer []byte, ignition string, opts InstallOptions) string {
+	script := fmt.Sprintf(`#!/bin/sh
+%s
+echo "%s status=$?" > /dev/ttyS0
+`, util.ShellJoin(append([]string{pxeInstallerPath}, opts.Args()...)...), pxeInstallMarker)
+
+	unit := fmt.Sprintf(`[Unit]
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=oneshot
+StandardOutput=journal+console
+StandardError=journal+console
+ExecStart=%s
+
+[Install]
+WantedBy=multi-user.target
+`, pxeScriptPath)
+
+	return mustJSON(map[string]interface{}{
+		"ignition": map[string]interface{}{"version": "2.1.0"},
+		"storage": map[string]interface{}{
+			"files": []interface{}{
+				map[string]interface{}{
+					"filesystem": "root",
+					"path":       pxeInstallerPath,
+					"mode":       0755,
+					"contents":   map[string]interface{}{"source": dataURL(installer)},
+				},
+				map[string]interface{}{
+					"filesystem": "root",
+					"path":       pxeScriptPath,
+					"mode":       0755,
+					"contents":   map[string]interface{}{"source": dataURL([]byte(script))},
+				},
+				map[string]interface{}{
+					"filesystem": "root",
+					"path":       pxeIgnitionPath,
+					"mode":       0644,
+					"contents":   map[string]interface{}{"source": dataURL([]byte(ignition))},
+				},
+			},
+		},
+		"systemd": map[string]interface{}{
+			"units": []interface{}{
+				map[string]interface{}{
+					"name":     "coreos-install-pxe.service",
+					"enabled":  true,
+					"contents": unit,
+				},
+			},
+		},
+	})
+}
+
+func dataURL(data []byte) string {
+	return "data:;base64," + base64.StdEncoding.EncodeToString(data)
+}
@@ -0,0 +1,141 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"flag"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+var pxeDirFlag = flag.String("pxe-dir", config.PXEDir, "directory with "+PXEKernel+", "+PXEInitrd+" and their .sig files, served for every version of the fixture mirror that doesn't have its own [$COREOS_TEST_PXE_DIR]")
+
+// the PXE artifacts of a release, published next to its images
+const (
+	PXEKernel = "coreos_production_pxe.vmlinuz"
+	PXEInitrd = "coreos_production_pxe_image.cpio.gz"
+)
+
+// PXEImage is where the fixture serves a release's PXE artifacts, as URL
+// paths to join with whatever address the client reaches the fixture at.
+// Each has its signature at the same path plus .sig.
+type PXEImage struct {
+	Kernel string
+	Initrd string
+}
+
+// ServePXE serves the PXE artifacts and signatures for board and version,
+// from the mirror if it has them and -pxe-dir otherwise, skipping the test
+// if neither does. version may be "current".
+func (f *FixtureServer) ServePXE(t *testing.T, board, version string) PXEImage {
+	dir := path.Join("/", board, version)
+	image := PXEImage{
+		Kernel: path.Join(dir, PXEKernel),
+		Initrd: path.Join(dir, PXEInitrd),
+	}
+
+	mirrored := filepath.Join(*fixtureDirFlag, board, version)
+	if hasPXEArtifacts(mirrored) {
+		return image
+	}
+	if *pxeDirFlag == "" || !hasPXEArtifacts(*pxeDirFlag) {
+		t.Skipf("neither the fixture mirror at %s nor -pxe-dir has %s, %s and their signatures", mirrored, PXEKernel, PXEInitrd)
+	}
+
+	for _, name := range pxeArtifactFiles() {
+		file := filepath.Join(*pxeDirFlag, name)
+		f.Handle(path.Join(dir, name), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.ServeFile(w, r, file)
+		}))
+	}
+	return image
+}
+
+// ValidatePXEDownloads asserts a PXE booted machine fetched the kernel and
+// initramfs from the fixture
+func (f *FixtureServer) ValidatePXEDownloads(t *testing.T, image PXEImage) {
+	for _, p := range []string{image.Kernel, image.Initrd} {
+		f.AssertRequested(t, p)
+	}
+}
+
+var gpgKeyPattern = regexp.MustCompile(`(?s)GPG_KEY="(-----BEGIN PGP PUBLIC KEY BLOCK-----.*?-----END PGP PUBLIC KEY BLOCK-----)`)
+
+// VerifyPXEImage checks the served artifacts against their signatures with
+// the key the installer verifies images with, so a -pxe-dir that doesn't
+// match the release fails here instead of as an obscure boot failure
+func (test Test) VerifyPXEImage(t *testing.T, f *FixtureServer, image PXEImage) {
+	util.RequireTools(t, "gpg")
+	installer := CoreosInstallPath(t)
+	script, err := ioutil.ReadFile(installer)
+	if err != nil {
+		t.Fatalf("couldn't read coreos-install: %v", err)
+	}
+	key := gpgKeyPattern.FindSubmatch(script)
+	if key == nil {
+		t.Skipf("%s doesn't embed GPG_KEY to verify with", installer)
+	}
+
+	home := test.TempDir(t, "coreos-install-gnupg")
+	if err := os.Chmod(home, 0700); err != nil {
+		t.Fatal(err)
+	}
+	util.MustRun(t, "gpg", "--homedir", home, "--batch", "--quiet", "--import", test.WriteFile(t, string(key[1])))
+
+	for _, p := range []string{image.Kernel, image.Initrd} {
+		artifact, sig := test.download(t, f.URL+p), test.download(t, f.URL+p+".sig")
+		util.MustRun(t, "gpg", "--homedir", home, "--batch", "--verify", sig, artifact)
+	}
+}
+
+// download saves url to a temp file
+func (test Test) download(t *testing.T, url string) string {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("couldn't download %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("couldn't download %s: %s", url, resp.Status)
+	}
+
+	f := test.TempFile(t, "coreos-install-download")
+	defer f.Close()
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		t.Fatalf("couldn't download %s: %v", url, err)
+	}
+	return f.Name()
+}
+
+func pxeArtifactFiles() []string {
+	return []string{PXEKernel, PXEKernel + ".sig", PXEInitrd, PXEInitrd + ".sig"}
+}
+
+func hasPXEArtifacts(dir string) bool {
+	for _, name := range pxeArtifactFiles() {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
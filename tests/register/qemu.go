@@ -0,0 +1,519 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreos/init/tests/util"
+)
+
+var (
+	qemuFlag        = flag.String("qemu", config.QEMU, "qemu-system-x86_64 to boot installed disks with, boot tests are skipped without it [$COREOS_TEST_QEMU]")
+	ovmfFlag        = flag.String("ovmf", config.OVMF, "OVMF firmware code for UEFI boots [$COREOS_TEST_OVMF]")
+	ovmfSecureFlag  = flag.String("ovmf-secure-boot", config.OVMFSecureBoot, "OVMF firmware code built with SMM for Secure Boot boots [$COREOS_TEST_OVMF_SECURE_BOOT]")
+	ovmfSecureVars  = flag.String("ovmf-secure-boot-vars", config.OVMFSecureBootVars, "OVMF variable store with Secure Boot keys enrolled [$COREOS_TEST_OVMF_SECURE_BOOT_VARS]")
+	bootTimeoutFlag = flag.Duration("boot-timeout", config.BootTimeout, "how long a booted install has to reach each expected console message [$COREOS_TEST_BOOT_TIMEOUT]")
+)
+
+type Firmware int
+
+const (
+	// SeaBIOS, qemu's default
+	BIOS Firmware = iota
+	// OVMF
+	UEFI
+	// OVMF enforcing Secure Boot
+	UEFISecureBoot
+)
+
+// Firmwares is every firmware an install should boot with
+var Firmwares = []Firmware{BIOS, UEFI, UEFISecureBoot}
+
+func (f Firmware) String() string {
+	switch f {
+	case BIOS:
+		return "BIOS"
+	case UEFI:
+		return "UEFI"
+	case UEFISecureBoot:
+		return "UEFI Secure Boot"
+	}
+	return fmt.Sprintf("Firmware(%d)", int(f))
+}
+
+type BootOptions struct {
+	Firmware Firmware
+	// MiB of memory, defaults to 2048
+	Memory int
+	// how long each Expect waits, defaults to -boot-timeout
+	Timeout time.Duration
+
+	// write to the disk instead of a snapshot of it, ShutdownVM powers
+	// the VM off cleanly so everything it wrote is on the disk
+	Writable bool
+	// iPXE script or image URL to boot from the network before the disk
+	NetBoot string
+	// attached after the installed disk, always as snapshots
+	ExtraDisks []string
+}
+
+// VM is an installed disk booted under qemu. Unless it's Writable the disk
+// is opened with snapshot=on, so booting doesn't change it and each boot is
+// a first boot.
+type VM struct {
+	Console *util.Expecter
+	// localhost port forwarded to the VM's sshd
+	SSHPort int
+
+	// saved console output, "" without -artifact-dir
+	ConsoleLog string
+
+	timeout time.Duration
+	// QMP socket of a Writable VM, for powering it off
+	qmp    string
+	cancel context.CancelFunc
+	// closed once qemu exited
+	done chan struct{}
+
+	firmware Firmware
+}
+
+// RequireBoot skips the test if installed disks can't be booted, call it
+// before installing so the install isn't wasted
+func (test Test) RequireBoot(t *testing.T) {
+	if *qemuFlag == "" {
+		t.Skip("-qemu is required to boot installed disks")
+	}
+	util.RequireTools(t, *qemuFlag)
+}
+
+// BootDisk boots diskFile, see RequireBoot
+func (test Test) BootDisk(t *testing.T, diskFile string, opts BootOptions) *VM {
+	test.RequireBoot(t)
+
+	if opts.Memory == 0 {
+		opts.Memory = 2048
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = *bootTimeoutFlag
+	}
+
+	vm := &VM{
+		Console:  util.NewExpecter(),
+		SSHPort:  freePort(t),
+		firmware: opts.Firmware,
+		timeout:  opts.Timeout,
+		done:     make(chan struct{}),
+	}
+
+	args := []string{
+		"-nodefaults", "-display", "none",
+		"-m", fmt.Sprint(opts.Memory),
+		"-serial", "stdio",
+	}
+
+	drive := "file=" + diskFile + ",if=virtio,format=raw"
+	if opts.Writable {
+		vm.qmp = filepath.Join(test.TempDir(t, "coreos-install-qmp"), "qmp.sock")
+		args = append(args, "-qmp", "unix:"+vm.qmp+",server=on,wait=off")
+	} else {
+		drive += ",snapshot=on"
+	}
+	args = append(args, "-drive", drive)
+	for _, extra := range opts.ExtraDisks {
+		args = append(args, "-drive", "file="+extra+",if=virtio,format=raw,snapshot=on")
+	}
+
+	// without kvm boots still work, just slowly
+	if f, err := os.OpenFile("/dev/kvm", os.O_RDWR, 0); err == nil {
+		f.Close()
+		args = append(args, "-enable-kvm", "-cpu", "host")
+	} else {
+		t.Logf("booting without kvm: %v", err)
+	}
+
+	nic := fmt.Sprintf("user,model=virtio-net-pci,hostfwd=tcp:127.0.0.1:%d-:22", vm.SSHPort)
+	if opts.NetBoot != "" {
+		// the NIC's iPXE rom fetches the DHCP filename itself
+		nic += ",bootfile=" + opts.NetBoot
+		args = append(args, "-boot", "order=nc")
+	}
+	args = append(args, "-nic", nic)
+
+	switch opts.Firmware {
+	case UEFI:
+		args = append(args, test.ovmfArgs(t)...)
+	case UEFISecureBoot:
+		args = append(args, test.secureBootArgs(t)...)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	vm.cancel = cancel
+	cmd := util.Command(ctx, *qemuFlag, args...)
+	cmd.Stdout = vm.Console
+	cmd.Stderr = vm.Console
+
+	var console *os.File
+	if vm.ConsoleLog = ArtifactPath(t, "console.log"); vm.ConsoleLog != "" {
+		var err error
+		if console, err = os.Create(vm.ConsoleLog); err != nil {
+			cancel()
+			t.Fatalf("couldn't create console log: %v", err)
+		}
+		cmd.Stdout = io.MultiWriter(vm.Console, console)
+		cmd.Stderr = cmd.Stdout
+		t.Logf("saving the console to %s", vm.ConsoleLog)
+	}
+
+	t.Logf("booting %s", util.FormatCommand(*qemuFlag, args...))
+	if err := cmd.Start(); err != nil {
+		cancel()
+		t.Fatalf("couldn't start qemu: %v", err)
+	}
+
+	go func() {
+		cmd.Wait()
+		util.Reap(cmd)
+		vm.Console.Close()
+		if console != nil {
+			console.Close()
+		}
+		close(vm.done)
+	}()
+	return vm
+}
+
+// ovmfArgs loads -ovmf with a private copy of its variable store, if it
+// has one next to it
+func (test Test) ovmfArgs(t *testing.T) []string {
+	if _, err := os.Stat(*ovmfFlag); err != nil {
+		t.Skipf("UEFI firmware not found: %v", err)
+	}
+
+	args := []string{"-drive", "if=pflash,format=raw,unit=0,readonly=on,file=" + *ovmfFlag}
+
+	vars := strings.Replace(*ovmfFlag, "CODE", "VARS", 1)
+	if vars == *ovmfFlag {
+		return args
+	}
+	if _, err := os.Stat(vars); err != nil {
+		return args
+	}
+
+	return append(args, "-drive", "if=pflash,format=raw,unit=1,file="+test.copyVars(t, vars))
+}
+
+// secureBootArgs loads the Secure Boot OVMF, which needs SMM and so the q35
+// machine
+func (test Test) secureBootArgs(t *testing.T) []string {
+	if *ovmfSecureFlag == "" || *ovmfSecureVars == "" {
+		t.Skip("-ovmf-secure-boot and -ovmf-secure-boot-vars are required to boot with Secure Boot")
+	}
+	if _, err := os.Stat(*ovmfSecureFlag); err != nil {
+		t.Skipf("Secure Boot firmware not found: %v", err)
+	}
+
+	return []string{
+		"-machine", "q35,smm=on",
+		"-global", "driver=cfi.pflash01,property=secure,value=on",
+		"-drive", "if=pflash,format=raw,unit=0,readonly=on,file=" + *ovmfSecureFlag,
+		"-drive", "if=pflash,format=raw,unit=1,file=" + test.copyVars(t, *ovmfSecureVars),
+	}
+}
+
+// copyVars gives a boot its own copy of a variable store, the firmware
+// writes to it
+func (test Test) copyVars(t *testing.T, vars string) string {
+	copied := filepath.Join(test.TempDir(t, "coreos-install-ovmf"), filepath.Base(vars))
+	if err := copyFile(vars, copied, 0644); err != nil {
+		t.Fatalf("couldn't copy UEFI variables: %v", err)
+	}
+	return copied
+}
+
+// RunFirmwareMatrix runs f in a subtest for each of Firmwares, firmware a
+// host can't provide is skipped
+func (test Test) RunFirmwareMatrix(t *testing.T, f func(t *testing.T, firmware Firmware)) {
+	for _, firmware := range Firmwares {
+		firmware := firmware
+		t.Run(firmware.String(), func(t *testing.T) {
+			f(t, firmware)
+		})
+	}
+}
+
+// Expect waits for pattern on the VM's serial console and returns its
+// submatches, failing the test if the VM doesn't print it in time
+func (vm *VM) Expect(t *testing.T, pattern string) []string {
+	matches, err := vm.Console.Expect(vm.timeout, regexp.MustCompile(pattern))
+	if err != nil {
+		vm.fatal(t, err)
+	}
+	return matches
+}
+
+// fatal fails the test with the end of the console, and why the boot went
+// wrong if it printed that
+func (vm *VM) fatal(t *testing.T, err error) {
+	output := vm.Console.Output()
+	if failures := bootFailures(output); len(failures) != 0 {
+		t.Fatalf("%v, the boot failed:\n%s", err, strings.Join(failures, "\n"))
+	}
+	t.Fatalf("%v, console ended with:\n%s", err, consoleTail(output))
+}
+
+var (
+	// printed by systemd in the initramfs as Ignition applies the config
+	IgnitionBootMessages = []string{
+		`Started Ignition \(disks\)`,
+		`Started Ignition \(files\)`,
+	}
+	MultiUserBootMessage = `Reached target Multi-User System`
+
+	// printed by the kernel, so the boot is known to have used the
+	// firmware it was meant to
+	FirmwareBootMessages = map[Firmware][]string{
+		UEFI:           {`efi: EFI v`},
+		UEFISecureBoot: {`efi: EFI v`, `Secure boot enabled`},
+	}
+
+	// a boot that prints any of these failed
+	BootFailurePatterns = []string{
+		`Kernel panic`,
+		`Entering emergency mode`,
+		`dracut-emergency`,
+		`Failed to start Ignition`,
+		`You are in emergency mode`,
+	}
+)
+
+// ValidateBootMessages waits for each message on the console. They're
+// matched anywhere in the output, so their order doesn't matter.
+func (test Test) ValidateBootMessages(t *testing.T, vm *VM, messages ...string) {
+	ctx, cancel := context.WithTimeout(context.Background(), vm.timeout)
+	defer cancel()
+
+	for _, m := range messages {
+		if _, err := vm.Console.Seen(ctx, regexp.MustCompile(m)); err != nil {
+			vm.fatal(t, err)
+		}
+	}
+}
+
+// ValidateNoBootFailures asserts the console so far has no kernel panics or
+// drops to an emergency shell
+func (test Test) ValidateNoBootFailures(t *testing.T, vm *VM) {
+	if failures := bootFailures(vm.Console.Output()); len(failures) != 0 {
+		t.Fatalf("the boot failed:\n%s", strings.Join(failures, "\n"))
+	}
+}
+
+func bootFailures(output []byte) []string {
+	var failures []string
+	for _, line := range strings.Split(string(output), "\n") {
+		for _, p := range BootFailurePatterns {
+			if regexp.MustCompile(p).MatchString(line) {
+				failures = append(failures, strings.TrimSpace(line))
+				break
+			}
+		}
+	}
+	return failures
+}
+
+const consoleTailLines = 30
+
+func consoleTail(output []byte) string {
+	lines := strings.Split(strings.TrimRight(string(output), "\n"), "\n")
+	if len(lines) > consoleTailLines {
+		lines = lines[len(lines)-consoleTailLines:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ShutdownVM stops the VM. A snapshot is thrown away, so the VM is just
+// killed, but a Writable VM is powered off first, or the writes its guest
+// still had cached would never make it to the disk. Stopping a VM twice
+// is fine, so it can be deferred and still called early.
+func (test Test) ShutdownVM(t *testing.T, vm *VM) {
+	defer func() {
+		vm.cancel()
+		<-vm.done
+	}()
+	select {
+	case <-vm.done:
+		return
+	default:
+	}
+	if vm.qmp == "" {
+		return
+	}
+
+	if err := vm.powerdown(); err != nil {
+		t.Errorf("couldn't power off the VM, its disk may be missing writes: %v", err)
+		return
+	}
+	select {
+	case <-vm.done:
+	case <-time.After(vm.timeout):
+		t.Errorf("the VM didn't power off within %v, its disk may be missing writes", vm.timeout)
+	}
+}
+
+// powerdown presses the VM's ACPI power button over QMP, qemu exits once
+// the guest has shut down
+func (vm *VM) powerdown() error {
+	conn, err := net.DialTimeout("unix", vm.qmp, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Minute))
+
+	// qemu greets first, then replies to each command, maybe after
+	// some events
+	dec := json.NewDecoder(conn)
+	var greeting map[string]interface{}
+	if err := dec.Decode(&greeting); err != nil {
+		return fmt.Errorf("reading the QMP greeting: %v", err)
+	}
+	for _, command := range []string{"qmp_capabilities", "system_powerdown"} {
+		if _, err := fmt.Fprintf(conn, "{\"execute\": %q}\n", command); err != nil {
+			return err
+		}
+		for {
+			var reply map[string]interface{}
+			if err := dec.Decode(&reply); err != nil {
+				return fmt.Errorf("%s: %v", command, err)
+			}
+			if e, ok := reply["error"]; ok {
+				return fmt.Errorf("%s: %v", command, e)
+			}
+			if _, ok := reply["return"]; ok {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// BootCheck is Ignition config that shows it was applied once the installed
+// system boots: it adds a user and a file, and a unit that prints both to
+// the serial console
+type BootCheck struct {
+	User     string
+	Path     string
+	Contents string
+
+	// authorized for the core user, see GenerateSSHKey
+	SSHKeys []string
+}
+
+func NewBootCheck() BootCheck {
+	return BootCheck{
+		User:     "coreos-install-test",
+		Path:     "/etc/coreos-install-test",
+		Contents: fmt.Sprintf("installed-%d", time.Now().UnixNano()),
+	}
+}
+
+const bootCheckMarker = "coreos-install-boot-check"
+
+func (c BootCheck) Ignition() string {
+	// $$ is a literal $ to systemd
+	unit := fmt.Sprintf(`[Unit]
+After=systemd-user-sessions.service
+
+[Service]
+Type=oneshot
+ExecStart=/bin/sh -c 'echo "%s user=$$(id -un %s) file=$$(cat %s)" > /dev/ttyS0'
+
+[Install]
+WantedBy=multi-user.target
+`, bootCheckMarker, c.User, c.Path)
+
+	users := []interface{}{
+		map[string]interface{}{"name": c.User},
+	}
+	if len(c.SSHKeys) != 0 {
+		users = append(users, map[string]interface{}{
+			"name":              "core",
+			"sshAuthorizedKeys": c.SSHKeys,
+		})
+	}
+
+	return mustJSON(map[string]interface{}{
+		"ignition": map[string]interface{}{"version": "2.1.0"},
+		"passwd":   map[string]interface{}{"users": users},
+		"storage": map[string]interface{}{
+			"files": []interface{}{
+				map[string]interface{}{
+					"filesystem": "root",
+					"path":       c.Path,
+					"mode":       0644,
+					"contents":   map[string]interface{}{"source": "data:," + c.Contents},
+				},
+			},
+		},
+		"systemd": map[string]interface{}{
+			"units": []interface{}{
+				map[string]interface{}{
+					"name":     "coreos-install-boot-check.service",
+					"enabled":  true,
+					"contents": unit,
+				},
+			},
+		},
+	})
+}
+
+// ValidateBoot waits for the VM to print the BootCheck's user and file and
+// then reach a login prompt, and checks Ignition ran and nothing failed on
+// the way
+func (test Test) ValidateBoot(t *testing.T, vm *VM, check BootCheck) {
+	matches := vm.Expect(t, bootCheckMarker+` user=(\S*) file=(\S*)`)
+	if matches[1] != check.User {
+		t.Fatalf("user %s wasn't created: id printed %q", check.User, matches[1])
+	}
+	if matches[2] != check.Contents {
+		t.Fatalf("%s wasn't written: expected %q, received %q", check.Path, check.Contents, matches[2])
+	}
+
+	vm.Expect(t, `login: `)
+
+	test.ValidateBootMessages(t, vm, append(IgnitionBootMessages, MultiUserBootMessage)...)
+	test.ValidateBootMessages(t, vm, FirmwareBootMessages[vm.firmware]...)
+	test.ValidateNoBootFailures(t, vm)
+}
+
+func mustJSON(v interface{}) string {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,70 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// a fake qemu that greets, answers each command, and sends an event before
+// answering system_powerdown
+func TestPowerdown(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "qmp.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+
+		fmt.Fprintln(conn, `{"QMP": {"version": {}, "capabilities": []}}`)
+		var commands []string
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			var command struct {
+				Execute string
+			}
+			json.Unmarshal(scanner.Bytes(), &command)
+			commands = append(commands, command.Execute)
+			if command.Execute == "system_powerdown" {
+				fmt.Fprintln(conn, `{"event": "POWERDOWN", "timestamp": {}}`)
+			}
+			fmt.Fprintln(conn, `{"return": {}}`)
+		}
+		received <- commands
+	}()
+
+	vm := &VM{qmp: socket}
+	if err := vm.powerdown(); err != nil {
+		t.Fatal(err)
+	}
+	if commands := <-received; !reflect.DeepEqual(commands, []string{"qmp_capabilities", "system_powerdown"}) {
+		t.Errorf("sent %q", commands)
+	}
+}
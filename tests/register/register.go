@@ -16,49 +16,190 @@ package register
 
 import (
 	"bufio"
+	"context"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/coreos/init/tests/util"
 )
 
+var (
+	// where coreos-install writes -i on the OEM partition
+	ignitionConfigPath = "coreos-install.json"
+	// where coreos-install writes -c on the ROOT partition
+	cloudinitPath = filepath.Join("var", "lib", "coreos-install", "user_data")
+)
+
 type Test struct {
 	Name string
 	Func func(*testing.T, Test)
+
+	// extra args expected on the installed kernel command line
+	KernelArgs []string
+
+	// check partitions are at their default numbers, not just present
+	StrictPartitionNumbers bool
+
+	// coreos.config.url grub.cfg should point at for -i, defaults to
+	// DefaultIgnitionURL
+	IgnitionURL string
+
+	// skip the test on hosts without these, see util.ProbeHost
+	Requires []util.HostCapability
+
+	// the test relies on the script's internals, like its set -x trace, so
+	// it's skipped with -implementation go
+	ScriptOnly bool
+
+	// removes the test's temp files when it finishes, set by Run
+	temp *util.TempManager
+	// device mapper devices the test created, set by Run
+	mapped *mappedDevices
+	// the fault chaos mode injects into the test, set by Run
+	chaos *ChaosFault
+	// runs instead of CoreosInstallPath, see WithInstaller
+	installer string
+}
+
+// temp files created outside of Run are removed if the suite is interrupted
+var defaultTemp = util.NewTempManager()
+
+func (test Test) tempManager() *util.TempManager {
+	if test.temp != nil {
+		return test.temp
+	}
+	return defaultTemp
+}
+
+// TempRoot is the test's TMPDIR, where its temp files are created and
+// where the installer creates its own
+func (test Test) TempRoot() string {
+	return test.tempManager().Root()
+}
+
+// WithTempDir returns a copy of the test that creates its temp files, and
+// runs the installer with TMPDIR, in dir. They're still removed when the
+// test finishes.
+func (test Test) WithTempDir(dir string) Test {
+	test.temp = test.tempManager().In(dir)
+	return test
+}
+
+// TempDir creates a dir that's removed when the test finishes
+func (test Test) TempDir(t *testing.T, prefix string) string {
+	dir, err := test.tempManager().Dir(prefix)
+	if err != nil {
+		t.Fatalf("couldn't create temp dir: %v", err)
+	}
+	return dir
+}
+
+// TempFile creates a file that's removed when the test finishes
+func (test Test) TempFile(t *testing.T, prefix string) *os.File {
+	f, err := test.tempManager().File(prefix)
+	if err != nil {
+		t.Fatalf("couldn't create temp file: %v", err)
+	}
+	return f
+}
+
+// DefaultIgnitionURL is where the installer points Ignition at the config
+// it copied to the OEM partition
+const DefaultIgnitionURL = "oem:///coreos-install.json"
+
+func (test Test) ignitionURL() string {
+	if test.IgnitionURL != "" {
+		return test.IgnitionURL
+	}
+	return DefaultIgnitionURL
+}
+
+// WithIgnitionURL returns a copy of the test that expects grub.cfg to point
+// Ignition at url, e.g. a config on a specific partition
+func (test Test) WithIgnitionURL(url string) Test {
+	test.IgnitionURL = url
+	return test
 }
 
 func (test Test) Run(t *testing.T) {
-	if os.Getenv("TMPDIR") == "" {
-		tmpDir, err := ioutil.TempDir("/var/tmp", "")
-		if err != nil {
-			t.Fatalf("failed to create temp working dir in /var/tmp: %v", err)
-		}
+	if configErr != nil {
+		t.Fatal(configErr)
+	}
+	// flags are only parsed once the tests start, so util's settings can't
+	// be applied at init, and tests may run in parallel once they have been
+	configureOnce.Do(func() {
+		util.PreflightFatal = *requireToolsFlag
+		util.DefaultCommandTimeout = config.CommandTimeout
+		addSecretPattern()
+		loadSuiteState(t)
+	})
+	if secretPatternErr != nil {
+		t.Fatal(secretPatternErr)
+	}
+	if stateErr != nil {
+		t.Fatal(stateErr)
+	}
+	// last, once everything the test saved is closed
+	defer redactArtifacts(t)
+	util.RequireHost(t, test.Requires...)
+	if test.ScriptOnly && *implementationFlag != "script" {
+		t.Skipf("only applies to the script, not -implementation %s", *implementationFlag)
+	}
+	defer test.skipPassed(t)()
 
-		err = os.Setenv("TMPDIR", tmpDir)
-		if err != nil {
-			t.Fatalf("couldn't set TMPDIR env var: %v", err)
-		}
+	// each test gets its own TMPDIR, passed to the installer rather than
+	// set in the suite's environment so tests can run in parallel
+	tmpDir, err := ioutil.TempDir(config.TempDir, "")
+	if err != nil {
+		t.Fatalf("failed to create temp working dir in %s: %v", config.TempDir, err)
+	}
+	defer test.RemoveAll(t, tmpDir)
 
-		defer test.RemoveAll(t, tmpDir)
-		defer os.Setenv("TMPDIR", "")
+	test.mapped = &mappedDevices{under: map[string]string{}}
+	if *chaosFlag != 0 {
+		fault := chaosFault(*chaosFlag, t.Name())
+		test.chaos = &fault
+		t.Logf("chaos: injecting %s, reproduce with -chaos %d -test.run '^%s$'", fault, *chaosFlag, t.Name())
 	}
+
+	test.temp = util.NewTempManagerIn(tmpDir)
+	defer func() {
+		if err := test.temp.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
 	test.Func(t, test)
 }
 
+var configureOnce sync.Once
+
+var requireToolsFlag = flag.Bool("require-tools", config.RequireTools, "fail tests that are missing tools or privileges instead of skipping them [$COREOS_TEST_REQUIRE_TOOLS]")
+
 func (test Test) CreateDevice(t *testing.T) (string, string) {
-	diskFile, err := ioutil.TempFile("", "coreos-install-disk")
-	if err != nil {
-		t.Fatalf("failed to create disk file: %v", err)
-	}
+	// this should be large enough for any image
+	return test.CreateDeviceSize(t, 10*1024*1024*1024)
+}
 
-	// truncate the disk file to 10GB, this should be large enough
-	err = os.Truncate(diskFile.Name(), 10*1024*1024*1024)
+// CreateDeviceSize is CreateDevice with a disk of size bytes, for tests
+// that never get as far as writing an image
+func (test Test) CreateDeviceSize(t *testing.T, size int64) (string, string) {
+	util.RequireRoot(t)
+	util.RequireTools(t, "sgdisk>=1.0", "kpartx")
+	util.RequireHost(t, util.HostLoopDevices, util.HostLoopPartscan)
+
+	diskFile := test.TempFile(t, "coreos-install-disk")
+	diskFile.Close()
+
+	err := os.Truncate(diskFile.Name(), size)
 	if err != nil {
 		t.Fatalf("failed to truncate disk file: %v", err)
 	}
@@ -66,17 +207,50 @@ func (test Test) CreateDevice(t *testing.T) (string, string) {
 	// create a gpt table
 	util.MustRun(t, "sgdisk", diskFile.Name())
 
-	// back a loop device with the disk file
-	device := string(util.MustRun(t, "losetup", "-P", "-f", diskFile.Name(), "--show"))
-	return diskFile.Name(), strings.TrimSpace(device)
+	// back a loop device with the disk file, other tests can grab the same
+	// free device first so it's retried
+	var loopDevice string
+	err = util.Retry(context.Background(), util.DefaultRetryPolicy, func() error {
+		loopDevice, err = util.AttachLoop(diskFile.Name(), util.LoopPartscan)
+		if e, ok := err.(*util.LoopError); ok && e.Errno != syscall.EBUSY {
+			return util.Permanent(err)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("couldn't attach loop device: %v", err)
+	}
+	// released on resume if the run is killed before CleanupDisk
+	if err := suiteState.AddDevice(ScratchDevice{diskFile.Name(), loopDevice}); err != nil {
+		t.Error(err)
+	}
+
+	if test.injects(ChaosSlowDisk) {
+		return diskFile.Name(), test.CreateDelayedDevice(t, loopDevice, chaosDiskDelay)
+	}
+	return diskFile.Name(), loopDevice
 }
 
+// CleanupDisk detaches the loop device and removes its disk file, failures
+// are reported but don't stop the rest of the cleanup
 func (test Test) CleanupDisk(t *testing.T, diskFile, loopDevice string) {
-	util.MustRun(t, "losetup", "-d", loopDevice)
+	if test.underlying(loopDevice) != "" {
+		loopDevice = test.RemoveMappedDevice(t, loopDevice)
+	}
+
+	err := util.Retry(context.Background(), util.DefaultRetryPolicy, func() error {
+		return util.DetachLoop(loopDevice)
+	})
+	if err != nil {
+		t.Error(err)
+	} else if err := suiteState.RemoveDevice(loopDevice); err != nil {
+		t.Error(err)
+	}
 	test.RemoveAll(t, diskFile)
 }
 
 func (test Test) CreateDeviceMappers(t *testing.T, diskFile string) (devices []string) {
+	util.RequireHost(t, util.HostDeviceMapper)
 	out := util.MustRun(t, "kpartx", "-avs", diskFile)
 	devices = util.RegexpSearchAll(t, "loop device", "map (?P<device>[\\w\\d]+)", out)
 
@@ -89,77 +263,70 @@ func (test Test) CreateDeviceMappers(t *testing.T, diskFile string) (devices []s
 }
 
 func (test Test) RemoveDeviceMappers(t *testing.T, diskFile string) {
-	util.MustRun(t, "kpartx", "-d", diskFile)
+	if _, err := util.RunRetry(context.Background(), util.DefaultRetryPolicy, "kpartx", "-d", diskFile); err != nil {
+		t.Error(err)
+	}
+}
+
+// device mapper nodes can take a moment to appear after kpartx returns
+var mountRetryPolicy = util.RetryPolicy{
+	Attempts:   3,
+	Initial:    100 * time.Millisecond,
+	Multiplier: 2,
+	Jitter:     0.2,
 }
 
 func (test Test) MountDeviceMapper(t *testing.T, device string) string {
-	dir, err := ioutil.TempDir("", "coreos-install-mount-point")
+	dir, _ := test.mountReadOnly(t, device)
+	return dir
+}
+
+// mountReadOnly mounts device, returning "" and why if it couldn't be
+func (test Test) mountReadOnly(t *testing.T, device string) (string, error) {
+	dir, err := test.tempManager().MountPoint("coreos-install-mount-point")
 	if err != nil {
 		t.Fatalf("couldn't create mount point directory: %v", err)
 	}
 
-	err = util.Run(t, "mount", device, dir, "-o", "ro")
+	err = util.Retry(context.Background(), mountRetryPolicy, func() error {
+		err := util.Mount(device, dir, "", syscall.MS_RDONLY, "")
+		// no filesystem is EINVAL, don't retry partitions that don't have one
+		if e, ok := err.(*util.MountError); ok && e.Errno == syscall.EINVAL {
+			return util.Permanent(err)
+		}
+		return err
+	})
 	if err != nil {
-		return ""
+		return "", err
 	}
 
-	return dir
+	return dir, nil
 }
 
 func (test Test) UnmountPath(t *testing.T, path string) {
-	util.MustRun(t, "umount", path)
-}
-
-func WhichCoreosInstall(t *testing.T) string {
-	out, err := exec.Command("which", "coreos-install").CombinedOutput()
+	err := util.Retry(context.Background(), util.DefaultRetryPolicy, func() error {
+		return util.Unmount(path, false)
+	})
 	if err != nil {
-		return ""
+		// don't leave the mount behind for the temp cleanup to trip over
+		t.Error(err)
+		util.Unmount(path, true)
 	}
-
-	return filepath.Dir(string(out))
 }
 
-func (test Test) RunCoreOSInstall(t *testing.T, opts ...string) {
-	util.MustRun(t, "coreos-install", opts...)
-}
-
-func (test Test) ValidateIgnition(t *testing.T, mountPaths []string, config string) {
-	ignition_found := false
-	grub_found := false
-	for _, p := range mountPaths {
-		ignition_path := filepath.Join(p, "coreos-install.json")
-		if _, err := os.Stat(ignition_path); !os.IsNotExist(err) {
-			ignition_found = true
-			data, err := ioutil.ReadFile(ignition_path)
-			if err != nil {
-				t.Fatalf("couldn't read coreos-install.json: %v", err)
-			}
-
-			if string(data) != config {
-				t.Fatalf("coreos-install.json doesn't match: expected %s, received %s", config, data)
-			}
-
-		}
-
-		grub_path := filepath.Join(p, "grub.cfg")
-		if _, err := os.Stat(grub_path); !os.IsNotExist(err) {
-			grub_found = true
-			data, err := ioutil.ReadFile(grub_path)
-			if err != nil {
-				t.Fatalf("couldn't read grub.cfg: %v", err)
-			}
-
-			util.RegexpContains(t, "ignition grub.cfg info", "coreos.config.url=oem:///coreos-install.json", data)
-		}
-	}
-
-	if !ignition_found {
-		t.Fatalf("couldn't find coreos-install.json")
-	}
-
-	if !grub_found {
-		t.Fatalf("couldn't find grub.cfg")
-	}
+// ValidateIgnition checks the config was installed, compared as JSON so
+// formatting differences don't matter
+func (test Test) ValidateIgnition(t *testing.T, partitions []Partition, config string) {
+	test.ValidateManifest(t, partitions, Manifest{{
+		Label:    "OEM",
+		Path:     ignitionConfigPath,
+		Contents: config,
+		Compare:  util.JSONEqual,
+		Meta:     &PrivateFileMeta,
+	}})
+
+	expectedArgs := append([]string{"coreos.config.url=" + test.ignitionURL()}, test.KernelArgs...)
+	test.ValidateKernelArgs(t, MountPaths(partitions), expectedArgs)
 }
 
 func (test Test) RemoveAll(t *testing.T, path string) {
@@ -170,13 +337,11 @@ func (test Test) RemoveAll(t *testing.T, path string) {
 }
 
 func (test Test) WriteFile(t *testing.T, data string) string {
-	tmpFile, err := ioutil.TempFile("", "coreos-install-file")
-	if err != nil {
-		t.Fatalf("failed creating tmp file: %v", err)
-	}
+	tmpFile := test.TempFile(t, "coreos-install-file")
+	defer tmpFile.Close()
 
 	writer := bufio.NewWriter(tmpFile)
-	_, err = writer.WriteString(data)
+	_, err := writer.WriteString(data)
 	if err != nil {
 		t.Fatalf("writing to tmp file failed: %v", err)
 	}
@@ -185,49 +350,48 @@ func (test Test) WriteFile(t *testing.T, data string) string {
 	return tmpFile.Name()
 }
 
-func (test Test) ValidateCloudinit(t *testing.T, mountPaths []string, config string) {
-	cloudinit_found := false
-
-	for _, p := range mountPaths {
-		path := filepath.Join(p, "var", "lib", "coreos-install", "user_data")
-		if _, err := os.Stat(path); !os.IsNotExist(err) {
-			cloudinit_found = true
-			data, err := ioutil.ReadFile(path)
-			if err != nil {
-				t.Fatalf("couldn't read coreos-install/user_data: %v", err)
-			}
-
-			if string(data) != config {
-				t.Fatalf("coreos-install/user_data doesn't match: expected %s, received %s", config, data)
-			}
-		}
+// ValidateCloudinit checks the config was installed. Cloud-configs are
+// compared as YAML, scripts byte for byte.
+func (test Test) ValidateCloudinit(t *testing.T, partitions []Partition, config string) {
+	expected := ExpectedFile{
+		Label:    "ROOT",
+		Path:     cloudinitPath,
+		Contents: config,
+		Meta:     &PrivateFileMeta,
+	}
+	if strings.HasPrefix(config, "#cloud-config") {
+		expected.Compare = util.YAMLEqual
 	}
+	test.ValidateManifest(t, partitions, Manifest{expected})
 
-	if !cloudinit_found {
-		t.Fatalf("couldn't find coreos-install/user_data")
+	root, _ := FindPartition(partitions, "ROOT")
+	test.validateCloudinitSyntax(t, filepath.Join(root.MountPath, cloudinitPath))
+}
+
+// runs the installed user_data through coreos-cloudinit's validator, the
+// check is skipped if coreos-cloudinit isn't available on the host
+func (test Test) validateCloudinitSyntax(t *testing.T, path string) {
+	if _, err := exec.LookPath("coreos-cloudinit"); err != nil {
+		t.Logf("coreos-cloudinit not found, skipping cloud-config validation")
+		return
 	}
 
+	util.MustRun(t, "coreos-cloudinit", "-from-file="+path, "-validate")
 }
 
-// searches for /usr/lib/os-release on all mount paths given
-func (test Test) ReleaseExists(t *testing.T, mountPaths []string) {
-	releaseExists := false
-	for _, p := range mountPaths {
-		releasePath := filepath.Join(p, "lib", "os-release")
-		if _, err := os.Stat(releasePath); !os.IsNotExist(err) {
-			releaseExists = true
-			break
+func (test Test) PartitionLabel(t *testing.T, diskFile string, partNum int) string {
+	for _, p := range test.ListPartitions(t, diskFile) {
+		if p.Number == partNum {
+			return p.Label
 		}
 	}
-	if !releaseExists {
-		t.Fatalf("/usr/lib/os-release not found on any partitions")
-	}
+
+	t.Fatalf("couldn't find partition %d", partNum)
+	return ""
 }
 
 func (test Test) ValidatePartitionLabel(t *testing.T, diskFile, expectedLabel string, rootPartNum int) {
-	diskInfo := util.MustRun(t, "sgdisk", "-i", strconv.Itoa(rootPartNum), diskFile)
-
-	actualLabel := util.RegexpSearch(t, "partition name", "Partition name: '(?P<name>[\\d\\w-_]+)'", diskInfo)
+	actualLabel := test.PartitionLabel(t, diskFile, rootPartNum)
 
 	if expectedLabel != actualLabel {
 		t.Fatalf("label on partition %d did not match. expected %s, received %s", rootPartNum, expectedLabel, actualLabel)
@@ -235,21 +399,68 @@ func (test Test) ValidatePartitionLabel(t *testing.T, diskFile, expectedLabel st
 }
 
 func (test Test) ValidateDefaultRootPartition(t *testing.T, diskFile string) {
-	test.ValidatePartitionLabel(t, diskFile, "ROOT", 9)
+	test.ValidatePartition(t, diskFile, RootPartition)
 }
 
 func (test Test) ValidateDefaultUSRAPartition(t *testing.T, diskFile string) {
-	test.ValidatePartitionLabel(t, diskFile, "USR-A", 3)
+	test.ValidatePartition(t, diskFile, USRAPartition)
+}
+
+// DefaultChecks runs the checks every successful install has to pass, args
+// are the ones it was installed with
+func (test Test) DefaultChecks(t *testing.T, mountPaths []string, diskFile string, args []string) {
+	v := test.NewValidations(t)
+	v.Run("release", func(t *testing.T) {
+		test.ValidateRelease(t, mountPaths, ExpectedVersion(args), ExpectedBoard(args))
+	})
+	v.Run("root partition", func(t *testing.T) {
+		test.ValidateDefaultRootPartition(t, diskFile)
+	})
+	v.Run("usr-a partition", func(t *testing.T) {
+		test.ValidateDefaultUSRAPartition(t, diskFile)
+	})
+	v.Run("first boot state", func(t *testing.T) {
+		test.ValidateFirstBootState(t, mountPaths)
+	})
+	v.Run("config permissions", func(t *testing.T) {
+		test.ValidateConfigPermissions(t, mountPaths)
+	})
+	v.Finish()
+}
+
+// Registry is a set of tests to run
+type Registry struct {
+	mu    sync.Mutex
+	tests []Test
 }
 
-func (test Test) DefaultChecks(t *testing.T, mountPaths []string, diskFile string) {
-	test.ReleaseExists(t, mountPaths)
-	test.ValidateDefaultRootPartition(t, diskFile)
-	test.ValidateDefaultUSRAPartition(t, diskFile)
+func NewRegistry() *Registry {
+	return &Registry{}
 }
 
-var Tests []Test
+func (r *Registry) Register(t Test) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tests = append(r.tests, t)
+}
+
+// Tests returns the registered tests in the order they were registered
+func (r *Registry) Tests() []Test {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Test(nil), r.tests...)
+}
 
+// DefaultRegistry holds the tests the positive and negative packages
+// register at init
+var DefaultRegistry = NewRegistry()
+
+// Register adds t to DefaultRegistry
 func Register(t Test) {
-	Tests = append(Tests, t)
+	DefaultRegistry.Register(t)
+}
+
+// Tests returns DefaultRegistry's tests
+func Tests() []Test {
+	return DefaultRegistry.Tests()
 }
@@ -0,0 +1,96 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testIgnitionConfig = `{"ignition": {"version": "2.1.0"}}`
+
+// writeTestIgnitionInstall lays out the ESP and OEM partition of an install
+// with -i, with oemGrub as the OEM grub.cfg
+func writeTestIgnitionInstall(t *testing.T, oemGrub string) []Partition {
+	if os.Geteuid() != 0 {
+		t.Skip("root is required to own the config like the installer does")
+	}
+
+	mountPaths := writeTestGrubConfigs(t, testMainGrubConfig)
+	oem := mountPaths[1]
+	if err := ioutil.WriteFile(filepath.Join(oem, grubOEMConfigPath), []byte(oemGrub), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(oem, ignitionConfigPath), []byte(testIgnitionConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return []Partition{
+		{Label: espLabel, MountPath: mountPaths[0]},
+		{Label: "OEM", MountPath: oem},
+	}
+}
+
+func TestValidateIgnitionURL(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		url  string
+	}{
+		{"default", DefaultIgnitionURL},
+		{"other OEM path", "oem:///config/ignition.json"},
+		{"remote", "https://configs.example.com/node.ign"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			oemGrub := `set linux_append="$linux_append coreos.config.url=` + c.url + `"` + "\n"
+			partitions := writeTestIgnitionInstall(t, oemGrub)
+
+			test := Test{}
+			if c.url != DefaultIgnitionURL {
+				test = test.WithIgnitionURL(c.url)
+			}
+			test.ValidateIgnition(t, partitions, testIgnitionConfig)
+
+			// the URL is the only one on the kernel line, so a test
+			// expecting another one wouldn't pass
+			kernels, _ := test.InstalledKernelArgs(t, MountPaths(partitions))
+			for _, kernel := range kernels {
+				if urls := KernelArgValues(kernel.Args, "coreos.config.url"); len(urls) != 1 || urls[0] != c.url {
+					t.Errorf("%s %s has coreos.config.url %q, expected %s", kernel.Command, kernel.Path, urls, c.url)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateIgnitionKernelArgs(t *testing.T) {
+	// an OEM image's grub.cfg with the installer's line appended
+	partitions := writeTestIgnitionInstall(t, `set oem_id="gce"
+set linux_append="coreos.oem.id=gce"
+set linux_append="$linux_append coreos.config.url=oem:///coreos-install.json"
+`)
+
+	Test{}.WithKernelArgs("coreos.oem.id=gce", "console=ttyS0,115200n8").ValidateIgnition(t, partitions, testIgnitionConfig)
+}
+
+func TestWithKernelArgs(t *testing.T) {
+	base := Test{}.WithKernelArgs("a=1")
+	first, second := base.WithKernelArgs("b=2"), base.WithKernelArgs("c=3")
+
+	// copies don't share the slice they append to
+	if len(base.KernelArgs) != 1 || len(first.KernelArgs) != 2 || first.KernelArgs[1] != "b=2" || second.KernelArgs[1] != "c=3" {
+		t.Errorf("args are %q, %q and %q", base.KernelArgs, first.KernelArgs, second.KernelArgs)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// ParseOSRelease reads the KEY=VALUE pairs of an os-release file
+func ParseOSRelease(data []byte) map[string]string {
+	release := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		release[parts[0]] = strings.Trim(parts[1], `"'`)
+	}
+	return release
+}
+
+// DefaultBoard mirrors the board coreos-install picks when -B isn't given
+func DefaultBoard() string {
+	if data, err := ioutil.ReadFile("/usr/share/coreos/release"); err == nil {
+		if board := ParseOSRelease(data)["COREOS_RELEASE_BOARD"]; board != "" {
+			return board
+		}
+	}
+
+	if runtime.GOARCH == "arm64" {
+		return "arm64-usr"
+	}
+	return "amd64-usr"
+}
+
+// ValidateRelease parses /usr/lib/os-release on the installed USR partition
+// and asserts it matches the image that was supposed to be installed. Empty
+// expectations aren't checked.
+func (test Test) ValidateRelease(t *testing.T, mountPaths []string, version, board string) {
+	release := installedRelease(t, mountPaths)
+
+	if version != "" && release["VERSION_ID"] != version {
+		t.Fatalf("installed VERSION_ID doesn't match: expected %s, received %s", version, release["VERSION_ID"])
+	}
+
+	if board != "" && release["COREOS_BOARD"] != board {
+		t.Fatalf("installed COREOS_BOARD doesn't match: expected %s, received %s", board, release["COREOS_BOARD"])
+	}
+}
+
+// ExpectedVersion is the VERSION_ID an install with these args should have,
+// "" if they don't pin one with -V
+func ExpectedVersion(args []string) string {
+	version := flagArg(args, "-V")
+	if version == "current" {
+		return ""
+	}
+	return version
+}
+
+// ExpectedBoard is the COREOS_BOARD an install with these args should have
+func ExpectedBoard(args []string) string {
+	if board := flagArg(args, "-B"); board != "" {
+		return board
+	}
+	return DefaultBoard()
+}
+
+// installedRelease parses /usr/lib/os-release from whichever partition has
+// it
+func installedRelease(t *testing.T, mountPaths []string) map[string]string {
+	for _, p := range mountPaths {
+		releasePath := filepath.Join(p, "lib", "os-release")
+		if fileExists(releasePath) {
+			data, err := ioutil.ReadFile(releasePath)
+			if err != nil {
+				t.Fatalf("couldn't read /usr/lib/os-release: %v", err)
+			}
+			return ParseOSRelease(data)
+		}
+	}
+
+	t.Fatalf("/usr/lib/os-release not found on any partitions")
+	return nil
+}
@@ -0,0 +1,42 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"testing"
+)
+
+func TestExpectedRelease(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		opts    InstallOptions
+		version string
+		board   string
+	}{
+		{"defaults", InstallOptions{Device: "/dev/vda"}, "", DefaultBoard()},
+		{"current", InstallOptions{Version: "current"}, "", DefaultBoard()},
+		{"pinned", InstallOptions{Version: "1632.3.0", Board: "arm64-usr"}, "1632.3.0", "arm64-usr"},
+		// getopts keeps the last one
+		{"repeated", InstallOptions{Version: "1632.3.0", Extra: []string{"-V", "1688.5.3"}}, "1688.5.3", DefaultBoard()},
+	} {
+		args := c.opts.Args()
+		if version := ExpectedVersion(args); version != c.version {
+			t.Errorf("%s: expected version %q, received %q", c.name, c.version, version)
+		}
+		if board := ExpectedBoard(args); board != c.board {
+			t.Errorf("%s: expected board %q, received %q", c.name, c.board, board)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+const (
+	sectorSize = 512
+	// the backup GPT header and entries at the end of the disk
+	backupGPTSectors = 33
+	// partitions are 1MiB aligned so a partition filling the disk can end
+	// up to this many sectors short of the backup GPT
+	alignmentSectors = 2048
+)
+
+// ValidateRootResize checks whether the ROOT partition was extended to fill
+// the target disk, and that its filesystem is consistent with the partition
+// size. The image leaves ROOT small and it is grown on first boot, so most
+// installs should expect extended to be false.
+func (test Test) ValidateRootResize(t *testing.T, diskFile string, partitions []Partition, extended bool) {
+	root, ok := FindPartition(partitions, RootPartition.Label)
+	if !ok {
+		t.Fatalf("couldn't find %s partition", RootPartition.Label)
+	}
+
+	info, err := os.Stat(diskFile)
+	if err != nil {
+		t.Fatalf("couldn't stat disk file: %v", err)
+	}
+
+	lastUsable := uint64(info.Size())/sectorSize - backupGPTSectors - 1
+	fillsDisk := root.LastSector+alignmentSectors >= lastUsable
+
+	if extended && !fillsDisk {
+		t.Fatalf("%s wasn't extended to fill the disk: ends at sector %d, last usable sector is %d", root.Label, root.LastSector, lastUsable)
+	} else if !extended && fillsDisk {
+		t.Fatalf("%s was unexpectedly extended to fill the disk: ends at sector %d", root.Label, root.LastSector)
+	}
+
+	partitionBytes := (root.LastSector - root.FirstSector + 1) * sectorSize
+	fsBytes, ok := test.filesystemSize(t, root.Device)
+	if !ok {
+		return
+	}
+
+	if fsBytes > partitionBytes {
+		t.Fatalf("%s filesystem is larger than its partition: filesystem is %d bytes, partition is %d bytes", root.Label, fsBytes, partitionBytes)
+	}
+
+	// a resized filesystem should fill the partition, allowing for the
+	// remainder that doesn't fit in a whole filesystem block
+	if extended && partitionBytes-fsBytes >= 64*1024 {
+		t.Fatalf("%s filesystem wasn't grown with its partition: filesystem is %d bytes, partition is %d bytes", root.Label, fsBytes, partitionBytes)
+	}
+}
+
+// reads the size of an ext filesystem from its superblock, other filesystems
+// aren't checked
+func (test Test) filesystemSize(t *testing.T, device string) (uint64, bool) {
+	fsType := strings.TrimSpace(string(util.MustRun(t, "blkid", "-o", "value", "-s", "TYPE", device)))
+	if !strings.HasPrefix(fsType, "ext") {
+		t.Logf("not checking size of %s filesystem on %s", fsType, device)
+		return 0, false
+	}
+
+	superblock := util.MustRun(t, "dumpe2fs", "-h", device)
+	blockCount, err := strconv.ParseUint(util.RegexpSearch(t, "block count", "Block count:\\s+(?P<count>\\d+)", superblock), 10, 64)
+	if err != nil {
+		t.Fatalf("couldn't parse block count of %s: %v", device, err)
+	}
+
+	blockSize, err := strconv.ParseUint(util.RegexpSearch(t, "block size", "Block size:\\s+(?P<size>\\d+)", superblock), 10, 64)
+	if err != nil {
+		t.Fatalf("couldn't parse block size of %s: %v", device, err)
+	}
+
+	return blockCount * blockSize, true
+}
@@ -0,0 +1,246 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// Scenario composes an install test from the suite's helpers:
+//
+//	test.NewScenario().
+//		WithServer(Hermetic{}).
+//		WithIgnition(config).
+//		ExpectIgnition(config).
+//		ExpectPartitions(RootPartition, OEMPartition).
+//		Run(t)
+//
+// Nothing happens until Run, which creates the disk, installs, mounts the
+// partitions and runs every expectation as a validation after the default
+// checks.
+type Scenario struct {
+	test Test
+
+	prepare []func(t *testing.T, disk ScenarioDisk)
+
+	opts        InstallOptions
+	inv         Invocation
+	ignition    *string
+	cloudConfig *string
+
+	hermetic *Hermetic
+
+	err     *InstallerError
+	expects []scenarioExpect
+}
+
+// ScenarioDisk is the scenario's device and, once installed, its result and
+// mounted partitions
+type ScenarioDisk struct {
+	DiskFile   string
+	LoopDevice string
+
+	Result     InstallResult
+	Partitions []Partition
+}
+
+type scenarioExpect struct {
+	name  string
+	check func(t *testing.T, disk ScenarioDisk)
+}
+
+func (test Test) NewScenario() *Scenario {
+	return &Scenario{test: test}
+}
+
+// WithDisk runs prepare on the fresh disk before the install, e.g. to fill
+// it or put an older install on it
+func (s *Scenario) WithDisk(prepare func(t *testing.T, disk ScenarioDisk)) *Scenario {
+	s.prepare = append(s.prepare, prepare)
+	return s
+}
+
+// WithServer installs from the fixture mirror with
+// RunCoreOSInstallHermeticWith
+func (s *Scenario) WithServer(hooks Hermetic) *Scenario {
+	s.hermetic = &hooks
+	return s
+}
+
+// WithInvocation changes how the installer is started, it can't be combined
+// with WithServer
+func (s *Scenario) WithInvocation(inv Invocation) *Scenario {
+	s.inv = inv
+	return s
+}
+
+// Install sets the installer's options, Device is filled in by Run
+func (s *Scenario) Install(opts InstallOptions) *Scenario {
+	s.opts = opts
+	return s
+}
+
+// WithIgnition passes config to -i
+func (s *Scenario) WithIgnition(config string) *Scenario {
+	s.ignition = &config
+	return s
+}
+
+// WithCloudConfig passes config to -c
+func (s *Scenario) WithCloudConfig(config string) *Scenario {
+	s.cloudConfig = &config
+	return s
+}
+
+// Expect adds a named validation of the install
+func (s *Scenario) Expect(name string, check func(t *testing.T, disk ScenarioDisk)) *Scenario {
+	s.expects = append(s.expects, scenarioExpect{name, check})
+	return s
+}
+
+func (s *Scenario) ExpectFiles(files ...ExpectedFile) *Scenario {
+	return s.Expect("files", func(t *testing.T, disk ScenarioDisk) {
+		s.test.ValidateManifest(t, disk.Partitions, files)
+	})
+}
+
+func (s *Scenario) ExpectPartitions(specs ...PartitionSpec) *Scenario {
+	return s.Expect("partitions", func(t *testing.T, disk ScenarioDisk) {
+		for _, spec := range specs {
+			s.test.ValidatePartition(t, disk.DiskFile, spec)
+		}
+	})
+}
+
+func (s *Scenario) ExpectIgnition(config string) *Scenario {
+	return s.Expect("ignition", func(t *testing.T, disk ScenarioDisk) {
+		s.test.ValidateIgnition(t, disk.Partitions, config)
+	})
+}
+
+func (s *Scenario) ExpectNoIgnition() *Scenario {
+	return s.Expect("no ignition", func(t *testing.T, disk ScenarioDisk) {
+		s.test.AssertNoIgnition(t, disk.Partitions)
+	})
+}
+
+func (s *Scenario) ExpectCloudinit(config string) *Scenario {
+	return s.Expect("cloudinit", func(t *testing.T, disk ScenarioDisk) {
+		s.test.ValidateCloudinit(t, disk.Partitions, config)
+	})
+}
+
+func (s *Scenario) ExpectNoCloudinit() *Scenario {
+	return s.Expect("no cloudinit", func(t *testing.T, disk ScenarioDisk) {
+		s.test.AssertNoCloudinit(t, disk.Partitions)
+	})
+}
+
+func (s *Scenario) ExpectGrubMenu(menu GrubMenu) *Scenario {
+	return s.Expect("grub menu", func(t *testing.T, disk ScenarioDisk) {
+		s.test.ValidateGrubMenu(t, MountPaths(disk.Partitions), menu)
+	})
+}
+
+// ExpectError makes the scenario a failing install, validated with
+// ValidateFailedInstall instead of any expectations
+func (s *Scenario) ExpectError(err InstallerError) *Scenario {
+	s.err = &err
+	return s
+}
+
+// Run creates the disk, installs and validates the scenario. With
+// -compare-installer it does so with both installers, then reports how
+// they differ.
+func (s *Scenario) Run(t *testing.T) {
+	if *compareInstallerFlag == "" {
+		s.run(t, s.test, nil)
+		return
+	}
+
+	installer, err := filepath.Abs(*compareInstallerFlag)
+	if err != nil {
+		t.Fatalf("couldn't resolve -compare-installer %s: %v", *compareInstallerFlag, err)
+	}
+
+	var baseline, candidate *InstallSnapshot
+	t.Run("baseline", func(t *testing.T) {
+		var snapshot InstallSnapshot
+		s.run(t, s.test.WithInstaller(installer), &snapshot)
+		baseline = &snapshot
+	})
+	t.Run("candidate", func(t *testing.T) {
+		var snapshot InstallSnapshot
+		s.run(t, s.test, &snapshot)
+		candidate = &snapshot
+	})
+	s.test.reportInstallDiff(t, baseline, candidate)
+}
+
+// run is Run with one installer, the install is recorded in snapshot if
+// it's set before anything is validated
+func (s *Scenario) run(t *testing.T, test Test, snapshot *InstallSnapshot) {
+	var disk ScenarioDisk
+	disk.DiskFile, disk.LoopDevice = test.CreateDevice(t)
+	defer test.CleanupDisk(t, disk.DiskFile, disk.LoopDevice)
+
+	for _, prepare := range s.prepare {
+		prepare(t, disk)
+	}
+
+	opts := s.opts
+	opts.Device = disk.LoopDevice
+	if s.ignition != nil {
+		opts.IgnitionPath = test.WriteFile(t, *s.ignition)
+	}
+	if s.cloudConfig != nil {
+		opts.CloudConfigPath = test.WriteFile(t, *s.cloudConfig)
+	}
+
+	if s.err != nil {
+		before := test.SnapshotDisk(t, disk.DiskFile)
+		disk.Result = test.TryCoreOSInstallWith(t, s.inv, opts.Args()...)
+		if snapshot != nil {
+			*snapshot = test.SnapshotInstall(t, disk.Result, disk.DiskFile, nil)
+		}
+		test.ValidateFailedInstall(t, disk.Result, *s.err, disk.DiskFile, disk.LoopDevice, before)
+		return
+	}
+
+	if s.hermetic != nil {
+		disk.Result = test.RunCoreOSInstallHermeticWith(t, opts, *s.hermetic)
+	} else {
+		disk.Result = test.RunCoreOSInstallWith(t, s.inv, opts.Args()...)
+	}
+
+	disk.Partitions = test.MountPartitions(t, disk.DiskFile, disk.LoopDevice)
+	defer test.UnmountPartitions(t, disk.LoopDevice, disk.Partitions)
+	if snapshot != nil {
+		*snapshot = test.SnapshotInstall(t, disk.Result, disk.DiskFile, disk.Partitions)
+	}
+
+	v := test.NewValidations(t)
+	v.Run("default", func(t *testing.T) {
+		test.DefaultChecks(t, MountPaths(disk.Partitions), disk.DiskFile, disk.Result.Args)
+	})
+	for _, e := range s.expects {
+		e := e
+		v.Run(e.name, func(t *testing.T) {
+			e.check(t, disk)
+		})
+	}
+	v.Finish()
+}
@@ -0,0 +1,90 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+var (
+	secretPatternFlag = flag.String("secret-pattern", config.SecretPattern, "regexp of secrets to redact from logs and artifacts, on top of credentials in URLs [$COREOS_TEST_SECRET_PATTERN]")
+	leakedSecretsFlag = flag.String("leaked-secrets", config.LeakedSecrets, "what to do with secrets found in a finished test's artifacts: redact them, or fail the test too [$COREOS_TEST_LEAKED_SECRETS]")
+
+	// set by Run once the flags are parsed
+	secretPatternErr error
+)
+
+func addSecretPattern() {
+	if *leakedSecretsFlag != "redact" && *leakedSecretsFlag != "fail" {
+		secretPatternErr = fmt.Errorf("-leaked-secrets must be redact or fail, not %q", *leakedSecretsFlag)
+		return
+	}
+	if *secretPatternFlag == "" {
+		return
+	}
+	pattern, err := regexp.Compile(*secretPatternFlag)
+	if err != nil {
+		secretPatternErr = fmt.Errorf("invalid -secret-pattern: %v", err)
+		return
+	}
+	util.AddRedaction(util.Redaction{Pattern: pattern, Replace: "REDACTED"})
+}
+
+// redactArtifacts masks secrets in everything the test saved once it
+// finished, so collecting artifacts doesn't leak the credentials its
+// configs carry
+func redactArtifacts(t *testing.T) {
+	if *artifactDirFlag == "" {
+		return
+	}
+
+	err := filepath.Walk(artifactDir(t), func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		masked := util.MaskSecrets(data)
+		if masked == 0 {
+			return nil
+		}
+		if err := ioutil.WriteFile(path, data, info.Mode()); err != nil {
+			return err
+		}
+
+		if *leakedSecretsFlag == "fail" {
+			t.Errorf("%s contained %d secrets, they were redacted", path, masked)
+		} else {
+			t.Logf("redacted %d secrets from %s", masked, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("couldn't scan artifacts for secrets: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+const espLabel = "EFI-SYSTEM"
+
+// SecureBootFiles returns the shim and grub binaries a Secure Boot capable
+// install of the board should have on the ESP
+func SecureBootFiles(board string) []string {
+	suffix := "x64"
+	if board == "arm64-usr" {
+		suffix = "aa64"
+	}
+
+	return []string{
+		filepath.Join("EFI", "boot", "boot"+suffix+".efi"),
+		filepath.Join("EFI", "boot", "grub"+suffix+".efi"),
+	}
+}
+
+// ValidateSecureBootFiles checks the shim and grub EFI binaries are present
+// on the ESP and, when sbverify is available, that they are signed. If
+// certFile is set the signatures must verify against it.
+func (test Test) ValidateSecureBootFiles(t *testing.T, partitions []Partition, board, certFile string) {
+	esp, ok := FindPartition(partitions, espLabel)
+	if !ok || esp.MountPath == "" {
+		t.Fatalf("couldn't find a mounted %s partition", espLabel)
+	}
+
+	_, err := exec.LookPath("sbverify")
+	haveSbverify := err == nil
+	if !haveSbverify {
+		t.Logf("sbverify not found, skipping EFI signature verification")
+	}
+
+	for _, file := range SecureBootFiles(board) {
+		path := filepath.Join(esp.MountPath, file)
+		test.validatePEImage(t, path)
+
+		if !haveSbverify {
+			continue
+		}
+
+		if certFile != "" {
+			util.MustRun(t, "sbverify", "--cert", certFile, path)
+			continue
+		}
+
+		// sbverify --list exits nonzero for unsigned images, that's
+		// reported below along with its output
+		result, err := util.Exec(context.Background(), "sbverify", "--list", path)
+		if err != nil {
+			t.Fatalf("couldn't run sbverify: %v", err)
+		}
+		if !util.RegexpContains(t, "signature", "(?m)^signature \\d+", result.Stdout) {
+			t.Fatalf("%s on %s isn't signed: %s%s", file, espLabel, result.Stdout, result.Stderr)
+		}
+	}
+}
+
+// EFI binaries are PE images, which start with the DOS "MZ" magic
+func (test Test) validatePEImage(t *testing.T, path string) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("couldn't find %s", path)
+	} else if err != nil {
+		t.Fatalf("couldn't open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	magic := make([]byte, 2)
+	if _, err := io.ReadFull(f, magic); err != nil {
+		t.Fatalf("couldn't read %s: %v", path, err)
+	}
+
+	if !bytes.Equal(magic, []byte("MZ")) {
+		t.Fatalf("%s isn't an EFI binary", path)
+	}
+}
+
+// ValidateSecureBootDisk mounts the installed disk and checks its ESP with
+// ValidateSecureBootFiles, before it's booted with Secure Boot firmware
+// that would refuse unsigned binaries with little to go on
+func (test Test) ValidateSecureBootDisk(t *testing.T, diskFile, loopDevice string) {
+	partitions := test.MountPartitions(t, diskFile, loopDevice)
+	defer test.UnmountPartitions(t, loopDevice, partitions)
+
+	test.ValidateSecureBootFiles(t, partitions, DefaultBoard(), "")
+}
@@ -0,0 +1,47 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateSecureBootFiles(t *testing.T) {
+	esp := t.TempDir()
+	for _, board := range []string{"amd64-usr", "arm64-usr"} {
+		for _, file := range SecureBootFiles(board) {
+			path := filepath.Join(esp, file)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			// only the PE magic, sbverify would find no signature
+			if err := ioutil.WriteFile(path, []byte("MZ\x90\x00"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// without sbverify only their presence and format are checked
+	t.Setenv("PATH", "")
+	partitions := []Partition{{Label: espLabel, MountPath: esp}}
+	for _, board := range []string{"amd64-usr", "arm64-usr"} {
+		t.Run(board, func(t *testing.T) {
+			Test{}.ValidateSecureBootFiles(t, partitions, board, "")
+		})
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// ToolStub replaces a tool in a shadowed PATH with a script that fails
+type ToolStub struct {
+	Name     string
+	ExitCode int
+	Stderr   string
+}
+
+// ShadowPath builds a directory with links to everything in the test's PATH
+// except the removed tools, and with the stubs in place of the real tools.
+// The directory is used as the installer's whole PATH and is removed when
+// the test is done.
+func (test Test) ShadowPath(t *testing.T, removed []string, stubs ...ToolStub) string {
+	dir := test.TempDir(t, "coreos-install-path")
+
+	skip := map[string]bool{}
+	for _, name := range removed {
+		skip[name] = true
+	}
+
+	for _, stub := range stubs {
+		script := fmt.Sprintf("#!/bin/sh\necho %s >&2\nexit %d\n", strconv.Quote(stub.Stderr), stub.ExitCode)
+		if err := ioutil.WriteFile(filepath.Join(dir, stub.Name), []byte(script), 0755); err != nil {
+			t.Fatalf("couldn't write stub %s: %v", stub.Name, err)
+		}
+		skip[stub.Name] = true
+	}
+
+	for _, p := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := ioutil.ReadDir(p)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			name := entry.Name()
+			link := filepath.Join(dir, name)
+			if skip[name] || fileExists(link) {
+				// earlier PATH entries win, like they do for lookups
+				continue
+			}
+
+			if err := os.Symlink(filepath.Join(p, name), link); err != nil {
+				t.Fatalf("couldn't link %s: %v", name, err)
+			}
+		}
+	}
+
+	return dir
+}
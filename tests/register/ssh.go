@@ -0,0 +1,137 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreos/init/tests/util"
+)
+
+// SSHKey is a passphraseless key pair for logging in to booted installs
+type SSHKey struct {
+	// path to the private key
+	Path   string
+	Public string
+}
+
+// GenerateSSHKey creates a key to put in a BootCheck, it skips the test if
+// ssh isn't installed so call it before installing
+func (test Test) GenerateSSHKey(t *testing.T) SSHKey {
+	util.RequireTools(t, "ssh", "ssh-keygen")
+
+	path := filepath.Join(test.TempDir(t, "coreos-install-ssh"), "id_ed25519")
+	util.MustRun(t, "ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-C", "coreos-install-test", "-f", path)
+
+	public, err := ioutil.ReadFile(path + ".pub")
+	if err != nil {
+		t.Fatalf("couldn't read public key: %v", err)
+	}
+	return SSHKey{Path: path, Public: strings.TrimSpace(string(public))}
+}
+
+// freePort finds a localhost port for qemu to forward, it can be taken
+// again before qemu binds it but that's unlikely
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("couldn't find a free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// SSHClient runs commands on a booted VM as core
+type SSHClient struct {
+	key  SSHKey
+	port int
+}
+
+func (c SSHClient) args(command string) []string {
+	return []string{
+		"-i", c.key.Path,
+		"-p", fmt.Sprint(c.port),
+		"-o", "BatchMode=yes",
+		"-o", "StrictHostKeyChecking=no",
+		"-o", "UserKnownHostsFile=/dev/null",
+		"-o", "ConnectTimeout=10",
+		"-o", "LogLevel=ERROR",
+		"core@127.0.0.1",
+		command,
+	}
+}
+
+const sshRetryInterval = 5 * time.Second
+
+// WaitSSH waits for the VM's sshd to accept key, failing the test if it
+// doesn't within the boot timeout
+func (test Test) WaitSSH(t *testing.T, vm *VM, key SSHKey) SSHClient {
+	c := SSHClient{key: key, port: vm.SSHPort}
+
+	ctx, cancel := context.WithTimeout(context.Background(), vm.timeout)
+	defer cancel()
+
+	for {
+		_, err := util.RunE(ctx, "ssh", c.args("true")...)
+		if err == nil {
+			return c
+		}
+
+		select {
+		case <-ctx.Done():
+			vm.fatal(t, fmt.Errorf("sshd never accepted the key: %v", err))
+		case <-vm.done:
+			vm.fatal(t, fmt.Errorf("qemu exited waiting for sshd: %v", err))
+		case <-time.After(sshRetryInterval):
+		}
+	}
+}
+
+// RunSSH runs command on the VM through a shell and returns its stdout,
+// failing the test if it exits nonzero
+func (test Test) RunSSH(t *testing.T, c SSHClient, command string) []byte {
+	return util.MustRun(t, "ssh", c.args(command)...)
+}
+
+// ValidateSystemRunning asserts systemd finished booting without failed
+// units
+func (test Test) ValidateSystemRunning(t *testing.T, c SSHClient) {
+	result, err := util.Exec(context.Background(), "ssh", c.args("systemctl is-system-running --wait")...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := string(bytes.TrimSpace(result.Stdout))
+	if state != "running" {
+		failed, _ := util.Exec(context.Background(), "ssh", c.args("systemctl --failed --no-legend")...)
+		t.Fatalf("system is %s instead of running, failed units:\n%s", state, failed.Stdout)
+	}
+}
+
+// ValidateRemoteFile asserts a file on the VM has the expected contents
+func (test Test) ValidateRemoteFile(t *testing.T, c SSHClient, path, expected string) {
+	actual := test.RunSSH(t, c, "sudo cat "+util.ShellQuote(path))
+	if string(actual) != expected {
+		t.Fatalf("%s on the VM doesn't match: expected %q, received %q", path, expected, actual)
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+var stateFileFlag = flag.String("state-file", config.StateFile, "save the suite's progress here and resume from it, tests that passed with the same installer are skipped and scratch devices an interrupted run left behind are released [$COREOS_TEST_STATE_FILE]")
+
+// SuiteState is the suite's progress, saved after every change so a run
+// killed by a CI timeout can be resumed instead of started over
+type SuiteState struct {
+	// digest of the installer each test passed with, tests are rerun
+	// against a different one
+	Passed map[string]string `json:"passed,omitempty"`
+	// fixtures computed once per suite, see CachedFixture
+	Fixtures map[string]json.RawMessage `json:"fixtures,omitempty"`
+	// scratch disks attached and not cleaned up yet
+	Devices []ScratchDevice `json:"devices,omitempty"`
+
+	mu sync.Mutex
+	// kept in memory only if empty
+	path string
+}
+
+// ScratchDevice is a loop device CreateDevice attached to a disk file
+type ScratchDevice struct {
+	DiskFile   string `json:"disk_file"`
+	LoopDevice string `json:"loop_device"`
+}
+
+var (
+	// set by Run once the flags are parsed
+	suiteState = &SuiteState{}
+	stateErr   error
+)
+
+// LoadSuiteState reads the state saved at path, a missing file is a fresh
+// start
+func LoadSuiteState(path string) (*SuiteState, error) {
+	s := &SuiteState{path: path}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("couldn't read suite state: %v", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("couldn't parse suite state %s: %v", path, err)
+	}
+	return s, nil
+}
+
+// save writes the state with s.mu held, replacing the file atomically so
+// an interrupt can't leave half of it behind
+func (s *SuiteState) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".")
+	if err != nil {
+		return fmt.Errorf("couldn't save suite state: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(append(data, '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't save suite state: %v", err)
+	}
+	return nil
+}
+
+// HasPassed reports whether the test passed with the installer digest in
+// an earlier run
+func (s *SuiteState) HasPassed(name, installer string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	digest, ok := s.Passed[name]
+	return ok && digest == installer
+}
+
+// MarkPassed records the test passed with the installer digest
+func (s *SuiteState) MarkPassed(name, installer string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Passed == nil {
+		s.Passed = map[string]string{}
+	}
+	s.Passed[name] = installer
+	return s.save()
+}
+
+// Fixture decodes the fixture saved under key into v, and reports whether
+// there was one
+func (s *SuiteState) Fixture(key string, v interface{}) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.Fixtures[key]
+	return ok && json.Unmarshal(data, v) == nil
+}
+
+// SetFixture saves v under key
+func (s *SuiteState) SetFixture(key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Fixtures == nil {
+		s.Fixtures = map[string]json.RawMessage{}
+	}
+	s.Fixtures[key] = data
+	return s.save()
+}
+
+// AddDevice records a scratch device before it's used, so it's released
+// on resume if the run is killed before CleanupDisk
+func (s *SuiteState) AddDevice(d ScratchDevice) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Devices = append(s.Devices, d)
+	return s.save()
+}
+
+// RemoveDevice forgets a scratch device once it was cleaned up
+func (s *SuiteState) RemoveDevice(loopDevice string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Devices = util.Filter(s.Devices, func(d ScratchDevice) bool {
+		return d.LoopDevice != loopDevice
+	})
+	return s.save()
+}
+
+// ReleaseDevices detaches the loop devices an interrupted run left behind
+// and removes their disk files. Devices since reused for another file are
+// left alone.
+func (s *SuiteState) ReleaseDevices() (problems []error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var kept []ScratchDevice
+	for _, d := range s.Devices {
+		if loopBackingFile(d.LoopDevice) == d.DiskFile {
+			if err := util.DetachLoop(d.LoopDevice); err != nil {
+				problems = append(problems, err)
+				kept = append(kept, d)
+				continue
+			}
+		}
+		if err := os.Remove(d.DiskFile); err != nil && !os.IsNotExist(err) {
+			problems = append(problems, err)
+		}
+	}
+	s.Devices = kept
+	if err := s.save(); err != nil {
+		problems = append(problems, err)
+	}
+	return
+}
+
+// loopBackingFile is the file attached to a loop device, "" if none is
+func loopBackingFile(device string) string {
+	data, err := ioutil.ReadFile(filepath.Join("/sys/block", filepath.Base(device), "loop", "backing_file"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// loadSuiteState is called by Run once the flags are parsed
+func loadSuiteState(t *testing.T) {
+	suiteState, stateErr = LoadSuiteState(*stateFileFlag)
+	if stateErr != nil {
+		suiteState = &SuiteState{}
+		return
+	}
+	for _, err := range suiteState.ReleaseDevices() {
+		t.Errorf("couldn't release a scratch device from an earlier run: %v", err)
+	}
+}
+
+// installerDigest identifies the installer under test in the suite state
+func (test Test) installerDigest(t *testing.T) string {
+	path := test.installer
+	if path == "" {
+		path = CoreosInstallPath(t)
+	}
+	sum, err := util.HashFile(path, util.SHA256)
+	if err != nil {
+		t.Fatalf("couldn't hash the installer: %v", err)
+	}
+	return sum
+}
+
+// skipPassed skips a test that already passed with this installer, and
+// returns a func for Run to defer that records it once it passes now
+func (test Test) skipPassed(t *testing.T) func() {
+	if *stateFileFlag == "" {
+		return func() {}
+	}
+
+	installer := test.installerDigest(t)
+	if suiteState.HasPassed(t.Name(), installer) {
+		t.Skipf("passed in an earlier run, see %s", *stateFileFlag)
+	}
+	return func() {
+		if t.Failed() || t.Skipped() {
+			return
+		}
+		if err := suiteState.MarkPassed(t.Name(), installer); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// CachedFixture returns what compute returned under key in this or an
+// earlier run, for fixtures that are expensive to build. Keys should
+// include whatever the fixture depends on.
+func CachedFixture[T any](t *testing.T, key string, compute func() T) T {
+	var v T
+	if suiteState.Fixture(key, &v) {
+		t.Logf("using cached fixture %q", key)
+		return v
+	}
+
+	v = compute()
+	if err := suiteState.SetFixture(key, v); err != nil {
+		t.Error(err)
+	}
+	return v
+}
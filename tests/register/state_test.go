@@ -0,0 +1,95 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSuiteState(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	diskFile := filepath.Join(dir, "disk")
+	if err := ioutil.WriteFile(diskFile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadSuiteState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range []error{
+		s.MarkPassed("TestCoreosInstall/a", "digest"),
+		s.SetFixture("layout", []Partition{{Number: 9, Label: "ROOT"}}),
+		// never attached, like a run killed right after AddDevice
+		s.AddDevice(ScratchDevice{diskFile, "/dev/loop-missing"}),
+		s.AddDevice(ScratchDevice{filepath.Join(dir, "cleaned"), "/dev/loop-cleaned"}),
+		s.RemoveDevice("/dev/loop-cleaned"),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// as a resumed run sees it
+	s, err = LoadSuiteState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.HasPassed("TestCoreosInstall/a", "digest") {
+		t.Errorf("a isn't recorded as passed")
+	}
+	if s.HasPassed("TestCoreosInstall/a", "other") {
+		t.Errorf("a is recorded as passed with another installer")
+	}
+
+	var layout []Partition
+	if !s.Fixture("layout", &layout) || !reflect.DeepEqual(layout, []Partition{{Number: 9, Label: "ROOT"}}) {
+		t.Errorf("cached layout is %+v", layout)
+	}
+	if s.Fixture("missing", &layout) {
+		t.Errorf("found a fixture that wasn't saved")
+	}
+
+	if expected := []ScratchDevice{{diskFile, "/dev/loop-missing"}}; !reflect.DeepEqual(s.Devices, expected) {
+		t.Fatalf("recorded devices %+v, expected %+v", s.Devices, expected)
+	}
+	if problems := s.ReleaseDevices(); len(problems) != 0 {
+		t.Fatal(problems)
+	}
+	if _, err := os.Stat(diskFile); !os.IsNotExist(err) {
+		t.Errorf("disk file wasn't removed: %v", err)
+	}
+	if s, _ := LoadSuiteState(path); len(s.Devices) != 0 {
+		t.Errorf("released devices are still recorded: %+v", s.Devices)
+	}
+}
+
+func TestSuiteStateWithoutFile(t *testing.T) {
+	s, err := LoadSuiteState("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.MarkPassed("a", "digest"); err != nil {
+		t.Fatal(err)
+	}
+	if !s.HasPassed("a", "digest") {
+		t.Errorf("a isn't recorded in memory")
+	}
+}
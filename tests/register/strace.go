@@ -0,0 +1,114 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"flag"
+	"io/ioutil"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+var straceFlag = flag.String("strace", config.Strace, "trace every install with strace -f into the artifact dir for debugging, \"all\" or an strace -e expression like trace=file,desc [$COREOS_TEST_STRACE]")
+
+// Strace runs the installer under strace -f, following everything it spawns
+type Strace struct {
+	// strace -e expression, e.g. trace=connect, everything if empty
+	Filter string
+	// where strace writes its trace
+	Output string
+	// prefix each call with the time and show how long it took
+	Timestamps bool
+}
+
+// debugStrace is the trace requested with -strace, nil if tracing is off
+func debugStrace(t *testing.T) *Strace {
+	if *straceFlag == "" {
+		return nil
+	}
+
+	if _, err := exec.LookPath("strace"); err != nil {
+		t.Fatalf("-strace was given but strace isn't installed: %v", err)
+	}
+
+	output := ArtifactPath(t, "coreos-install.strace")
+	if output == "" {
+		t.Fatalf("-strace requires -artifact-dir to save the trace in")
+	}
+
+	s := &Strace{Output: output, Timestamps: true}
+	if *straceFlag != "all" {
+		s.Filter = *straceFlag
+	}
+	return s
+}
+
+func (s *Strace) command(name string, args []string) (string, []string) {
+	straceArgs := []string{"-f", "-qq", "-o", s.Output}
+	if s.Timestamps {
+		straceArgs = append(straceArgs, "-tt", "-T")
+	}
+	if s.Filter != "" {
+		straceArgs = append(straceArgs, "-e", s.Filter)
+	}
+	return "strace", append(append(straceArgs, "--", name), args...)
+}
+
+// Connection is an internet address a traced process tried to connect to
+type Connection struct {
+	Family string
+	Addr   string
+	Port   int
+}
+
+var (
+	connectPattern = regexp.MustCompile(`connect\(\d+, \{sa_family=(AF_INET6?)(.*)\}, \d+\)`)
+	inetPattern    = regexp.MustCompile(`sin_port=htons\((\d+)\), sin_addr=inet_addr\("([^"]+)"\)`)
+	inet6Pattern   = regexp.MustCompile(`sin6_port=htons\((\d+)\),.*inet_pton\(AF_INET6, "([^"]+)"`)
+)
+
+// TracedConnections parses every AF_INET and AF_INET6 connect() from an
+// strace log, local sockets and AF_UNSPEC disconnects are ignored
+func (test Test) TracedConnections(t *testing.T, path string) (connections []Connection) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("couldn't read strace output: %v", err)
+	}
+
+	for _, m := range connectPattern.FindAllSubmatch(data, -1) {
+		pattern := inetPattern
+		if string(m[1]) == "AF_INET6" {
+			pattern = inet6Pattern
+		}
+
+		addr := pattern.FindSubmatch(m[2])
+		if addr == nil {
+			t.Fatalf("couldn't parse connect() from strace: %s", m[0])
+		}
+
+		port, err := strconv.Atoi(string(addr[1]))
+		if err != nil {
+			t.Fatalf("couldn't parse port in %s: %v", m[0], err)
+		}
+		connections = append(connections, Connection{
+			Family: string(m[1]),
+			Addr:   string(addr[2]),
+			Port:   port,
+		})
+	}
+	return
+}
@@ -0,0 +1,95 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// installLog streams the installer's output into the test log line by line
+// as it is written, and into the test's artifact log if there is one, so
+// long or hung installs still show where they got to
+type installLog struct {
+	mu    sync.Mutex
+	t     *testing.T
+	start time.Time
+	file  *os.File
+}
+
+func newInstallLog(t *testing.T, start time.Time) *installLog {
+	l := &installLog{t: t, start: start}
+
+	if path := ArtifactPath(t, "coreos-install.log"); path != "" {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			t.Fatalf("couldn't create install log: %v", err)
+		}
+		l.file = f
+	}
+	return l
+}
+
+func (l *installLog) line(stream string, line []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.t.Logf("%s: %s", stream, line)
+	if l.file != nil {
+		fmt.Fprintf(l.file, "%8.3fs %s: %s\n", time.Since(l.start).Seconds(), stream, line)
+	}
+}
+
+func (l *installLog) Close() {
+	if l.file != nil {
+		l.file.Close()
+	}
+}
+
+// Writer returns a writer for one of the installer's output streams
+func (l *installLog) Writer(stream string) *lineWriter {
+	return &lineWriter{log: l, stream: stream}
+}
+
+type lineWriter struct {
+	log     *installLog
+	stream  string
+	partial []byte
+}
+
+func (w *lineWriter) Write(data []byte) (int, error) {
+	w.partial = append(w.partial, data...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.log.line(w.stream, w.partial[:i])
+		w.partial = w.partial[i+1:]
+	}
+	return len(data), nil
+}
+
+// Flush logs a final line that wasn't newline terminated
+func (w *lineWriter) Flush() {
+	if len(w.partial) != 0 {
+		w.log.line(w.stream, w.partial)
+		w.partial = nil
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// where an installer that ignores TMPDIR would leave its work dir
+var systemTempDirs = []string{"/tmp", "/var/tmp"}
+
+// TempSnapshot maps every path the installer could have left behind to its
+// size
+type TempSnapshot map[string]int64
+
+// LeakedFile is a path the installer created and didn't remove
+type LeakedFile struct {
+	Path string
+	Size int64
+}
+
+// snapshotTemp records everything under the installer's TMPDIR and any
+// coreos-install.* work dirs in the system temp dirs
+func snapshotTemp(tmpDir string) TempSnapshot {
+	snapshot := TempSnapshot{}
+	record := func(root string) {
+		filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			// files can disappear while it's walking
+			if err != nil || path == root {
+				return nil
+			}
+			snapshot[path] = info.Size()
+			return nil
+		})
+	}
+
+	if tmpDir != "" {
+		record(tmpDir)
+	}
+	for _, dir := range systemTempDirs {
+		workDirs, _ := filepath.Glob(filepath.Join(dir, "coreos-install.*"))
+		for _, workDir := range workDirs {
+			snapshot[workDir] = 0
+			record(workDir)
+		}
+	}
+	return snapshot
+}
+
+// Leaks lists the paths in after that weren't in the snapshot, with a leaked
+// dir's size totalling everything in it
+func (before TempSnapshot) Leaks(after TempSnapshot) []LeakedFile {
+	var created []string
+	for path := range after {
+		if _, ok := before[path]; !ok {
+			created = append(created, path)
+		}
+	}
+	sort.Strings(created)
+
+	// only the topmost of each leaked tree is reported
+	var leaks []LeakedFile
+	for _, path := range created {
+		if n := len(leaks); n != 0 && strings.HasPrefix(path, leaks[n-1].Path+"/") {
+			leaks[n-1].Size += after[path]
+			continue
+		}
+		leaks = append(leaks, LeakedFile{Path: path, Size: after[path]})
+	}
+	return leaks
+}
+
+// ValidateTempCleanup asserts the installer removed everything it created
+// in its TMPDIR, and any work dir it created elsewhere, whether the install
+// succeeded or failed
+func (test Test) ValidateTempCleanup(t *testing.T, result InstallResult) {
+	if len(result.TempLeaks) == 0 {
+		return
+	}
+
+	var total int64
+	paths := make([]string, len(result.TempLeaks))
+	for i, leak := range result.TempLeaks {
+		total += leak.Size
+		paths[i] = leak.Path
+	}
+	t.Fatalf("installer left %d bytes of temp files behind: %s", total, strings.Join(paths, ", "))
+}
@@ -0,0 +1,62 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+func TestTempLeaks(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		mock  MockInstaller
+		leaks []string
+	}{
+		{"clean", MockInstaller{}, nil},
+		{"clean failure", MockInstaller{ExitCode: 1}, nil},
+		{"work dir", MockInstaller{LeaveTempDir: true}, []string{"coreos-install."}},
+		{"files", MockInstaller{ExitCode: 1, Files: map[string]string{"image/part": "12345", "sig": "678"}}, []string{"image", "sig"}},
+	} {
+		// the mock and its TMPDIR are removed with t's temp dir
+		test := Test{temp: util.NewTempManagerIn(t.TempDir())}
+		mock := test.CreateMockInstaller(t, c.mock)
+		result := test.TryCoreOSInstallWith(t, mock.Apply(Invocation{}))
+
+		if len(result.TempLeaks) != len(c.leaks) {
+			t.Errorf("%s: leaked %+v, expected %q", c.name, result.TempLeaks, c.leaks)
+			continue
+		}
+		for i, leak := range result.TempLeaks {
+			if !strings.HasPrefix(filepath.Base(leak.Path), c.leaks[i]) {
+				t.Errorf("%s: leaked %s, expected %s", c.name, leak.Path, c.leaks[i])
+			}
+		}
+	}
+}
+
+func TestTempSnapshotLeaks(t *testing.T) {
+	before := TempSnapshot{"/t/kept": 1}
+	after := TempSnapshot{"/t/kept": 1, "/t/dir": 4096, "/t/dir/a": 10, "/t/dir/b": 20, "/t/file": 5}
+
+	leaks := before.Leaks(after)
+	expected := []LeakedFile{{"/t/dir", 4126}, {"/t/file", 5}}
+	if len(leaks) != len(expected) || leaks[0] != expected[0] || leaks[1] != expected[1] {
+		t.Errorf("leaked %+v, expected %+v", leaks, expected)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+// TerminalMode is how the installer's stdin and controlling terminal are
+// set up
+type TerminalMode int
+
+const (
+	// the suite's controlling terminal if it has one, and Stdin
+	InheritTerminal TerminalMode = iota
+	// no controlling terminal, and stdin is a pipe that's never written
+	// or closed, so reading input blocks the install until it times out
+	// rather than reading EOF
+	NoTerminal
+	// a pty is the controlling terminal, stdin, stdout and stderr like in
+	// an interactive shell. Nothing is typed into it, and both stdout and
+	// stderr end up in the result's Stdout.
+	PseudoTerminal
+)
+
+var TerminalModes = []TerminalMode{NoTerminal, PseudoTerminal}
+
+func (m TerminalMode) String() string {
+	switch m {
+	case NoTerminal:
+		return "no terminal"
+	case PseudoTerminal:
+		return "pty"
+	}
+	return "inherited terminal"
+}
+
+// setupTerminal replaces cmd's stdio for the mode, finish is called once
+// the installer and everything it spawned exited
+func setupTerminal(t *testing.T, mode TerminalMode, cmd *exec.Cmd) (finish func()) {
+	if mode == InheritTerminal {
+		return func() {}
+	}
+	if cmd.Stdin != nil {
+		t.Fatalf("Stdin can't be combined with %s", mode)
+	}
+
+	// a new session has no controlling terminal, and its process group is
+	// still the installer's pid
+	cmd.SysProcAttr.Setpgid = false
+	cmd.SysProcAttr.Setsid = true
+
+	if mode == NoTerminal {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("couldn't create stdin pipe: %v", err)
+		}
+		cmd.Stdin = r
+		return func() {
+			r.Close()
+			w.Close()
+		}
+	}
+
+	master, slave, err := util.OpenPTY()
+	if err != nil {
+		t.Fatalf("couldn't allocate a pty: %v", err)
+	}
+	out := cmd.Stdout
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = 0
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// reads fail with EIO once every process closed the slave
+		io.Copy(out, master)
+	}()
+	return func() {
+		slave.Close()
+		<-done
+		master.Close()
+	}
+}
+
+var escapePattern = regexp.MustCompile("\x1b\\[[0-9;?]*[A-Za-z]")
+
+// PlainOutput is what's left on screen of output written to a terminal:
+// escape sequences are dropped, and so is everything on a line before its
+// last carriage return, which progress bars redraw themselves with
+func PlainOutput(output []byte) string {
+	lines := strings.Split(escapePattern.ReplaceAllString(string(output), ""), "\n")
+	for i, line := range lines {
+		if j := strings.LastIndexByte(line, '\r'); j >= 0 {
+			lines[i] = line[j+1:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ValidatePlainOutput asserts the installer printed no progress bars or
+// escape sequences, which garble logs when nobody is watching
+func (test Test) ValidatePlainOutput(t *testing.T, result InstallResult) {
+	for name, output := range map[string][]byte{"stdout": result.Stdout, "stderr": result.Stderr} {
+		for _, line := range strings.Split(string(output), "\n") {
+			if PlainOutput([]byte(line)) != line {
+				t.Fatalf("coreos-install printed terminal output to %s without a terminal: %q", name, line)
+			}
+		}
+	}
+}
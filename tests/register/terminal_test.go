@@ -0,0 +1,45 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+func TestTerminalModes(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "tty")
+	err := ioutil.WriteFile(script, []byte("#!/bin/bash\n[ -t 0 ] && echo stdin\n[ -t 1 ] && echo stdout\necho progress$'\\r'done\n[ -t 2 ] && echo stderr >&2\nexit 0\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for mode, expected := range map[TerminalMode]string{
+		NoTerminal:     "progress\rdone\n",
+		PseudoTerminal: "stdin\nstdout\nprogress\rdone\nstderr\n",
+	} {
+		test := Test{temp: util.NewTempManagerIn(t.TempDir())}
+		result := test.TryCoreOSInstallWith(t, Invocation{Binary: script, Terminal: mode})
+		if string(result.Stdout) != expected || result.ExitCode != 0 {
+			t.Errorf("%s: printed %q and exited %d, expected %q", mode, result.Stdout, result.ExitCode, expected)
+		}
+		if PlainOutput(result.Stdout) == string(result.Stdout) {
+			t.Errorf("%s: PlainOutput didn't drop the progress", mode)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+// ErrNoSpace is the installer failing because a tool it runs couldn't write
+// its temp files. It isn't one of the installer's own messages, so it isn't
+// in InstallerErrors.
+var ErrNoSpace = InstallerError{"EXIT_NO_SPACE", ExitFailure, `No space left on device`}
+
+// FullTempDir is a small tmpfs filled up to leave only a few bytes free, to
+// use as the installer's TMPDIR
+type FullTempDir struct {
+	Path string
+}
+
+// CreateFullTempDir mounts a 1MiB tmpfs and fills all but free bytes of it
+func (test Test) CreateFullTempDir(t *testing.T, free int64) FullTempDir {
+	const size = 1 << 20
+
+	path := test.TempDir(t, "coreos-install-tmpdir")
+	if err := util.Mount("tmpfs", path, "tmpfs", 0, fmt.Sprintf("size=%d", size)); err != nil {
+		test.RemoveAll(t, path)
+		t.Fatalf("couldn't mount temp dir: %v", err)
+	}
+	dir := FullTempDir{Path: path}
+
+	filler, err := os.Create(filepath.Join(path, "filler"))
+	if err != nil {
+		test.CleanupFullTempDir(t, dir)
+		t.Fatalf("couldn't create filler: %v", err)
+	}
+	defer filler.Close()
+
+	block := make([]byte, 4096)
+	for written := int64(0); written < size-free; written += int64(len(block)) {
+		if _, err := filler.Write(block); err != nil {
+			// tmpfs rounds to pages, the last page may not fit
+			break
+		}
+	}
+	return dir
+}
+
+func (test Test) CleanupFullTempDir(t *testing.T, dir FullTempDir) {
+	test.UnmountPath(t, dir.Path)
+	test.RemoveAll(t, dir.Path)
+}
+
+// Apply returns a copy of the invocation that uses dir as TMPDIR
+func (dir FullTempDir) Apply(inv Invocation) Invocation {
+	env := map[string]string{"TMPDIR": dir.Path}
+	for k, v := range inv.Env {
+		env[k] = v
+	}
+	inv.Env = env
+	return inv
+}
+
+// ValidateNoTempFiles asserts the installer removed its work dir from the
+// full TMPDIR after failing
+func (dir FullTempDir) ValidateNoTempFiles(t *testing.T) {
+	validateNoTempFilesIn(t, dir.Path)
+}
@@ -0,0 +1,129 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+var updateGoldenFlag = flag.Bool("update-golden", config.UpdateGolden, "rewrite the golden transcripts in "+GoldenDir+" with the installer's current output instead of comparing against them [$COREOS_TEST_UPDATE_GOLDEN]")
+
+// GoldenDir holds the golden transcripts, relative to the tests package
+const GoldenDir = "testdata/transcripts"
+
+// volatile parts of the installer's output, replaced in order after the
+// run's own paths and URLs
+var transcriptRules = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	// wget --no-verbose logs a timestamp and sizes with every download
+	{regexp.MustCompile(`(?m)^\d{4}-\d\d-\d\d \d\d:\d\d:\d\d URL: ?(\S+) .*$`), "wget: $1"},
+	// gpg's chatter depends on its version and the key's trust
+	{regexp.MustCompile(`(?m)(^gpg: .*\n)+`), "gpg: ...\n"},
+	{regexp.MustCompile(`coreos-install\.[A-Za-z0-9]{10}`), "coreos-install.XXXXXXXXXX"},
+	// temp files from TempFile and TempDir
+	{regexp.MustCompile(`(coreos-install-[a-z-]+)\d{3,}`), "${1}N"},
+	{regexp.MustCompile(`/dev/(loop\d+|mapper/[\w-]+)`), "$$DEVICE"},
+	// servers listen on random ports
+	{regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}:\d+\b`), "$$ADDR"},
+	{regexp.MustCompile(`\b\d+\.\d+\.\d+\b`), "$$VERSION"},
+	{regexp.MustCompile(`\b(amd64|arm64)-usr\b`), "$$BOARD"},
+	{regexp.MustCompile(`\b\d+(\.\d+)? ?[kKMG]?B/s\b`), "$$SPEED"},
+	{regexp.MustCompile(`\b\d+(\.\d+)? s\b`), "$$DURATION"},
+}
+
+// Transcript renders the installer's exit status and output with volatile
+// parts normalized, for comparing against a golden transcript. replace maps
+// values only this run knows, like the fixture's URL or TMPDIR, to
+// placeholders.
+func Transcript(result InstallResult, replace map[string]string) string {
+	literals := map[string]string{}
+	if result.Binary != "" {
+		literals[result.Binary] = "coreos-install"
+	}
+	for k, v := range replace {
+		literals[k] = v
+	}
+
+	// longest first, so a path isn't half replaced by its parent
+	keys := make([]string, 0, len(literals))
+	for k := range literals {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return len(keys[i]) > len(keys[j])
+	})
+
+	normalize := func(output string) string {
+		for _, k := range keys {
+			output = strings.Replace(output, k, literals[k], -1)
+		}
+		for _, r := range transcriptRules {
+			output = r.pattern.ReplaceAllString(output, r.replacement)
+		}
+		return output
+	}
+
+	return fmt.Sprintf("exit: %s\n--- stdout\n%s--- stderr\n%s", ExitName(result), normalize(string(result.Stdout)), normalize(string(result.Stderr)))
+}
+
+// ValidateTranscript compares the install's transcript against the golden
+// one named name, or records it with -update-golden
+func (test Test) ValidateTranscript(t *testing.T, name string, result InstallResult, replace map[string]string) {
+	literals := map[string]string{test.TempRoot(): "$TMPDIR"}
+	for k, v := range replace {
+		literals[k] = v
+	}
+	transcript := Transcript(result, literals)
+	golden := filepath.Join(GoldenDir, name+".txt")
+
+	if *updateGoldenFlag {
+		// goldens are committed, unlike artifacts
+		if util.Redact(transcript) != transcript {
+			t.Fatalf("not recording %s, the output contains secrets:\n%s", golden, util.Redact(transcript))
+		}
+		if err := os.MkdirAll(GoldenDir, 0755); err != nil {
+			t.Fatalf("couldn't create %s: %v", GoldenDir, err)
+		}
+		if err := ioutil.WriteFile(golden, []byte(transcript), 0644); err != nil {
+			t.Fatalf("couldn't update golden transcript: %v", err)
+		}
+		t.Logf("updated %s", golden)
+		return
+	}
+
+	expected, err := ioutil.ReadFile(golden)
+	if os.IsNotExist(err) {
+		t.Fatalf("no golden transcript at %s, record one with -update-golden", golden)
+	} else if err != nil {
+		t.Fatalf("couldn't read golden transcript: %v", err)
+	}
+
+	if string(expected) != transcript {
+		diffs := util.DiffLines(strings.Split(string(expected), "\n"), strings.Split(transcript, "\n"))
+		t.Fatalf("output differs from %s, review and rerun with -update-golden if the change is intended:\n%s", golden, strings.Join(diffs, "\n"))
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+const unitDir = "usr/lib/systemd/system"
+
+// executables this repo ships, unit references to them must resolve in
+// the installed tree
+const coreosLibexec = "/usr/lib/coreos/"
+
+// CheckoutDir is the root of this checkout, skipping the test if the suite
+// isn't running from one
+func CheckoutDir(t *testing.T) string {
+	for _, p := range checkoutInstallPaths {
+		dir := filepath.Dir(filepath.Dir(p))
+		if _, err := os.Stat(filepath.Join(dir, "systemd", "system")); err == nil {
+			path, err := filepath.Abs(dir)
+			if err != nil {
+				t.Fatalf("couldn't resolve %s: %v", dir, err)
+			}
+			return path
+		}
+	}
+	t.Skip("not running from a checkout")
+	return ""
+}
+
+// InstallCheckout runs the checkout's make install into a temp dir and
+// returns it, so units are tested as they're shipped
+func (test Test) InstallCheckout(t *testing.T) string {
+	util.RequireTools(t, "make", "install")
+
+	root := test.TempDir(t, "coreos-init-root")
+	util.MustRun(t, "make", "-C", CheckoutDir(t), "install", "DESTDIR="+root)
+	return root
+}
+
+// Unit is a parsed unit file, values are kept in order and an empty
+// assignment resets a key like systemd does
+type Unit struct {
+	Name     string
+	Path     string
+	Sections map[string]map[string][]string
+}
+
+func ParseUnit(path string) (Unit, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Unit{}, err
+	}
+	defer f.Close()
+
+	u := Unit{
+		Name:     filepath.Base(path),
+		Path:     path,
+		Sections: map[string]map[string][]string{},
+	}
+
+	var section map[string][]string
+	var continued string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := continued + strings.TrimSpace(scanner.Text())
+		continued = ""
+		if strings.HasSuffix(line, `\`) {
+			continued = strings.TrimSuffix(line, `\`) + " "
+			continue
+		}
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			name := strings.Trim(line, "[]")
+			if u.Sections[name] == nil {
+				u.Sections[name] = map[string][]string{}
+			}
+			section = u.Sections[name]
+		case section != nil && strings.Contains(line, "="):
+			kv := strings.SplitN(line, "=", 2)
+			key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+			if value == "" {
+				delete(section, key)
+			} else {
+				section[key] = append(section[key], value)
+			}
+		}
+	}
+	return u, scanner.Err()
+}
+
+func (u Unit) Get(section, key string) []string {
+	return u.Sections[section][key]
+}
+
+// LoadUnits parses every unit installed under root
+func (test Test) LoadUnits(t *testing.T, root string) []Unit {
+	files, err := ioutil.ReadDir(filepath.Join(root, unitDir))
+	if err != nil {
+		t.Fatalf("couldn't list units: %v", err)
+	}
+
+	var units []Unit
+	for _, f := range files {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+
+		u, err := ParseUnit(filepath.Join(root, unitDir, f.Name()))
+		if err != nil {
+			t.Fatalf("couldn't parse %s: %v", f.Name(), err)
+		}
+		units = append(units, u)
+	}
+	return units
+}
+
+var execKeys = []string{"ExecStartPre", "ExecStart", "ExecStartPost", "ExecStop", "ExecStopPost", "ExecReload"}
+
+// execCommands returns the unit's commands split into words, without the
+// prefixes that change how systemd runs them
+func (u Unit) execCommands(key string) [][]string {
+	var commands [][]string
+	for _, line := range u.Get("Service", key) {
+		words := splitExec(strings.TrimLeft(line, "-@:+!"))
+		if len(words) != 0 {
+			commands = append(commands, words)
+		}
+	}
+	return commands
+}
+
+// splitExec splits a command line on spaces, keeping double quoted words
+// together
+func splitExec(line string) []string {
+	var words []string
+	var word strings.Builder
+	quoted, inWord := false, false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inWord = true
+		case r == ' ' && !quoted:
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// ValidateUnit checks the commands a unit runs from this repo are
+// installed and executable
+func (test Test) ValidateUnit(t *testing.T, root string, u Unit) {
+	for _, key := range execKeys {
+		for _, command := range u.execCommands(key) {
+			if !strings.HasPrefix(command[0], coreosLibexec) {
+				continue
+			}
+
+			info, err := os.Stat(filepath.Join(root, command[0]))
+			if err != nil {
+				t.Fatalf("%s %s runs a missing command: %v", u.Name, key, err)
+			}
+			if info.Mode()&0111 == 0 {
+				t.Fatalf("%s %s runs %s which isn't executable", u.Name, key, command[0])
+			}
+		}
+	}
+}
+
+// ValidateUnitLinks checks every unit enabled through a .wants or
+// .requires directory links to an installed unit
+func (test Test) ValidateUnitLinks(t *testing.T, root string) {
+	links, err := filepath.Glob(filepath.Join(root, unitDir, "*.[wr]*s", "*"))
+	if err != nil {
+		t.Fatalf("couldn't list unit links: %v", err)
+	}
+	sort.Strings(links)
+
+	for _, link := range links {
+		if _, err := os.Stat(link); err != nil {
+			t.Errorf("%s is enabled but doesn't resolve: %v", strings.TrimPrefix(link, root), err)
+		}
+	}
+}
+
+// UnitRun controls how RunUnit runs a unit's commands
+type UnitRun struct {
+	// stub commands, name to shell script, found first in PATH
+	Mocks map[string]string
+	// paths replaced by an empty directory for the run, so what the unit
+	// writes there can be inspected
+	Scratch []string
+}
+
+type UnitResult struct {
+	Output   []byte
+	ExitCode int
+	// the host directory each of UnitRun's Scratch paths was
+	Scratch map[string]string
+}
+
+// RunUnit runs a oneshot unit's ExecStart commands as transient units with
+// systemd-run, with the installed /usr/lib/coreos bound in place of the
+// host's. It needs a host booted with systemd.
+func (test Test) RunUnit(t *testing.T, root string, u Unit, run UnitRun) UnitResult {
+	util.RequireRoot(t)
+	util.RequireTools(t, "systemd-run")
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		t.Skip("systemd isn't running, it's needed to run units")
+	}
+
+	result := UnitResult{Scratch: map[string]string{}}
+	props := []string{
+		"--wait", "--pipe", "--collect", "--quiet",
+		"-p", "BindReadOnlyPaths=" + filepath.Join(root, coreosLibexec) + ":" + coreosLibexec,
+	}
+
+	path := "/usr/sbin:/usr/bin:/sbin:/bin"
+	if len(run.Mocks) != 0 {
+		mocks := test.TempDir(t, "coreos-init-mocks")
+		for name, script := range run.Mocks {
+			if err := ioutil.WriteFile(filepath.Join(mocks, name), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+				t.Fatalf("couldn't write mock %s: %v", name, err)
+			}
+		}
+		path = mocks + ":" + path
+	}
+	props = append(props, "-p", "Environment=PATH="+path)
+
+	for _, env := range u.Get("Service", "Environment") {
+		props = append(props, "-p", "Environment="+env)
+	}
+
+	for _, p := range run.Scratch {
+		dir := test.TempDir(t, "coreos-init-scratch")
+		result.Scratch[p] = dir
+		props = append(props, "-p", "BindPaths="+dir+":"+p)
+	}
+
+	for _, command := range u.execCommands("ExecStart") {
+		args := append(append(append([]string(nil), props...), "--"), command...)
+		t.Logf("running %s", util.FormatCommand("systemd-run", args...))
+
+		cmd := util.Command(context.Background(), "systemd-run", args...)
+		out, err := cmd.CombinedOutput()
+		result.Output = append(result.Output, out...)
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			result.ExitCode = exitErr.ExitCode()
+			break
+		} else if err != nil {
+			t.Fatalf("couldn't run %s: %v", u.Name, err)
+		}
+	}
+	return result
+}
@@ -0,0 +1,71 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+// ImagePath is where the mirror keeps an image, for reading it directly
+func (f *FixtureServer) ImagePath(base, version, image string) string {
+	return filepath.Join(*fixtureDirFlag, filepath.FromSlash(base), version, image)
+}
+
+// ValidateUpgrade asserts an install over an earlier one reused its layout,
+// partitions keep their numbers, labels, types and start sectors, and that
+// every GUID on the disk is the new image's rather than left over from the
+// earlier install
+func (test Test) ValidateUpgrade(t *testing.T, diskFile string, before []Partition, imagePath string) {
+	image, err := util.ReadImageGPT(imagePath)
+	if err != nil {
+		t.Fatalf("couldn't read the partition table of %s: %v", imagePath, err)
+	}
+
+	disk, err := util.ReadGPT(diskFile)
+	if err != nil {
+		t.Fatalf("couldn't read partition table: %v", err)
+	}
+
+	if disk.Header.DiskGUID != image.Header.DiskGUID {
+		t.Fatalf("disk GUID doesn't match the image: expected %s, received %s", image.Header.DiskGUID, disk.Header.DiskGUID)
+	}
+
+	after := test.ListPartitions(t, diskFile)
+	for _, old := range before {
+		p, ok := FindPartition(after, old.Label)
+		if !ok {
+			t.Fatalf("partition %s from the earlier install is gone", old.Label)
+		}
+
+		if p.Number != old.Number || !strings.EqualFold(p.TypeGUID, old.TypeGUID) || p.FirstSector != old.FirstSector {
+			t.Fatalf("partition %s moved: was number %d type %s at sector %d, now number %d type %s at sector %d",
+				old.Label, old.Number, old.TypeGUID, old.FirstSector, p.Number, p.TypeGUID, p.FirstSector)
+		}
+	}
+
+	guids := map[int]string{}
+	for _, p := range image.Partitions {
+		guids[p.Number] = p.GUID
+	}
+	for _, p := range after {
+		if expected := guids[p.Number]; !strings.EqualFold(p.GUID, expected) {
+			t.Fatalf("partition %s has GUID %s, expected the image's %s", p.Label, p.GUID, expected)
+		}
+	}
+}
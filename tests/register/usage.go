@@ -0,0 +1,152 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/coreos/init/tests/util"
+)
+
+// ResourceUsage is what an install cost, to make double writes and repeated
+// downloads show up as numbers
+type ResourceUsage struct {
+	// growth of everything under TMPDIR at its peak while the installer ran
+	PeakTempBytes int64
+	// read from and written to the target, from /proc/diskstats
+	BytesRead    uint64
+	BytesWritten uint64
+	// served by the fixture, only set by RunCoreOSInstallHermetic
+	BytesDownloaded int64
+}
+
+const usageSampleInterval = 250 * time.Millisecond
+
+// usageMonitor samples TMPDIR while the installer runs and diffs the
+// target's diskstats around it
+type usageMonitor struct {
+	device string
+	before *util.DiskStats
+	tmpDir string
+
+	baseline int64
+	peak     int64
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// startUsageMonitor measures the installer's use of tmpDir, its TMPDIR, and
+// its I/O to device
+func startUsageMonitor(t *testing.T, device, tmpDir string) *usageMonitor {
+	m := &usageMonitor{
+		device: device,
+		tmpDir: tmpDir,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if m.tmpDir == "" {
+		m.tmpDir = os.TempDir()
+	}
+
+	if device != "" {
+		if stats, err := util.ReadDiskStats(device); err == nil {
+			m.before = &stats
+		} else {
+			t.Logf("not measuring I/O to %s: %v", device, err)
+		}
+	}
+
+	m.baseline = allocatedBytes(m.tmpDir)
+	m.peak = m.baseline
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(usageSampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+				if used := allocatedBytes(m.tmpDir); used > m.peak {
+					m.peak = used
+				}
+			}
+		}
+	}()
+	return m
+}
+
+func (m *usageMonitor) Stop(t *testing.T) ResourceUsage {
+	close(m.stop)
+	<-m.done
+
+	usage := ResourceUsage{PeakTempBytes: m.peak - m.baseline}
+	if m.before != nil {
+		after, err := util.ReadDiskStats(m.device)
+		if err != nil {
+			t.Logf("couldn't measure I/O to %s: %v", m.device, err)
+			return usage
+		}
+		delta := after.Sub(*m.before)
+		usage.BytesRead = delta.BytesRead()
+		usage.BytesWritten = delta.BytesWritten()
+	}
+	return usage
+}
+
+// allocatedBytes is the space files under dir take up on disk, so sparse
+// disk files only count what's been written to them. Files that disappear
+// while it's walking are skipped.
+func allocatedBytes(dir string) int64 {
+	var total int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			total += stat.Blocks * 512
+		}
+		return nil
+	})
+	return total
+}
+
+// logUsage reports the install's usage and saves it with the test's
+// artifacts
+func logUsage(t *testing.T, usage ResourceUsage) {
+	t.Logf("coreos-install used %d bytes of TMPDIR at its peak, read %d and wrote %d bytes on the target", usage.PeakTempBytes, usage.BytesRead, usage.BytesWritten)
+	saveUsage(t, usage)
+}
+
+// saveUsage writes usage.json, replacing any saved for the test before
+func saveUsage(t *testing.T, usage ResourceUsage) {
+	path := ArtifactPath(t, "usage.json")
+	if path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(usage, "", "  ")
+	if err != nil {
+		t.Fatalf("couldn't encode usage: %v", err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("couldn't save usage: %v", err)
+	}
+}
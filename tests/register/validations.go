@@ -0,0 +1,48 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"strings"
+	"testing"
+)
+
+// Validations runs each validator as a subtest so one failing check doesn't
+// hide the others, and a single run reports everything wrong with an
+// install
+type Validations struct {
+	t      *testing.T
+	total  int
+	failed []string
+}
+
+func (test Test) NewValidations(t *testing.T) *Validations {
+	return &Validations{t: t}
+}
+
+// Run runs f as a subtest, a Fatalf inside f only stops that check
+func (v *Validations) Run(name string, f func(t *testing.T)) {
+	v.total++
+	if !v.t.Run(name, f) {
+		v.failed = append(v.failed, name)
+	}
+}
+
+// Finish fails the test with a summary of every failed check
+func (v *Validations) Finish() {
+	if len(v.failed) != 0 {
+		v.t.Fatalf("%d of %d validations failed: %s", len(v.failed), v.total, strings.Join(v.failed, ", "))
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+// VendorDisk is the disk a bare-metal machine ships with, the vendor's ESP
+// at the start and a data partition for its tooling at the end, see
+// CreateVendorDisk
+type VendorDisk struct {
+	ESP  Partition
+	Data Partition
+
+	// hashes of the partitions before the install
+	hashes map[int]string
+}
+
+const (
+	vendorESPLabel  = "EFI System Partition"
+	vendorDataLabel = "vendor-data"
+)
+
+// CreateVendorDisk partitions and formats the disk like a machine fresh
+// from the vendor, and makes the kernel read the table so the installer
+// finds the partitions in use like it would on real hardware
+func (test Test) CreateVendorDisk(t *testing.T, diskFile, loopDevice string) VendorDisk {
+	util.RequireTools(t, "mkfs.vfat", "mkfs.ext4")
+
+	// the data partition ends before the tail the installer zeroes
+	util.MustRun(t, "sgdisk",
+		"-n", "1:2048:+256M", "-t", "1:EF00", "-c", "1:"+vendorESPLabel,
+		"-n", "2:-1G:-1M", "-t", "2:8300", "-c", "2:"+vendorDataLabel,
+		diskFile)
+
+	var vendor VendorDisk
+	for _, p := range test.ListPartitions(t, diskFile) {
+		switch p.Label {
+		case vendorESPLabel:
+			vendor.ESP = p
+		case vendorDataLabel:
+			vendor.Data = p
+		}
+	}
+
+	espSize := int64(vendor.ESP.LastSector-vendor.ESP.FirstSector+1) * sectorSize
+	util.MustRun(t, "mkfs.vfat", "-F", "32", "-n", "VENDOR", "--offset", strconv.FormatUint(vendor.ESP.FirstSector, 10), diskFile, strconv.FormatInt(espSize/1024, 10))
+	dataSize := int64(vendor.Data.LastSector-vendor.Data.FirstSector+1) * sectorSize
+	util.MustRun(t, "mkfs.ext4", "-q", "-F", "-L", "vendor-data", "-E", fmt.Sprintf("offset=%d", int64(vendor.Data.FirstSector)*sectorSize), diskFile, strconv.FormatInt(dataSize/1024, 10))
+
+	device := loopDevice
+	if underlying := test.underlying(loopDevice); underlying != "" {
+		device = underlying
+	}
+	if err := util.RescanPartitions(device); err != nil {
+		t.Fatalf("couldn't reread partitions on %s: %v", device, err)
+	}
+
+	vendor.hashes = map[int]string{
+		vendor.ESP.Number:  test.HashPartition(t, diskFile, vendor.ESP),
+		vendor.Data.Number: test.HashPartition(t, diskFile, vendor.Data),
+	}
+	return vendor
+}
+
+// ValidateVendorDisk asserts what coreos-install documents for existing
+// partitions: the image replaces the partition table and everything it
+// covers, including the vendor ESP, while bytes past the end of the image
+// are left alone, unreferenced but recoverable
+func (test Test) ValidateVendorDisk(t *testing.T, diskFile string, vendor VendorDisk) {
+	partitions := test.ListPartitions(t, diskFile)
+	var imageEnd uint64
+	for _, p := range partitions {
+		if p.Label == vendorESPLabel || p.Label == vendorDataLabel {
+			t.Fatalf("vendor partition %d %q is still in the partition table", p.Number, p.Label)
+		}
+		if p.LastSector > imageEnd {
+			imageEnd = p.LastSector
+		}
+	}
+
+	if test.HashPartition(t, diskFile, vendor.ESP) == vendor.hashes[vendor.ESP.Number] {
+		t.Fatalf("vendor ESP at sectors %d-%d wasn't overwritten", vendor.ESP.FirstSector, vendor.ESP.LastSector)
+	}
+
+	if vendor.Data.FirstSector <= imageEnd {
+		t.Fatalf("vendor data partition at sector %d overlaps the image, which ends at %d", vendor.Data.FirstSector, imageEnd)
+	}
+	if test.HashPartition(t, diskFile, vendor.Data) != vendor.hashes[vendor.Data.Number] {
+		t.Fatalf("vendor data past the end of the image at sectors %d-%d was modified", vendor.Data.FirstSector, vendor.Data.LastSector)
+	}
+}
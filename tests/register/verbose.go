@@ -0,0 +1,47 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+)
+
+// VerboseTraces are expected in the output of any successful install run
+// with -v, which turns on bash tracing
+var VerboseTraces = []string{
+	`(?m)^\++ `,
+	`(?m)^\++ write_to_disk`,
+	`(?m)^Success! `,
+}
+
+// ValidateVerboseOutput checks the output of an install run with -v has
+// every trace pattern and none of the secrets
+func (test Test) ValidateVerboseOutput(t *testing.T, result InstallResult, traces []string, secrets []string) {
+	output := append(append([]byte{}, result.Stdout...), result.Stderr...)
+
+	for _, pattern := range append(append([]string{}, VerboseTraces...), traces...) {
+		if !regexp.MustCompile(pattern).Match(output) {
+			t.Fatalf("verbose output is missing %q", pattern)
+		}
+	}
+
+	for _, secret := range secrets {
+		if bytes.Contains(output, []byte(secret)) {
+			t.Fatalf("verbose output leaked a secret")
+		}
+	}
+}
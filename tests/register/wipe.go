@@ -0,0 +1,98 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+// ByteRange is a region of the disk file
+type ByteRange struct {
+	Offset int64
+	Length int64
+}
+
+// coreos-install zeroes the last 1024 sectors of the target before writing
+// the image, where ZFS and others keep their labels
+const wipedTailSectors = 1024
+
+// WipedTail returns the region at the end of the disk the installer zeroes
+func (test Test) WipedTail(t *testing.T, diskFile string) ByteRange {
+	info, err := os.Stat(diskFile)
+	if err != nil {
+		t.Fatalf("couldn't stat disk file: %v", err)
+	}
+
+	length := int64(wipedTailSectors * sectorSize)
+	return ByteRange{Offset: info.Size() - length, Length: length}
+}
+
+// FillRanges writes a non-zero pattern over the ranges so a later
+// ValidateZeroed proves they were actually wiped
+func (test Test) FillRanges(t *testing.T, diskFile string, ranges ...ByteRange) {
+	f, err := os.OpenFile(diskFile, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("couldn't open disk file: %v", err)
+	}
+	defer f.Close()
+
+	for _, r := range ranges {
+		pattern := bytes.Repeat([]byte{0xa5}, int(r.Length))
+		if _, err := f.WriteAt(pattern, r.Offset); err != nil {
+			t.Fatalf("couldn't fill %d bytes at %d: %v", r.Length, r.Offset, err)
+		}
+	}
+
+	if err := f.Sync(); err != nil {
+		t.Fatalf("couldn't sync disk file: %v", err)
+	}
+}
+
+// ValidateZeroed asserts the ranges of the disk file read back as zeros
+func (test Test) ValidateZeroed(t *testing.T, diskFile string, ranges ...ByteRange) {
+	f, err := os.Open(diskFile)
+	if err != nil {
+		t.Fatalf("couldn't open disk file: %v", err)
+	}
+	defer f.Close()
+
+	buf := make([]byte, 1024*1024)
+	for _, r := range ranges {
+		reader := io.NewSectionReader(f, r.Offset, r.Length)
+		offset := r.Offset
+		for {
+			n, err := reader.Read(buf)
+			for i, b := range buf[:n] {
+				if b != 0 {
+					t.Fatalf("disk isn't zeroed at byte %d (range %d+%d)", offset+int64(i), r.Offset, r.Length)
+				}
+			}
+			offset += int64(n)
+
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("couldn't read %d bytes at %d: %v", r.Length, r.Offset, err)
+			}
+		}
+
+		if offset != r.Offset+r.Length {
+			t.Fatalf("range %d+%d is past the end of the disk", r.Offset, r.Length)
+		}
+	}
+}
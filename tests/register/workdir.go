@@ -0,0 +1,77 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package register
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/coreos/init/tests/util"
+)
+
+type WorkingDirKind int
+
+const (
+	// removed after the installer starts in it
+	DeletedWorkingDir WorkingDirKind = iota
+	// a read-only tmpfs, so it is read-only even for root
+	ReadOnlyWorkingDir
+	// a path with spaces in it
+	SpacedWorkingDir
+)
+
+// WorkingDir is an unusual directory to start the installer in, to catch
+// assumptions about relative paths
+type WorkingDir struct {
+	Path string
+
+	deleted bool
+	mounted bool
+}
+
+func (test Test) CreateWorkingDir(t *testing.T, kind WorkingDirKind) WorkingDir {
+	prefix := "coreos-install-cwd"
+	if kind == SpacedWorkingDir {
+		prefix = "coreos install cwd with spaces "
+	}
+
+	path := test.TempDir(t, prefix)
+
+	dir := WorkingDir{Path: path}
+	switch kind {
+	case DeletedWorkingDir:
+		dir.deleted = true
+	case ReadOnlyWorkingDir:
+		if err := util.Mount("tmpfs", path, "tmpfs", syscall.MS_RDONLY, "size=1m"); err != nil {
+			t.Fatalf("couldn't mount read-only working dir: %v", err)
+		}
+		dir.mounted = true
+	}
+	return dir
+}
+
+func (test Test) CleanupWorkingDir(t *testing.T, dir WorkingDir) {
+	if dir.mounted {
+		test.UnmountPath(t, dir.Path)
+	}
+	test.RemoveAll(t, dir.Path)
+}
+
+// Apply returns a copy of the invocation that starts the installer in dir
+func (dir WorkingDir) Apply(inv Invocation) Invocation {
+	inv.Dir = dir.Path
+	inv.DeleteDir = dir.deleted
+	return inv
+}
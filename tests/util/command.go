@@ -0,0 +1,116 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// DefaultCommandTimeout bounds the helper commands, none of them should
+// take more than a few seconds and a stuck one shouldn't hang the suite
+var DefaultCommandTimeout = 5 * time.Minute
+
+// Command prepares a command that runs in its own process group, which is
+// killed along with everything it spawned when ctx is done
+func Command(ctx context.Context, command string, opts ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, command, opts...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	// don't wait forever on output pipes held open by orphaned children
+	cmd.WaitDelay = 10 * time.Second
+	return cmd
+}
+
+// Reap kills anything a finished command left running in its process group
+func Reap(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// WaitExited blocks until the process exits, without reaping it. Until it's
+// reaped with Wait its PID, and the process group named after it, can't be
+// reused, so it's still safe to signal.
+func WaitExited(pid int) error {
+	const pPID, wNoWait = 1, 0x1000000
+	// siginfo_t, which isn't looked at
+	var info [128]byte
+	for {
+		_, _, errno := syscall.Syscall6(syscall.SYS_WAITID, pPID, uintptr(pid), uintptr(unsafe.Pointer(&info)), syscall.WEXITED|wNoWait, 0, 0)
+		if errno != syscall.EINTR {
+			if errno != 0 {
+				return errno
+			}
+			return nil
+		}
+	}
+}
+
+// CmdResult is how a command ended
+type CmdResult struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+	Duration time.Duration
+}
+
+// Exec runs the command with DefaultCommandTimeout on top of ctx. A nonzero
+// exit isn't an error, err is only set if the command couldn't be run or
+// didn't finish in time.
+func Exec(ctx context.Context, command string, opts ...string) (CmdResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultCommandTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := Command(ctx, command, opts...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	Reap(cmd)
+
+	result := CmdResult{
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+		Duration: time.Since(start),
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return result, fmt.Errorf("timed out after %v", result.Duration)
+	}
+
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		result.ExitCode = exitErr.ExitCode()
+		return result, nil
+	}
+	return result, err
+}
+
+// Err describes a failed result, nil if it exited successfully
+func (r CmdResult) Err() error {
+	if r.ExitCode != 0 {
+		return fmt.Errorf("exit code %d: %s", r.ExitCode, bytes.TrimSpace(r.Stderr))
+	}
+	return nil
+}
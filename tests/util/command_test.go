@@ -0,0 +1,45 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestWaitExited(t *testing.T) {
+	cmd := Command(context.Background(), "true")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := WaitExited(cmd.Process.Pid); err != nil {
+		t.Fatal(err)
+	}
+
+	// exited but not reaped, so it keeps its PID
+	stat, err := ioutil.ReadFile("/proc/" + fmt.Sprint(cmd.Process.Pid) + "/stat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields := strings.Fields(string(stat[strings.LastIndex(string(stat), ")")+1:])); fields[0] != "Z" {
+		t.Errorf("process is in state %s, expected a zombie", fields[0])
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Errorf("the exit status was lost: %v", err)
+	}
+}
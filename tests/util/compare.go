@@ -0,0 +1,173 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// JSONEqual compares two JSON documents by value, ignoring key order and
+// whitespace. The error lists every difference by its path in the document.
+func JSONEqual(expected, actual []byte) error {
+	var e, a interface{}
+	if err := json.Unmarshal(expected, &e); err != nil {
+		return fmt.Errorf("couldn't parse expected JSON: %v", err)
+	}
+	if err := json.Unmarshal(actual, &a); err != nil {
+		return fmt.Errorf("couldn't parse JSON: %v", err)
+	}
+	return diffError(e, a)
+}
+
+// YAMLEqual compares two YAML documents by value like JSONEqual, see
+// ParseYAML for the YAML that's supported
+func YAMLEqual(expected, actual []byte) error {
+	e, err := ParseYAML(expected)
+	if err != nil {
+		return fmt.Errorf("couldn't parse expected YAML: %v", err)
+	}
+	a, err := ParseYAML(actual)
+	if err != nil {
+		return fmt.Errorf("couldn't parse YAML: %v", err)
+	}
+	return diffError(e, a)
+}
+
+func diffError(expected, actual interface{}) error {
+	diffs := DiffValues("", expected, actual)
+	if len(diffs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d differences:\n%s", len(diffs), strings.Join(diffs, "\n"))
+}
+
+// DiffValues lists the differences between two decoded documents, each
+// prefixed with its path like .storage.files[0].path
+func DiffValues(path string, expected, actual interface{}) (diffs []string) {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		keys := map[string]bool{}
+		for k := range e {
+			keys[k] = true
+		}
+		for k := range a {
+			keys[k] = true
+		}
+
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+
+		for _, k := range sorted {
+			ev, inE := e[k]
+			av, inA := a[k]
+			switch {
+			case !inA:
+				diffs = append(diffs, fmt.Sprintf("%s.%s: missing, expected %s", path, k, describe(ev)))
+			case !inE:
+				diffs = append(diffs, fmt.Sprintf("%s.%s: unexpected %s", path, k, describe(av)))
+			default:
+				diffs = append(diffs, DiffValues(path+"."+k, ev, av)...)
+			}
+		}
+		return
+
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok {
+			break
+		}
+
+		if len(e) != len(a) {
+			diffs = append(diffs, fmt.Sprintf("%s: expected %d items, received %d", pathOrRoot(path), len(e), len(a)))
+		}
+		for i := 0; i < len(e) && i < len(a); i++ {
+			diffs = append(diffs, DiffValues(fmt.Sprintf("%s[%d]", path, i), e[i], a[i])...)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		diffs = append(diffs, fmt.Sprintf("%s: expected %s, received %s", pathOrRoot(path), describe(expected), describe(actual)))
+	}
+	return
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "."
+	}
+	return path
+}
+
+func describe(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%#v", v)
+	}
+	if len(data) > 80 {
+		return string(data[:77]) + "..."
+	}
+	return string(data)
+}
+
+// DiffLines lists the lines only in expected prefixed with "-" and those
+// only in actual with "+", in order, with unchanged lines left out
+func DiffLines(expected, actual []string) (diffs []string) {
+	// longest common subsequence, transcripts are short enough for the
+	// quadratic table
+	lcs := make([][]int, len(expected)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(actual)+1)
+	}
+	for i := len(expected) - 1; i >= 0; i-- {
+		for j := len(actual) - 1; j >= 0; j-- {
+			if expected[i] == actual[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(expected) || j < len(actual) {
+		switch {
+		case i < len(expected) && j < len(actual) && expected[i] == actual[j]:
+			i++
+			j++
+		case j == len(actual) || (i < len(expected) && lcs[i+1][j] >= lcs[i][j+1]):
+			diffs = append(diffs, "-"+expected[i])
+			i++
+		default:
+			diffs = append(diffs, "+"+actual[j])
+			j++
+		}
+	}
+	return
+}
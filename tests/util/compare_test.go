@@ -0,0 +1,108 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONEqual(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		expected string
+		actual   string
+		err      string
+	}{
+		{"key order and whitespace", `{"a": 1, "b": [true, null]}`, "{\"b\":[true,null],\n\"a\":1}", ""},
+		{"numbers by value", `{"a": 1}`, `{"a": 1.0}`, ""},
+		{"changed value", `{"a": {"b": "x"}}`, `{"a": {"b": "y"}}`, "1 differences:\n.a.b: expected \"x\", received \"y\""},
+		{"every difference", `{"a": 1, "b": [1, 2], "c": {}}`, `{"a": "1", "b": [1], "d": null}`,
+			"4 differences:\n" +
+				".a: expected 1, received \"1\"\n" +
+				".b: expected 2 items, received 1\n" +
+				".c: missing, expected {}\n" +
+				".d: unexpected null"},
+		{"invalid expected", `{`, `{}`, "couldn't parse expected JSON: "},
+		{"invalid actual", `{}`, `{"a": }`, "couldn't parse JSON: "},
+	} {
+		err := JSONEqual([]byte(c.expected), []byte(c.actual))
+		switch {
+		case c.err == "" && err != nil:
+			t.Errorf("%s: %v", c.name, err)
+		case c.err != "" && (err == nil || !strings.HasPrefix(err.Error(), c.err)):
+			t.Errorf("%s: got error %v, expected %q", c.name, err, c.err)
+		}
+	}
+}
+
+func TestYAMLEqual(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		expected string
+		actual   string
+		err      string
+	}{
+		{"formatting", "a:\n  - b\n  - 'c'\nd: |\n  text\n", "d: \"text\\n\" # comment\na: [\"b\", \"c\"]\n", ""},
+		{"changed value", "a:\n  b: 1\n", "a:\n  b: 2\n", "1 differences:\n.a.b: expected 1, received 2"},
+		{"quoted number", "a: 1\n", "a: '1'\n", "1 differences:\n.a: expected 1, received \"1\""},
+		{"invalid expected", "a: 1\n  b: 2\n", "a: 1\n", "couldn't parse expected YAML: line 2: bad indentation"},
+		{"invalid actual", "a: 1\n", "a: [b\n", "couldn't parse YAML: line 1: "},
+	} {
+		err := YAMLEqual([]byte(c.expected), []byte(c.actual))
+		switch {
+		case c.err == "" && err != nil:
+			t.Errorf("%s: %v", c.name, err)
+		case c.err != "" && (err == nil || !strings.HasPrefix(err.Error(), c.err)):
+			t.Errorf("%s: got error %v, expected %q", c.name, err, c.err)
+		}
+	}
+}
+
+func TestDiffValues(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	for _, c := range []struct {
+		name     string
+		expected interface{}
+		actual   interface{}
+		diffs    []string
+	}{
+		{"equal", map[string]interface{}{"a": []interface{}{1.0}}, map[string]interface{}{"a": []interface{}{1.0}}, nil},
+		{"root scalar", "a", "b", []string{`.: expected "a", received "b"`}},
+		{"root type", map[string]interface{}{}, []interface{}{}, []string{".: expected {}, received []"}},
+		{"nested path", map[string]interface{}{"storage": map[string]interface{}{"files": []interface{}{
+			map[string]interface{}{"path": "/a", "mode": 420.0},
+		}}}, map[string]interface{}{"storage": map[string]interface{}{"files": []interface{}{
+			map[string]interface{}{"path": "/b", "mode": 420.0},
+		}}}, []string{`.storage.files[0].path: expected "/a", received "/b"`}},
+		{"sorted keys", map[string]interface{}{"b": 1.0, "a": 1.0}, map[string]interface{}{"c": 1.0, "b": 2.0}, []string{
+			".a: missing, expected 1",
+			".b: expected 1, received 2",
+			".c: unexpected 1",
+		}},
+		{"longer sequence", []interface{}{"a", "b"}, []interface{}{"a", "c", "d"}, []string{
+			".: expected 2 items, received 3",
+			`[1]: expected "b", received "c"`,
+		}},
+		{"long values are cut short", long, "", []string{
+			`.: expected "` + long[:76] + `..., received ""`,
+		}},
+	} {
+		if diffs := DiffValues("", c.expected, c.actual); !reflect.DeepEqual(diffs, c.diffs) {
+			t.Errorf("%s: got %q, expected %q", c.name, diffs, c.diffs)
+		}
+	}
+}
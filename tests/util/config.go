@@ -0,0 +1,201 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigPrefix is prepended to every Config variable name
+const ConfigPrefix = "COREOS_TEST_"
+
+// Config is the suite's configuration from COREOS_TEST_* environment
+// variables. Command line flags of the same name override it.
+type Config struct {
+	// coreos-install to test, defaults to the one in the checkout
+	Installer string `env:"INSTALLER"`
+	// which implementation in the checkout to test, "script" or "go"
+	Implementation string `env:"IMPLEMENTATION" default:"script"`
+	// where logs and other artifacts are saved, none are if empty
+	ArtifactDir string `env:"ARTIFACT_DIR"`
+	// another regexp to redact from logs and artifacts, on top of
+	// credentials in URLs
+	SecretPattern string `env:"SECRET_PATTERN"`
+	// what to do about secrets found in a test's artifacts once it
+	// finished, "redact" them or also "fail" the test
+	LeakedSecrets string `env:"LEAKED_SECRETS" default:"redact"`
+	// where the suite's progress is saved so an interrupted run can be
+	// resumed, nothing is saved if empty
+	StateFile string `env:"STATE_FILE"`
+	// disk image or block device with an install made by another tool,
+	// validated instead of running installs
+	ValidateImage string `env:"VALIDATE_IMAGE"`
+	// the version it was installed with
+	ValidateImageVersion string `env:"VALIDATE_IMAGE_VERSION"`
+	// local mirror of release.core-os.net for hermetic installs
+	FixtureDir string `env:"FIXTURE_DIR"`
+	// PXE kernel and initramfs with their signatures, for mirrors that
+	// don't have them
+	PXEDir string `env:"PXE_DIR"`
+
+	// docker or podman, with the image to install from
+	ContainerRuntime string `env:"CONTAINER_RUNTIME"`
+	ContainerImage   string `env:"CONTAINER_IMAGE"`
+
+	// strace every install, "all" or an strace -e expression
+	Strace string `env:"STRACE"`
+	// capture every install's traffic with tcpdump
+	Pcap bool `env:"PCAP"`
+	// fail instead of skipping when tools or privileges are missing
+	RequireTools bool `env:"REQUIRE_TOOLS"`
+	// seed for injecting a random fault into each test, 0 disables it
+	Chaos int64 `env:"CHAOS"`
+	// rewrite golden transcripts instead of comparing against them
+	UpdateGolden bool `env:"UPDATE_GOLDEN"`
+	// another coreos-install to run each scenario with, reporting how it
+	// behaves differently from the one under test
+	CompareInstaller string `env:"COMPARE_INSTALLER"`
+
+	// qemu-system-x86_64 to boot installed disks with, boot tests are
+	// skipped without it
+	QEMU string `env:"QEMU"`
+	// UEFI firmware for booting with OVMF
+	OVMF string `env:"OVMF" default:"/usr/share/OVMF/OVMF_CODE.fd"`
+	// OVMF built with SMM and a variable store with Secure Boot keys
+	// enrolled, Secure Boot boots are skipped without them
+	OVMFSecureBoot     string `env:"OVMF_SECURE_BOOT"`
+	OVMFSecureBootVars string `env:"OVMF_SECURE_BOOT_VARS"`
+
+	// the parent of each test's TMPDIR
+	TempDir        string        `env:"TMPDIR" default:"/var/tmp"`
+	InstallTimeout time.Duration `env:"INSTALL_TIMEOUT" default:"30m"`
+	CommandTimeout time.Duration `env:"COMMAND_TIMEOUT" default:"5m"`
+	BootTimeout    time.Duration `env:"BOOT_TIMEOUT" default:"10m"`
+}
+
+// LoadConfig reads Config from the environment, applying defaults for
+// unset variables, and reports every invalid value at once
+func LoadConfig() (Config, error) {
+	var config Config
+	var problems []string
+
+	v := reflect.ValueOf(&config).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name := ConfigPrefix + field.Tag.Get("env")
+
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			value = field.Tag.Get("default")
+		}
+		if value == "" {
+			continue
+		}
+
+		if err := setConfigField(v.Field(i), value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s=%q: %v", name, value, err))
+		}
+	}
+
+	if len(problems) == 0 {
+		problems = config.validate()
+	}
+	if len(problems) != 0 {
+		return config, fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+	return config, nil
+}
+
+func setConfigField(field reflect.Value, value string) error {
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(value)
+	case bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+	case int64:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	default:
+		return fmt.Errorf("unsupported config type %v", field.Type())
+	}
+	return nil
+}
+
+func (c Config) validate() (problems []string) {
+	if c.InstallTimeout <= 0 {
+		problems = append(problems, ConfigPrefix+"INSTALL_TIMEOUT must be positive")
+	}
+	if c.CommandTimeout <= 0 {
+		problems = append(problems, ConfigPrefix+"COMMAND_TIMEOUT must be positive")
+	}
+	if c.BootTimeout <= 0 {
+		problems = append(problems, ConfigPrefix+"BOOT_TIMEOUT must be positive")
+	}
+
+	switch c.Implementation {
+	case "script", "go":
+	default:
+		problems = append(problems, ConfigPrefix+"IMPLEMENTATION must be script or go")
+	}
+
+	switch c.LeakedSecrets {
+	case "redact", "fail":
+	default:
+		problems = append(problems, ConfigPrefix+"LEAKED_SECRETS must be redact or fail")
+	}
+	if _, err := regexp.Compile(c.SecretPattern); err != nil {
+		problems = append(problems, ConfigPrefix+"SECRET_PATTERN: "+err.Error())
+	}
+
+	switch c.ContainerRuntime {
+	case "", "docker", "podman":
+	default:
+		problems = append(problems, ConfigPrefix+"CONTAINER_RUNTIME must be docker or podman")
+	}
+	if (c.ContainerRuntime == "") != (c.ContainerImage == "") {
+		problems = append(problems, ConfigPrefix+"CONTAINER_RUNTIME and "+ConfigPrefix+"CONTAINER_IMAGE must be set together")
+	}
+
+	if (c.OVMFSecureBoot == "") != (c.OVMFSecureBootVars == "") {
+		problems = append(problems, ConfigPrefix+"OVMF_SECURE_BOOT and "+ConfigPrefix+"OVMF_SECURE_BOOT_VARS must be set together")
+	}
+
+	if c.Strace != "" && c.ArtifactDir == "" {
+		problems = append(problems, ConfigPrefix+"STRACE requires "+ConfigPrefix+"ARTIFACT_DIR")
+	}
+	if c.Pcap && c.ArtifactDir == "" {
+		problems = append(problems, ConfigPrefix+"PCAP requires "+ConfigPrefix+"ARTIFACT_DIR")
+	}
+	return
+}
@@ -0,0 +1,151 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+type Compression string
+
+const (
+	Uncompressed Compression = ""
+	Bzip2        Compression = "bzip2"
+	Gzip         Compression = "gzip"
+	Zstd         Compression = "zstd"
+)
+
+var compressionMagic = []struct {
+	format Compression
+	magic  []byte
+}{
+	{Bzip2, []byte("BZh")},
+	{Gzip, []byte{0x1f, 0x8b}},
+	{Zstd, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+}
+
+// DetectCompression identifies a format from the first bytes of a stream
+func DetectCompression(header []byte) Compression {
+	for _, m := range compressionMagic {
+		if bytes.HasPrefix(header, m.magic) {
+			return m.format
+		}
+	}
+	return Uncompressed
+}
+
+// Decompress streams r decompressed. bzip2 and gzip are decompressed in Go,
+// zstd is piped through the zstd command since the standard library can't.
+func Decompress(r io.Reader, format Compression) (io.ReadCloser, error) {
+	switch format {
+	case Uncompressed:
+		return ioutil.NopCloser(r), nil
+	case Bzip2:
+		return ioutil.NopCloser(bzip2.NewReader(r)), nil
+	case Gzip:
+		return gzip.NewReader(r)
+	case Zstd:
+		return zstdReader(r)
+	}
+	return nil, fmt.Errorf("unknown compression %q", format)
+}
+
+type commandReader struct {
+	io.ReadCloser
+	cancel func()
+	wait   func() error
+}
+
+func (c *commandReader) Close() error {
+	c.ReadCloser.Close()
+	c.cancel()
+	c.wait()
+	return nil
+}
+
+func zstdReader(r io.Reader) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := Command(ctx, "zstd", "-dc")
+	cmd.Stdin = r
+
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("couldn't start zstd: %v", err)
+	}
+	return &commandReader{ReadCloser: out, cancel: cancel, wait: cmd.Wait}, nil
+}
+
+type fileReader struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (f *fileReader) Close() error {
+	f.ReadCloser.Close()
+	return f.file.Close()
+}
+
+// OpenDecompressed opens a file of any supported format, detected from its
+// contents rather than its name
+func OpenDecompressed(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	buffered := bufio.NewReader(f)
+	header, _ := buffered.Peek(4)
+	r, err := Decompress(buffered, DetectCompression(header))
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("couldn't decompress %s: %v", path, err)
+	}
+	return &fileReader{ReadCloser: r, file: f}, nil
+}
+
+// ReadDecompressedRange returns length bytes at offset into the
+// decompressed contents of path. Everything before offset is decompressed
+// and discarded, nothing after the range is decompressed.
+func ReadDecompressedRange(path string, offset, length int64) ([]byte, error) {
+	r, err := OpenDecompressed(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	if _, err := io.CopyN(ioutil.Discard, r, offset); err != nil {
+		return nil, fmt.Errorf("couldn't skip to %d in %s: %v", offset, path, err)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("couldn't read %d bytes at %d in %s: %v", length, offset, path, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DiskStats are a block device's I/O counters from /proc/diskstats
+type DiskStats struct {
+	Reads          uint64
+	ReadSectors    uint64
+	Writes         uint64
+	WrittenSectors uint64
+}
+
+// diskstats counts in 512 byte sectors whatever the device's sector size
+const diskstatsSector = 512
+
+func (s DiskStats) BytesRead() uint64 {
+	return s.ReadSectors * diskstatsSector
+}
+
+func (s DiskStats) BytesWritten() uint64 {
+	return s.WrittenSectors * diskstatsSector
+}
+
+// Sub returns the I/O between before and s
+func (s DiskStats) Sub(before DiskStats) DiskStats {
+	return DiskStats{
+		Reads:          s.Reads - before.Reads,
+		ReadSectors:    s.ReadSectors - before.ReadSectors,
+		Writes:         s.Writes - before.Writes,
+		WrittenSectors: s.WrittenSectors - before.WrittenSectors,
+	}
+}
+
+// ReadDiskStats reads the counters for device, e.g. /dev/loop0 or a
+// /dev/mapper symlink
+func ReadDiskStats(device string) (DiskStats, error) {
+	resolved, err := filepath.EvalSymlinks(device)
+	if err != nil {
+		return DiskStats{}, err
+	}
+	name := filepath.Base(resolved)
+
+	f, err := os.Open("/proc/diskstats")
+	if err != nil {
+		return DiskStats{}, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// major minor name reads merged sectors ms writes merged sectors ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[2] != name {
+			continue
+		}
+
+		var values [4]uint64
+		for i, field := range []string{fields[3], fields[5], fields[7], fields[9]} {
+			if values[i], err = strconv.ParseUint(field, 10, 64); err != nil {
+				return DiskStats{}, fmt.Errorf("couldn't parse diskstats for %s: %v", name, err)
+			}
+		}
+		return DiskStats{Reads: values[0], ReadSectors: values[1], Writes: values[2], WrittenSectors: values[3]}, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return DiskStats{}, err
+	}
+	return DiskStats{}, fmt.Errorf("%s isn't in /proc/diskstats", name)
+}
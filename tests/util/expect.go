@@ -0,0 +1,141 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Expecter is written a command's output and waits for patterns in it like
+// expect(1). Each match consumes the output up to its end, so a sequence of
+// Expects must match in order.
+type Expecter struct {
+	mu      sync.Mutex
+	output  []byte
+	offset  int
+	closed  bool
+	written time.Time
+	// closed and replaced whenever output is written or the Expecter closes
+	changed chan struct{}
+}
+
+func NewExpecter() *Expecter {
+	return &Expecter{
+		written: time.Now(),
+		changed: make(chan struct{}),
+	}
+}
+
+func (e *Expecter) Write(p []byte) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.output = append(e.output, p...)
+	e.written = time.Now()
+	e.notify()
+	return len(p), nil
+}
+
+// Close marks the end of the output, pending and later Expects that don't
+// match what was written fail with io.EOF
+func (e *Expecter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.closed {
+		e.closed = true
+		e.notify()
+	}
+	return nil
+}
+
+func (e *Expecter) notify() {
+	close(e.changed)
+	e.changed = make(chan struct{})
+}
+
+// Expect waits up to timeout for pattern and returns its submatches
+func (e *Expecter) Expect(timeout time.Duration, pattern *regexp.Regexp) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return e.ExpectContext(ctx, pattern)
+}
+
+func (e *Expecter) ExpectContext(ctx context.Context, pattern *regexp.Regexp) ([]string, error) {
+	return e.wait(ctx, pattern, true)
+}
+
+// Seen waits for pattern anywhere in the output, including what earlier
+// Expects consumed, without consuming anything itself. It's for messages
+// whose order relative to the rest isn't fixed.
+func (e *Expecter) Seen(ctx context.Context, pattern *regexp.Regexp) ([]string, error) {
+	return e.wait(ctx, pattern, false)
+}
+
+func (e *Expecter) wait(ctx context.Context, pattern *regexp.Regexp, consume bool) ([]string, error) {
+	for {
+		e.mu.Lock()
+		start := 0
+		if consume {
+			start = e.offset
+		}
+		loc := pattern.FindSubmatchIndex(e.output[start:])
+		if loc != nil {
+			matches := make([]string, len(loc)/2)
+			for i := range matches {
+				if loc[2*i] >= 0 {
+					matches[i] = string(e.output[start+loc[2*i] : start+loc[2*i+1]])
+				}
+			}
+			if consume {
+				e.offset += loc[1]
+			}
+			e.mu.Unlock()
+			return matches, nil
+		}
+		closed, changed := e.closed, e.changed
+		e.mu.Unlock()
+
+		if closed {
+			return nil, fmt.Errorf("output ended before %q: %w", pattern, io.EOF)
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for %q: %w", pattern, ctx.Err())
+		}
+	}
+}
+
+// Idle is how long it's been since output was last written, for watchdogs
+// that kill a command that stopped making progress
+func (e *Expecter) Idle() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return time.Since(e.written)
+}
+
+// Output is everything written so far
+func (e *Expecter) Output() []byte {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]byte(nil), e.output...)
+}
@@ -0,0 +1,178 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"unicode/utf16"
+)
+
+// GPTSectorSize is the logical sector size the GPT is read with, loop
+// devices default to 512
+const GPTSectorSize = 512
+
+// GPTHeader is the part of a GPT header needed to find the partitions
+type GPTHeader struct {
+	CurrentLBA     uint64
+	BackupLBA      uint64
+	FirstUsableLBA uint64
+	LastUsableLBA  uint64
+	DiskGUID       string
+	EntriesLBA     uint64
+	NumEntries     uint32
+	EntrySize      uint32
+}
+
+// GPTPartition is one used entry of the partition array
+type GPTPartition struct {
+	// 1 based, like sgdisk and the kernel number them
+	Number     int
+	TypeGUID   string
+	GUID       string
+	FirstLBA   uint64
+	LastLBA    uint64
+	Attributes uint64
+	Name       string
+}
+
+// GPT is a disk's partition table
+type GPT struct {
+	Header     GPTHeader
+	Partitions []GPTPartition
+}
+
+// ReadGPT reads the partition table of a disk file or block device, from
+// the primary header or from the backup if the primary is damaged
+func ReadGPT(path string) (*GPT, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gpt, primaryErr := readGPTAt(f, 1)
+	if primaryErr == nil {
+		return gpt, nil
+	}
+
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	gpt, err = readGPTAt(f, uint64(size/GPTSectorSize-1))
+	if err != nil {
+		return nil, fmt.Errorf("no valid GPT on %s: primary: %v, backup: %v", path, primaryErr, err)
+	}
+	return gpt, nil
+}
+
+func readGPTAt(r io.ReaderAt, lba uint64) (*GPT, error) {
+	sector := make([]byte, GPTSectorSize)
+	if _, err := r.ReadAt(sector, int64(lba*GPTSectorSize)); err != nil {
+		return nil, fmt.Errorf("couldn't read header at LBA %d: %v", lba, err)
+	}
+
+	if !bytes.Equal(sector[0:8], []byte("EFI PART")) {
+		return nil, fmt.Errorf("no GPT signature at LBA %d", lba)
+	}
+
+	le := binary.LittleEndian
+	headerSize := le.Uint32(sector[12:16])
+	if headerSize < 92 || headerSize > GPTSectorSize {
+		return nil, fmt.Errorf("bad header size %d at LBA %d", headerSize, lba)
+	}
+
+	header := append([]byte(nil), sector[:headerSize]...)
+	expectedCRC := le.Uint32(header[16:20])
+	copy(header[16:20], []byte{0, 0, 0, 0})
+	if crc := crc32.ChecksumIEEE(header); crc != expectedCRC {
+		return nil, fmt.Errorf("header CRC at LBA %d is %08x, expected %08x", lba, crc, expectedCRC)
+	}
+
+	gpt := &GPT{Header: GPTHeader{
+		CurrentLBA:     le.Uint64(sector[24:32]),
+		BackupLBA:      le.Uint64(sector[32:40]),
+		FirstUsableLBA: le.Uint64(sector[40:48]),
+		LastUsableLBA:  le.Uint64(sector[48:56]),
+		DiskGUID:       FormatGUID(sector[56:72]),
+		EntriesLBA:     le.Uint64(sector[72:80]),
+		NumEntries:     le.Uint32(sector[80:84]),
+		EntrySize:      le.Uint32(sector[84:88]),
+	}}
+
+	if gpt.Header.EntrySize < 128 || gpt.Header.NumEntries > 1024 {
+		return nil, fmt.Errorf("unsupported partition array of %d %d byte entries", gpt.Header.NumEntries, gpt.Header.EntrySize)
+	}
+
+	entries := make([]byte, int(gpt.Header.NumEntries)*int(gpt.Header.EntrySize))
+	if _, err := r.ReadAt(entries, int64(gpt.Header.EntriesLBA*GPTSectorSize)); err != nil {
+		return nil, fmt.Errorf("couldn't read partition entries at LBA %d: %v", gpt.Header.EntriesLBA, err)
+	}
+	if crc := crc32.ChecksumIEEE(entries); crc != le.Uint32(sector[88:92]) {
+		return nil, fmt.Errorf("partition entries CRC is %08x, expected %08x", crc, le.Uint32(sector[88:92]))
+	}
+
+	for i := 0; i < int(gpt.Header.NumEntries); i++ {
+		entry := entries[i*int(gpt.Header.EntrySize):][:128]
+		if bytes.Equal(entry[0:16], make([]byte, 16)) {
+			continue
+		}
+
+		gpt.Partitions = append(gpt.Partitions, GPTPartition{
+			Number:     i + 1,
+			TypeGUID:   FormatGUID(entry[0:16]),
+			GUID:       FormatGUID(entry[16:32]),
+			FirstLBA:   le.Uint64(entry[32:40]),
+			LastLBA:    le.Uint64(entry[40:48]),
+			Attributes: le.Uint64(entry[48:56]),
+			Name:       decodeUTF16(entry[56:128]),
+		})
+	}
+	return gpt, nil
+}
+
+// FormatGUID formats a GUID as stored on disk, with its first three fields
+// little endian, in the uppercase form sgdisk prints
+func FormatGUID(b []byte) string {
+	le := binary.LittleEndian
+	return fmt.Sprintf("%08X-%04X-%04X-%X-%X", le.Uint32(b[0:4]), le.Uint16(b[4:6]), le.Uint16(b[6:8]), b[8:10], b[10:16])
+}
+
+func decodeUTF16(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u := binary.LittleEndian.Uint16(b[i:])
+		if u == 0 {
+			break
+		}
+		units = append(units, u)
+	}
+	return string(utf16.Decode(units))
+}
+
+// ReadImageGPT reads the primary partition table of a disk image that may
+// be compressed, without decompressing more than the start of it
+func ReadImageGPT(path string) (*GPT, error) {
+	head, err := ReadDecompressedRange(path, 0, 1024*1024)
+	if err != nil {
+		return nil, err
+	}
+	return readGPTAt(bytes.NewReader(head), 1)
+}
@@ -0,0 +1,191 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+const (
+	testDiskSectors = 4096
+	testEntries     = 128
+	// the 128 entries of 128 bytes
+	testEntrySectors = 32
+)
+
+var testPartitions = []GPTPartition{
+	{Number: 1, TypeGUID: "C12A7328-F81F-11D2-BA4B-00A0C93EC93B", GUID: "0A0B0C0D-0E0F-1011-1213-141516171819", FirstLBA: 2048, LastLBA: 2559, Name: "EFI-SYSTEM"},
+	{Number: 9, TypeGUID: "3884DD41-8582-4404-B9A8-E9B84F2DF50E", GUID: "1A1B1C1D-1E1F-2021-2223-242526272829", FirstLBA: 2560, LastLBA: 4000, Attributes: 1 << 48, Name: "ROOT"},
+}
+
+// parseGUID is FormatGUID in reverse
+func parseGUID(t *testing.T, guid string) []byte {
+	hex := strings.Replace(guid, "-", "", -1)
+	b := make([]byte, 16)
+	for i := range b {
+		var v byte
+		for _, c := range hex[2*i : 2*i+2] {
+			v <<= 4
+			switch {
+			case c >= '0' && c <= '9':
+				v |= byte(c - '0')
+			default:
+				v |= byte(c - 'A' + 10)
+			}
+		}
+		b[i] = v
+	}
+	// the first three fields are little endian on disk
+	b[0], b[1], b[2], b[3] = b[3], b[2], b[1], b[0]
+	b[4], b[5] = b[5], b[4]
+	b[6], b[7] = b[7], b[6]
+	return b
+}
+
+// writeTestGPT writes a disk with testPartitions in its primary and backup
+// tables
+func writeTestGPT(t *testing.T) string {
+	le := binary.LittleEndian
+	disk := make([]byte, testDiskSectors*GPTSectorSize)
+
+	entries := make([]byte, testEntries*128)
+	for _, p := range testPartitions {
+		entry := entries[(p.Number-1)*128:]
+		copy(entry[0:16], parseGUID(t, p.TypeGUID))
+		copy(entry[16:32], parseGUID(t, p.GUID))
+		le.PutUint64(entry[32:40], p.FirstLBA)
+		le.PutUint64(entry[40:48], p.LastLBA)
+		le.PutUint64(entry[48:56], p.Attributes)
+		for i, u := range utf16.Encode([]rune(p.Name)) {
+			le.PutUint16(entry[56+2*i:], u)
+		}
+	}
+
+	last := uint64(testDiskSectors - 1)
+	header := func(current, backup, entriesLBA uint64) []byte {
+		h := make([]byte, 92)
+		copy(h[0:8], "EFI PART")
+		le.PutUint32(h[8:12], 0x00010000)
+		le.PutUint32(h[12:16], 92)
+		le.PutUint64(h[24:32], current)
+		le.PutUint64(h[32:40], backup)
+		le.PutUint64(h[40:48], 2+testEntrySectors)
+		le.PutUint64(h[48:56], last-1-testEntrySectors)
+		copy(h[56:72], parseGUID(t, "00112233-4455-6677-8899-AABBCCDDEEFF"))
+		le.PutUint64(h[72:80], entriesLBA)
+		le.PutUint32(h[80:84], testEntries)
+		le.PutUint32(h[84:88], 128)
+		le.PutUint32(h[88:92], crc32.ChecksumIEEE(entries))
+		le.PutUint32(h[16:20], crc32.ChecksumIEEE(h))
+		return h
+	}
+
+	copy(disk[1*GPTSectorSize:], header(1, last, 2))
+	copy(disk[2*GPTSectorSize:], entries)
+	copy(disk[(last-testEntrySectors)*GPTSectorSize:], entries)
+	copy(disk[last*GPTSectorSize:], header(last, 1, last-testEntrySectors))
+
+	path := filepath.Join(t.TempDir(), "disk")
+	if err := os.WriteFile(path, disk, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// corruptSector flips a byte in a sector of the disk
+func corruptSector(t *testing.T, path string, lba int64) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	b := make([]byte, 1)
+	offset := lba*GPTSectorSize + 20
+	if _, err := f.ReadAt(b, offset); err != nil {
+		t.Fatal(err)
+	}
+	b[0] ^= 0xff
+	if _, err := f.WriteAt(b, offset); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadGPT(t *testing.T) {
+	gpt, err := ReadGPT(writeTestGPT(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if gpt.Header.CurrentLBA != 1 || gpt.Header.BackupLBA != testDiskSectors-1 {
+		t.Errorf("read header at LBA %d with backup at %d", gpt.Header.CurrentLBA, gpt.Header.BackupLBA)
+	}
+	if gpt.Header.DiskGUID != "00112233-4455-6677-8899-AABBCCDDEEFF" {
+		t.Errorf("disk GUID is %s", gpt.Header.DiskGUID)
+	}
+	if !reflect.DeepEqual(gpt.Partitions, testPartitions) {
+		t.Errorf("read partitions %+v, expected %+v", gpt.Partitions, testPartitions)
+	}
+}
+
+func TestReadGPTBackup(t *testing.T) {
+	path := writeTestGPT(t)
+	corruptSector(t, path, 1)
+
+	gpt, err := ReadGPT(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gpt.Header.CurrentLBA != testDiskSectors-1 {
+		t.Errorf("read header at LBA %d, expected the backup", gpt.Header.CurrentLBA)
+	}
+	if !reflect.DeepEqual(gpt.Partitions, testPartitions) {
+		t.Errorf("read partitions %+v, expected %+v", gpt.Partitions, testPartitions)
+	}
+}
+
+func TestReadGPTCorrupt(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		sectors []int64
+	}{
+		{"both headers", []int64{1, testDiskSectors - 1}},
+		// the entries are shared by both headers' CRCs
+		{"entries", []int64{2, testDiskSectors - 1 - testEntrySectors}},
+	} {
+		path := writeTestGPT(t)
+		for _, lba := range c.sectors {
+			corruptSector(t, path, lba)
+		}
+		if _, err := ReadGPT(path); err == nil {
+			t.Errorf("%s: expected an error", c.name)
+		}
+	}
+}
+
+func TestFormatGUID(t *testing.T) {
+	// the EFI system partition type as it's stored on disk
+	b := []byte{0x28, 0x73, 0x2a, 0xc1, 0x1f, 0xf8, 0xd2, 0x11, 0xba, 0x4b, 0x00, 0xa0, 0xc9, 0x3e, 0xc9, 0x3b}
+	if guid := FormatGUID(b); guid != "C12A7328-F81F-11D2-BA4B-00A0C93EC93B" {
+		t.Errorf("formatted %s", guid)
+	}
+}
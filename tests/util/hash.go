@@ -0,0 +1,102 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+)
+
+type HashAlgorithm string
+
+const (
+	SHA256 HashAlgorithm = "sha256"
+	SHA512 HashAlgorithm = "sha512"
+)
+
+func (a HashAlgorithm) new() (hash.Hash, error) {
+	switch a {
+	case SHA256:
+		return sha256.New(), nil
+	case SHA512:
+		return sha512.New(), nil
+	}
+	return nil, fmt.Errorf("unknown hash algorithm %q", a)
+}
+
+// ProgressFunc is told how many bytes of total have been hashed so far
+type ProgressFunc func(done, total int64)
+
+// ProgressInterval is how many bytes are hashed between progress reports
+const ProgressInterval = 256 * 1024 * 1024
+
+// HashReader hashes everything read from r, reporting progress towards
+// total if progress isn't nil
+func HashReader(r io.Reader, alg HashAlgorithm, total int64, progress ProgressFunc) (string, error) {
+	h, err := alg.new()
+	if err != nil {
+		return "", err
+	}
+
+	var done int64
+	for {
+		n, err := io.CopyN(h, r, ProgressInterval)
+		done += n
+		if progress != nil && n != 0 {
+			progress(done, total)
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// HashFile hashes a whole file
+func HashFile(path string, alg HashAlgorithm) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return HashReader(f, alg, 0, nil)
+}
+
+// HashRange hashes length bytes starting at offset of a file or block
+// device, e.g. a single partition of a disk image
+func HashRange(path string, offset, length int64, alg HashAlgorithm, progress ProgressFunc) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	section := io.NewSectionReader(f, offset, length)
+	sum, err := HashReader(section, alg, length, progress)
+	if err != nil {
+		return "", fmt.Errorf("couldn't hash %d bytes at %d of %s: %v", length, offset, path, err)
+	}
+	return sum, nil
+}
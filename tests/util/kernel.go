@@ -0,0 +1,100 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// KernelModule reports whether a module is loaded, built in, or installed
+// where the kernel can load it on demand. name is as in /sys/module, with
+// underscores.
+func KernelModule(name string) bool {
+	if _, err := os.Stat(filepath.Join("/sys/module", name)); err == nil {
+		return true
+	}
+
+	release, err := ioutil.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return false
+	}
+	dir := filepath.Join("/lib/modules", strings.TrimSpace(string(release)))
+	return listsModule(filepath.Join(dir, "modules.builtin"), name) ||
+		listsModule(filepath.Join(dir, "modules.dep"), name)
+}
+
+// listsModule searches modules.builtin or modules.dep, whose lines start
+// with a path like kernel/drivers/md/dm-delay.ko.xz
+func listsModule(path, name string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		module := strings.SplitN(scanner.Text(), ":", 2)[0]
+		module = filepath.Base(module)
+		if i := strings.Index(module, ".ko"); i >= 0 {
+			module = module[:i]
+		}
+		if strings.Replace(module, "-", "_", -1) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// KernelFilesystem reports whether the kernel can mount fstype, either
+// already registered in /proc/filesystems or as a loadable module
+func KernelFilesystem(fstype string) bool {
+	if data, err := ioutil.ReadFile("/proc/filesystems"); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 0 && fields[len(fields)-1] == fstype {
+				return true
+			}
+		}
+	}
+	return KernelModule(fstype)
+}
+
+// probeLoopPartscan attaches a scratch file with LoopPartscan and checks
+// the kernel kept the flag, old kernels and some minimal ones drop it
+func probeLoopPartscan() bool {
+	f, err := ioutil.TempFile("", "coreos-install-probe")
+	if err != nil {
+		return false
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if err := f.Truncate(1024 * 1024); err != nil {
+		return false
+	}
+
+	device, err := AttachLoop(f.Name(), LoopPartscan|LoopReadOnly)
+	if err != nil {
+		return false
+	}
+	defer DetachLoop(device)
+
+	data, err := ioutil.ReadFile(filepath.Join("/sys/block", filepath.Base(device), "loop", "partscan"))
+	return err == nil && strings.TrimSpace(string(data)) == "1"
+}
@@ -0,0 +1,40 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestListsModule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "modules.dep")
+	dep := "kernel/drivers/md/dm-delay.ko.xz: kernel/drivers/md/dm-mod.ko.xz\nkernel/fs/fat/vfat.ko: kernel/fs/fat/fat.ko\n"
+	if err := ioutil.WriteFile(path, []byte(dep), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, listed := range map[string]bool{
+		"dm_delay":  true,
+		"vfat":      true,
+		"fat":       false,
+		"dm_flakey": false,
+	} {
+		if listsModule(path, name) != listed {
+			t.Errorf("listsModule(%q) = %v, expected %v", name, !listed, listed)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// from linux/loop.h and linux/fs.h
+const (
+	loopSetFD       = 0x4C00
+	loopClrFD       = 0x4C01
+	loopSetStatus64 = 0x4C04
+	loopCtlAdd      = 0x4C80
+	loopCtlGetFree  = 0x4C82
+	blkRRPart       = 0x125F
+)
+
+type LoopFlags uint32
+
+const (
+	LoopReadOnly LoopFlags = 1
+	// the device detaches itself once nothing has it open
+	LoopAutoclear LoopFlags = 4
+	// the kernel creates pN nodes for the partitions on the device
+	LoopPartscan LoopFlags = 8
+)
+
+// struct loop_info64
+type loopInfo64 struct {
+	device         uint64
+	inode          uint64
+	rdevice        uint64
+	offset         uint64
+	sizelimit      uint64
+	number         uint32
+	encryptType    uint32
+	encryptKeySize uint32
+	flags          uint32
+	fileName       [64]byte
+	cryptName      [64]byte
+	encryptKey     [32]byte
+	init           [2]uint64
+}
+
+// LoopError is a failed loop device ioctl, Errno says why
+type LoopError struct {
+	Op     string
+	Device string
+	Errno  syscall.Errno
+}
+
+func (e *LoopError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Op, e.Device, e.Errno)
+}
+
+func ioctl(f *os.File, req, arg uintptr) (uintptr, syscall.Errno) {
+	r, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), req, arg)
+	return r, errno
+}
+
+// AttachLoop backs a free loop device with the file at path and returns the
+// device. Another process can take the free device first, that's an EBUSY
+// LoopError and worth retrying.
+func AttachLoop(path string, flags LoopFlags) (string, error) {
+	ctl, err := os.OpenFile("/dev/loop-control", os.O_RDWR, 0)
+	if err != nil {
+		return "", err
+	}
+	defer ctl.Close()
+
+	n, errno := ioctl(ctl, loopCtlGetFree, 0)
+	if errno != 0 {
+		return "", &LoopError{Op: "LOOP_CTL_GET_FREE", Device: ctl.Name(), Errno: errno}
+	}
+	return attachLoop(path, flags, int(n))
+}
+
+// AttachLoopAt is AttachLoop onto /dev/loop<n>, which is created if it
+// doesn't exist. It's an EBUSY LoopError if the device is in use.
+func AttachLoopAt(path string, flags LoopFlags, n int) (string, error) {
+	ctl, err := os.OpenFile("/dev/loop-control", os.O_RDWR, 0)
+	if err != nil {
+		return "", err
+	}
+	defer ctl.Close()
+
+	if _, errno := ioctl(ctl, loopCtlAdd, uintptr(n)); errno != 0 && errno != syscall.EEXIST {
+		return "", &LoopError{Op: "LOOP_CTL_ADD", Device: ctl.Name(), Errno: errno}
+	}
+	return attachLoop(path, flags, n)
+}
+
+func attachLoop(path string, flags LoopFlags, n int) (string, error) {
+	mode := os.O_RDWR
+	if flags&LoopReadOnly != 0 {
+		mode = os.O_RDONLY
+	}
+
+	backing, err := os.OpenFile(path, mode, 0)
+	if err != nil {
+		return "", err
+	}
+	defer backing.Close()
+
+	device := fmt.Sprintf("/dev/loop%d", n)
+	loop, err := os.OpenFile(device, mode, 0)
+	if err != nil {
+		return "", err
+	}
+	defer loop.Close()
+
+	if _, errno := ioctl(loop, loopSetFD, backing.Fd()); errno != 0 {
+		return "", &LoopError{Op: "LOOP_SET_FD", Device: device, Errno: errno}
+	}
+
+	info := loopInfo64{flags: uint32(flags)}
+	copy(info.fileName[:len(info.fileName)-1], path)
+	if _, errno := ioctl(loop, loopSetStatus64, uintptr(unsafe.Pointer(&info))); errno != 0 {
+		ioctl(loop, loopClrFD, 0)
+		return "", &LoopError{Op: "LOOP_SET_STATUS64", Device: device, Errno: errno}
+	}
+	return device, nil
+}
+
+// DetachLoop frees a loop device, it's EBUSY while a partition on it is
+// still mounted or mapped
+func DetachLoop(device string) error {
+	loop, err := os.OpenFile(device, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer loop.Close()
+
+	if _, errno := ioctl(loop, loopClrFD, 0); errno != 0 {
+		return &LoopError{Op: "LOOP_CLR_FD", Device: device, Errno: errno}
+	}
+	return nil
+}
+
+// RescanPartitions makes the kernel reread the partition table of a device
+// attached with LoopPartscan
+func RescanPartitions(device string) error {
+	f, err := os.OpenFile(device, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, errno := ioctl(f, blkRRPart, 0); errno != 0 {
+		return &LoopError{Op: "BLKRRPART", Device: device, Errno: errno}
+	}
+	return nil
+}
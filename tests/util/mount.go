@@ -0,0 +1,118 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// MountError is a failed mount(2) or umount(2), Errno says why
+type MountError struct {
+	Op     string
+	Source string
+	Target string
+	Errno  syscall.Errno
+}
+
+func (e *MountError) Error() string {
+	if e.Source != "" {
+		return fmt.Sprintf("%s %s on %s: %v", e.Op, e.Source, e.Target, e.Errno)
+	}
+	return fmt.Sprintf("%s %s: %v", e.Op, e.Target, e.Errno)
+}
+
+// Mount mounts source on target. If fstype is empty every filesystem the
+// kernel supports is tried like mount(8) does, falling back to the mount
+// command if the list can't be read.
+func Mount(source, target, fstype string, flags uintptr, data string) error {
+	if fstype != "" {
+		return mount(source, target, fstype, flags, data)
+	}
+
+	fstypes, err := blockFilesystems()
+	if err != nil {
+		return mountCommand(source, target, flags, data)
+	}
+
+	for _, fs := range fstypes {
+		err := mount(source, target, fs, flags, data)
+		if err == nil {
+			return nil
+		}
+		// EINVAL is the kernel saying the superblock isn't this filesystem
+		if e, ok := err.(*MountError); !ok || e.Errno != syscall.EINVAL {
+			return err
+		}
+	}
+	return &MountError{Op: "mount", Source: source, Target: target, Errno: syscall.EINVAL}
+}
+
+func mount(source, target, fstype string, flags uintptr, data string) error {
+	if err := syscall.Mount(source, target, fstype, flags, data); err != nil {
+		return &MountError{Op: "mount", Source: source, Target: target, Errno: err.(syscall.Errno)}
+	}
+	return nil
+}
+
+// blockFilesystems lists the filesystems in /proc/filesystems that live on
+// a device
+func blockFilesystems() ([]string, error) {
+	f, err := os.Open("/proc/filesystems")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var fstypes []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 1 {
+			fstypes = append(fstypes, fields[0])
+		}
+	}
+	return fstypes, scanner.Err()
+}
+
+func mountCommand(source, target string, flags uintptr, data string) error {
+	opts := data
+	if flags&syscall.MS_RDONLY != 0 {
+		opts = strings.TrimSuffix("ro,"+opts, ",")
+	}
+
+	args := []string{source, target}
+	if opts != "" {
+		args = append(args, "-o", opts)
+	}
+	_, err := RunE(context.Background(), "mount", args...)
+	return err
+}
+
+// Unmount unmounts target, lazily detaching it if it's busy and lazy is set
+func Unmount(target string, lazy bool) error {
+	err := syscall.Unmount(target, 0)
+	if err == syscall.EBUSY && lazy {
+		err = syscall.Unmount(target, syscall.MNT_DETACH)
+	}
+	if err != nil {
+		return &MountError{Op: "umount", Target: target, Errno: err.(syscall.Errno)}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// from linux/capability.h
+const CapSysAdmin = 21
+
+const loopControl = "/dev/loop-control"
+
+// RequireRoot checks the suite can create loop devices and mount them:
+// running as root, with CAP_SYS_ADMIN, and with access to loop-control.
+// Like RequireTools it skips unless PreflightFatal is set.
+func RequireRoot(t *testing.T) {
+	var problems []string
+	if os.Geteuid() != 0 {
+		problems = append(problems, "not running as root, run the tests with sudo -E")
+	}
+
+	if ok, err := HasCapability(CapSysAdmin); err != nil {
+		problems = append(problems, fmt.Sprintf("couldn't read capabilities: %v", err))
+	} else if !ok {
+		problems = append(problems, "CAP_SYS_ADMIN is required to mount, run containers with --privileged")
+	}
+
+	if f, err := os.OpenFile(loopControl, os.O_RDWR, 0); err != nil {
+		problems = append(problems, fmt.Sprintf("can't open %s to create loop devices: %v", loopControl, err))
+	} else {
+		f.Close()
+	}
+
+	if len(problems) == 0 {
+		return
+	}
+
+	msg := "insufficient privileges: " + strings.Join(problems, "; ")
+	if PreflightFatal {
+		t.Fatal(msg)
+	}
+	t.Skip(msg)
+}
+
+// HasCapability reports whether the capability is in this process's
+// effective set
+func HasCapability(capability uint) (bool, error) {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "CapEff:") {
+			continue
+		}
+
+		caps, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "CapEff:")), 16, 64)
+		if err != nil {
+			return false, err
+		}
+		return caps&(1<<capability) != 0, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return false, err
+	}
+	return false, fmt.Errorf("no CapEff in /proc/self/status")
+}
@@ -0,0 +1,253 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// HostReport describes what the host running the suite can do
+type HostReport struct {
+	Root        bool
+	CapSysAdmin bool
+	// /dev/loop-control can be opened to allocate loop devices
+	LoopControl bool
+	// the loop driver is loaded or built in
+	LoopModule bool
+	// the loop driver's max_loop, 0 means devices are created on demand
+	MaxLoop int
+	// the kernel scans the partition tables of loop devices, only probed
+	// as root
+	LoopPartscan bool
+	// device mapper, which kpartx needs, and the targets tests layer over
+	// their disks
+	DeviceMapper bool
+	DMDelay      bool
+	DMFlakey     bool
+	// the kernel can mount the EFI system partition
+	VFAT bool
+
+	Kpartx bool
+	// udev is running and creates device nodes
+	Udev bool
+	// "v1", "v2" or "" if no cgroup filesystem is mounted
+	Cgroup string
+	// the container runtime the suite is running in, "" on a host
+	Container string
+}
+
+// HostCapability is something a test needs from the host, see RequireHost
+type HostCapability string
+
+const (
+	HostRoot           HostCapability = "root"
+	HostLoopDevices    HostCapability = "loop devices"
+	HostLoopPartscan   HostCapability = "loop partscan"
+	HostDeviceMapper   HostCapability = "device mapper"
+	HostDMDelay        HostCapability = "dm-delay"
+	HostDMFlakey       HostCapability = "dm-flakey"
+	HostVFAT           HostCapability = "vfat"
+	HostKpartx         HostCapability = "kpartx"
+	HostUdev           HostCapability = "udev"
+	HostCgroupV2       HostCapability = "cgroup v2"
+	HostNotInContainer HostCapability = "not in a container"
+)
+
+// why tests need each capability, so a skip on a minimal CI kernel says
+// what to fix
+var hostCapabilityHelp = map[HostCapability]string{
+	HostLoopDevices:  "the loop module and /dev/loop-control back the test disks",
+	HostLoopPartscan: "the loop driver must scan partition tables (linux 3.2+) for the installer to reread them",
+	HostDeviceMapper: "dm_mod is needed by kpartx to map the installed partitions",
+	HostDMDelay:      "dm_delay slows down the test disk",
+	HostDMFlakey:     "dm_flakey fails I/O to the test disk",
+	HostVFAT:         "vfat is needed to mount the EFI system partition",
+}
+
+// Has reports whether the host provides the capability
+func (r HostReport) Has(c HostCapability) bool {
+	switch c {
+	case HostRoot:
+		return r.Root && r.CapSysAdmin
+	case HostLoopDevices:
+		return r.LoopModule && r.LoopControl
+	case HostLoopPartscan:
+		return r.LoopPartscan
+	case HostDeviceMapper:
+		return r.DeviceMapper
+	case HostDMDelay:
+		return r.DeviceMapper && r.DMDelay
+	case HostDMFlakey:
+		return r.DeviceMapper && r.DMFlakey
+	case HostVFAT:
+		return r.VFAT
+	case HostKpartx:
+		return r.Kpartx
+	case HostUdev:
+		return r.Udev
+	case HostCgroupV2:
+		return r.Cgroup == "v2"
+	case HostNotInContainer:
+		return r.Container == ""
+	}
+	return false
+}
+
+// Missing lists the capabilities the host doesn't provide
+func (r HostReport) Missing(capabilities ...HostCapability) []HostCapability {
+	return Filter(capabilities, func(c HostCapability) bool {
+		return !r.Has(c)
+	})
+}
+
+func (r HostReport) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "root: %v\n", r.Root)
+	fmt.Fprintf(&b, "CAP_SYS_ADMIN: %v\n", r.CapSysAdmin)
+	fmt.Fprintf(&b, "loop module: %v\n", r.LoopModule)
+	fmt.Fprintf(&b, "loop-control: %v\n", r.LoopControl)
+	fmt.Fprintf(&b, "max loop devices: %s\n", orString(r.MaxLoop, "on demand"))
+	fmt.Fprintf(&b, "loop partscan: %v\n", r.LoopPartscan)
+	fmt.Fprintf(&b, "device mapper: %v\n", r.DeviceMapper)
+	fmt.Fprintf(&b, "dm-delay: %v\n", r.DMDelay)
+	fmt.Fprintf(&b, "dm-flakey: %v\n", r.DMFlakey)
+	fmt.Fprintf(&b, "vfat: %v\n", r.VFAT)
+	fmt.Fprintf(&b, "kpartx: %v\n", r.Kpartx)
+	fmt.Fprintf(&b, "udev: %v\n", r.Udev)
+	fmt.Fprintf(&b, "cgroup: %s\n", orString(r.Cgroup, "none"))
+	fmt.Fprintf(&b, "container: %s\n", orString(r.Container, "none"))
+	return b.String()
+}
+
+func orString[T comparable](v T, zero string) string {
+	var z T
+	if v == z {
+		return zero
+	}
+	return fmt.Sprint(v)
+}
+
+// ProbeHost inspects the host, it never fails and reports anything it
+// couldn't check as unavailable
+func ProbeHost() HostReport {
+	r := HostReport{
+		Root:      os.Geteuid() == 0,
+		Cgroup:    probeCgroup(),
+		Container: probeContainer(),
+	}
+
+	r.CapSysAdmin, _ = HasCapability(CapSysAdmin)
+
+	if f, err := os.OpenFile(loopControl, os.O_RDWR, 0); err == nil {
+		r.LoopControl = true
+		f.Close()
+	}
+
+	if _, err := os.Stat("/sys/module/loop"); err == nil {
+		r.LoopModule = true
+		if data, err := ioutil.ReadFile("/sys/module/loop/parameters/max_loop"); err == nil {
+			r.MaxLoop, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+		}
+	}
+	if r.Root && r.LoopControl {
+		r.LoopPartscan = probeLoopPartscan()
+	}
+
+	r.DeviceMapper = KernelModule("dm_mod")
+	r.DMDelay = KernelModule("dm_delay")
+	r.DMFlakey = KernelModule("dm_flakey")
+	r.VFAT = KernelFilesystem("vfat")
+
+	if _, err := exec.LookPath("kpartx"); err == nil {
+		r.Kpartx = true
+	}
+
+	if _, err := os.Stat("/run/udev/control"); err == nil {
+		r.Udev = true
+	}
+	return r
+}
+
+func probeCgroup() string {
+	if _, err := os.Stat("/sys/fs/cgroup/cgroup.controllers"); err == nil {
+		return "v2"
+	}
+	if entries, err := ioutil.ReadDir("/sys/fs/cgroup"); err == nil && len(entries) != 0 {
+		return "v1"
+	}
+	return ""
+}
+
+func probeContainer() string {
+	// set by systemd-nspawn, podman and lxc
+	if data, err := ioutil.ReadFile("/run/systemd/container"); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	if _, err := os.Stat("/run/.containerenv"); err == nil {
+		return "podman"
+	}
+	if _, err := os.Stat("/.dockerenv"); err == nil {
+		return "docker"
+	}
+	if data, err := ioutil.ReadFile("/proc/1/environ"); err == nil {
+		for _, kv := range bytes.Split(data, []byte{0}) {
+			if bytes.HasPrefix(kv, []byte("container=")) {
+				return string(bytes.TrimPrefix(kv, []byte("container=")))
+			}
+		}
+	}
+	return ""
+}
+
+var (
+	hostOnce   sync.Once
+	hostReport HostReport
+)
+
+// Host is ProbeHost, probed once for the whole suite
+func Host() HostReport {
+	hostOnce.Do(func() {
+		hostReport = ProbeHost()
+	})
+	return hostReport
+}
+
+// RequireHost skips the test if the host is missing any of the capabilities,
+// or fails it if PreflightFatal is set
+func RequireHost(t *testing.T, capabilities ...HostCapability) {
+	missing := Host().Missing(capabilities...)
+	if len(missing) == 0 {
+		return
+	}
+
+	msg := fmt.Sprintf("host is missing %v", missing)
+	for _, c := range missing {
+		if help, ok := hostCapabilityHelp[c]; ok {
+			msg += fmt.Sprintf("; %s: %s", c, help)
+		}
+	}
+	if PreflightFatal {
+		t.Fatal(msg)
+	}
+	t.Skip(msg)
+}
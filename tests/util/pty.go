@@ -0,0 +1,56 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// OpenPTY allocates a pseudo-terminal like posix_openpt, grantpt and
+// unlockpt would. The terminal doesn't turn \n into \r\n, so what's read
+// from master is byte for byte what was written to slave.
+func OpenPTY() (master, slave *os.File, err error) {
+	master, err = os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var unlock int32
+	if _, errno := ioctl(master, syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+		master.Close()
+		return nil, nil, fmt.Errorf("TIOCSPTLCK: %v", errno)
+	}
+	var n uint32
+	if _, errno := ioctl(master, syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); errno != 0 {
+		master.Close()
+		return nil, nil, fmt.Errorf("TIOCGPTN: %v", errno)
+	}
+
+	slave, err = os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+
+	var termios syscall.Termios
+	if _, errno := ioctl(slave, syscall.TCGETS, uintptr(unsafe.Pointer(&termios))); errno == 0 {
+		termios.Oflag &^= syscall.ONLCR
+		ioctl(slave, syscall.TCSETS, uintptr(unsafe.Pointer(&termios)))
+	}
+	return master, slave, nil
+}
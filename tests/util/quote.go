@@ -0,0 +1,119 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redaction replaces matches of Pattern, Replace can refer to submatches
+// like regexp.ReplaceAllString
+type Redaction struct {
+	Pattern *regexp.Regexp
+	Replace string
+}
+
+// Redactions are applied to every command before it's logged and to saved
+// artifacts, tests can add their own secrets with AddSecret
+var Redactions = []Redaction{
+	// basic-auth credentials in URLs, keeping the user
+	{regexp.MustCompile(`(\w+://[^/\s:@]+):[^/\s@]+@`), "${1}:REDACTED@"},
+	// tokens and passwords in query strings
+	{regexp.MustCompile(`(?i)([?&](?:access_token|token|password|passwd|secret|key)=)[^&\s]+`), "${1}REDACTED"},
+}
+
+var redactionsMu sync.RWMutex
+
+// AddSecret redacts secret wherever it appears from now on, e.g. a token a
+// test puts in a config
+func AddSecret(secret string) {
+	AddRedaction(Redaction{regexp.MustCompile(regexp.QuoteMeta(secret)), "REDACTED"})
+}
+
+// AddRedaction adds to Redactions, it's safe while tests run in parallel
+func AddRedaction(r Redaction) {
+	redactionsMu.Lock()
+	defer redactionsMu.Unlock()
+	Redactions = append(Redactions, r)
+}
+
+// Redact applies Redactions to s
+func Redact(s string) string {
+	redactionsMu.RLock()
+	defer redactionsMu.RUnlock()
+	for _, r := range Redactions {
+		s = r.Pattern.ReplaceAllString(s, r.Replace)
+	}
+	return s
+}
+
+// MaskSecrets overwrites what Redactions would replace in data with *,
+// keeping its length so binary files like packet captures stay readable,
+// and returns how many secrets it masked
+func MaskSecrets(data []byte) (masked int) {
+	redactionsMu.RLock()
+	defer redactionsMu.RUnlock()
+	for _, r := range Redactions {
+		// a byte that doesn't match the secret, in case it starts or ends
+		// like the placeholder
+		template := []byte(strings.Replace(r.Replace, "REDACTED", "\xff", -1))
+		for _, m := range r.Pattern.FindAllSubmatchIndex(data, -1) {
+			match := data[m[0]:m[1]]
+			replaced := r.Pattern.Expand(nil, template, data, m)
+
+			// only mask what the redaction changes, e.g. the password
+			// but not the user of a URL
+			start, end := 0, len(match)
+			for start < end && start < len(replaced) && match[start] == replaced[start] {
+				start++
+			}
+			for end > start && len(match)-end < len(replaced)-start && match[end-1] == replaced[len(replaced)-len(match)+end-1] {
+				end--
+			}
+			if start == end {
+				continue
+			}
+			for i := start; i < end; i++ {
+				match[i] = '*'
+			}
+			masked++
+		}
+	}
+	return
+}
+
+var shellSafe = regexp.MustCompile(`^[\w@%+=:,./-]+$`)
+
+// ShellQuote quotes s so a POSIX shell reads it back as one word
+func ShellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// ShellJoin quotes and joins words into a shell command line, unlike
+// FormatCommand it doesn't redact anything so it's for running, not logging
+func ShellJoin(words ...string) string {
+	return strings.Join(Map(words, ShellQuote), " ")
+}
+
+// FormatCommand renders a command so the log line can be pasted into a
+// shell to rerun it, with secrets redacted
+func FormatCommand(command string, args ...string) string {
+	return ShellJoin(Map(append([]string{command}, args...), Redact)...)
+}
@@ -0,0 +1,100 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy is how often and how patiently Retry tries
+type RetryPolicy struct {
+	Attempts int
+	// wait before the second attempt, multiplied by Multiplier after each
+	// further attempt up to Max
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	// each wait is randomly shortened or lengthened by up to this fraction
+	Jitter float64
+}
+
+// DefaultRetryPolicy rides out udev and the device mapper catching up with
+// a new or removed loop device, which takes a second or two under load
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:   6,
+	Initial:    100 * time.Millisecond,
+	Max:        5 * time.Second,
+	Multiplier: 2,
+	Jitter:     0.2,
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+// Permanent wraps an error fn returns to stop Retry retrying, Retry returns
+// the unwrapped error
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+// Retry calls fn until it succeeds, the policy's attempts run out or ctx is
+// done, and returns the last error
+func Retry(ctx context.Context, policy RetryPolicy, fn func() error) error {
+	wait := policy.Initial
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+
+		if permanent, ok := err.(permanentError); ok {
+			return permanent.err
+		}
+
+		if attempt >= policy.Attempts {
+			return fmt.Errorf("failed after %d attempts: %v", attempt, err)
+		}
+
+		delay := time.Duration(float64(wait) * (1 + policy.Jitter*(2*rand.Float64()-1)))
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("gave up after %d attempts: %v", attempt, err)
+		case <-time.After(delay):
+		}
+
+		wait = time.Duration(float64(wait) * policy.Multiplier)
+		if policy.Max != 0 && wait > policy.Max {
+			wait = policy.Max
+		}
+	}
+}
+
+// RunRetry is RunE retried with the policy, for commands that fail while
+// devices are still settling
+func RunRetry(ctx context.Context, policy RetryPolicy, command string, opts ...string) (result CmdResult, err error) {
+	err = Retry(ctx, policy, func() error {
+		result, err = RunE(ctx, command, opts...)
+		return err
+	})
+	return
+}
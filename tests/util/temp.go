@@ -0,0 +1,190 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+type tempPath struct {
+	path string
+	// mount points are only removed once empty, so a leftover mount is
+	// never recursively deleted through
+	mountPoint bool
+}
+
+// TempManager creates temp files and dirs and removes all of them in one
+// Close. Open managers are also closed if the suite is interrupted with
+// SIGINT or SIGTERM.
+type TempManager struct {
+	// where new paths are created, "" is TMPDIR
+	root string
+	// shared with every manager In returns
+	*tempPaths
+}
+
+type tempPaths struct {
+	mu     sync.Mutex
+	paths  []tempPath
+	closed bool
+}
+
+var (
+	liveManagersMu sync.Mutex
+	liveManagers   = map[*TempManager]bool{}
+	signalOnce     sync.Once
+)
+
+func NewTempManager() *TempManager {
+	return NewTempManagerIn("")
+}
+
+// NewTempManagerIn creates everything in root instead of TMPDIR
+func NewTempManagerIn(root string) *TempManager {
+	m := &TempManager{root: root, tempPaths: &tempPaths{}}
+
+	liveManagersMu.Lock()
+	liveManagers[m] = true
+	liveManagersMu.Unlock()
+
+	signalOnce.Do(cleanupOnSignal)
+	return m
+}
+
+// cleanupOnSignal closes every open manager on SIGINT or SIGTERM, then lets
+// the signal kill the process as it would have
+func cleanupOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		sig := <-signals
+
+		liveManagersMu.Lock()
+		managers := make([]*TempManager, 0, len(liveManagers))
+		for m := range liveManagers {
+			managers = append(managers, m)
+		}
+		liveManagersMu.Unlock()
+
+		for _, m := range managers {
+			if err := m.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "cleaning up temp files: %v\n", err)
+			}
+		}
+
+		signal.Stop(signals)
+		syscall.Kill(os.Getpid(), sig.(syscall.Signal))
+	}()
+}
+
+// In returns a manager that creates paths in root and removes them when m
+// is closed
+func (m *TempManager) In(root string) *TempManager {
+	return &TempManager{root: root, tempPaths: m.tempPaths}
+}
+
+// Root is the dir new paths are created in
+func (m *TempManager) Root() string {
+	if m.root == "" {
+		return os.TempDir()
+	}
+	return m.root
+}
+
+func (m *TempManager) track(p tempPath) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.paths = append(m.paths, p)
+}
+
+// Track adds an existing path to be removed on Close
+func (m *TempManager) Track(path string) {
+	m.track(tempPath{path: path})
+}
+
+// Dir creates a temp dir in Root
+func (m *TempManager) Dir(prefix string) (string, error) {
+	dir, err := ioutil.TempDir(m.root, prefix)
+	if err != nil {
+		return "", err
+	}
+	m.Track(dir)
+	return dir, nil
+}
+
+// File creates a temp file in Root, the caller closes it
+func (m *TempManager) File(prefix string) (*os.File, error) {
+	f, err := ioutil.TempFile(m.root, prefix)
+	if err != nil {
+		return nil, err
+	}
+	m.Track(f.Name())
+	return f, nil
+}
+
+// MountPoint creates an empty dir to mount on. Close only removes it once
+// it's empty, it doesn't delete anything still mounted there.
+func (m *TempManager) MountPoint(prefix string) (string, error) {
+	dir, err := ioutil.TempDir(m.root, prefix)
+	if err != nil {
+		return "", err
+	}
+	m.track(tempPath{path: dir, mountPoint: true})
+	return dir, nil
+}
+
+// Close removes everything the manager created, newest first, and reports
+// every path it couldn't remove
+func (m *TempManager) Close() error {
+	liveManagersMu.Lock()
+	delete(liveManagers, m)
+	liveManagersMu.Unlock()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+
+	var failed []string
+	for i := len(m.paths) - 1; i >= 0; i-- {
+		p := m.paths[i]
+
+		var err error
+		if p.mountPoint {
+			err = os.Remove(p.path)
+		} else {
+			err = os.RemoveAll(p.path)
+		}
+
+		if err != nil && !os.IsNotExist(err) {
+			failed = append(failed, err.Error())
+		}
+	}
+	m.paths = nil
+
+	if len(failed) != 0 {
+		return fmt.Errorf("couldn't remove %d temp paths: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return nil
+}
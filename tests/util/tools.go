@@ -0,0 +1,108 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// PreflightFatal makes RequireTools and RequireRoot fail the test instead of
+// skipping it, for CI where a missing tool means a broken runner
+var PreflightFatal = false
+
+var versionPattern = regexp.MustCompile(`\d+(\.\d+)+`)
+
+// RequireTools checks each tool is in PATH, and new enough for specs like
+// "sgdisk>=1.0", and skips the test with one message listing everything
+// that's missing
+func RequireTools(t *testing.T, specs ...string) {
+	var problems []string
+	for _, spec := range specs {
+		if err := checkTool(spec); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) == 0 {
+		return
+	}
+
+	msg := "missing required tools: " + strings.Join(problems, "; ")
+	if PreflightFatal {
+		t.Fatal(msg)
+	}
+	t.Skip(msg)
+}
+
+func checkTool(spec string) error {
+	name, minVersion := spec, ""
+	if parts := strings.SplitN(spec, ">=", 2); len(parts) == 2 {
+		name, minVersion = parts[0], parts[1]
+	}
+
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return fmt.Errorf("%s not found in PATH", name)
+	}
+
+	if minVersion == "" {
+		return nil
+	}
+
+	// tools disagree on whether --version goes to stdout or stderr
+	result, err := Exec(context.Background(), path, "--version")
+	if err != nil {
+		return fmt.Errorf("couldn't get the version of %s: %v", name, err)
+	}
+
+	version := versionPattern.Find(append(result.Stdout, result.Stderr...))
+	if version == nil {
+		return fmt.Errorf("couldn't find the version of %s in its --version output", name)
+	}
+
+	if compareVersions(string(version), minVersion) < 0 {
+		return fmt.Errorf("%s is version %s, %s or newer is required", name, version, minVersion)
+	}
+	return nil
+}
+
+// compareVersions compares dotted numeric versions, missing components
+// count as 0
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,130 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+)
+
+// TreeEntry describes a file by everything but its times
+type TreeEntry struct {
+	// relative to the root of the tree
+	Path string
+	Mode os.FileMode
+	Uid  uint32
+	Gid  uint32
+	Size int64
+	// regular files only
+	SHA256 string
+	// symlinks only
+	Target string
+}
+
+func (e TreeEntry) String() string {
+	s := fmt.Sprintf("%v %d:%d", e.Mode, e.Uid, e.Gid)
+	if e.Mode.IsRegular() {
+		s += fmt.Sprintf(" %d %s", e.Size, e.SHA256)
+	}
+	if e.Target != "" {
+		s += " -> " + e.Target
+	}
+	return s
+}
+
+// Tree is every file under a directory, sorted by path
+type Tree []TreeEntry
+
+// WalkTree builds the manifest of everything under root, e.g. a mounted
+// partition
+func WalkTree(root string) (Tree, error) {
+	var tree Tree
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		entry := TreeEntry{Path: rel, Mode: info.Mode()}
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			entry.Uid, entry.Gid = stat.Uid, stat.Gid
+		}
+
+		switch {
+		case info.Mode().IsRegular():
+			entry.Size = info.Size()
+			if entry.SHA256, err = HashFile(path, SHA256); err != nil {
+				return err
+			}
+		case info.Mode()&os.ModeSymlink != 0:
+			if entry.Target, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+
+		tree = append(tree, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't walk %s: %v", root, err)
+	}
+
+	sort.Slice(tree, func(i, j int) bool { return tree[i].Path < tree[j].Path })
+	return tree, nil
+}
+
+// WriteTo writes the manifest one file per line, for reports
+func (tree Tree) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	for _, e := range tree {
+		n, err := fmt.Fprintf(w, "%s %s\n", e.Path, e)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// DiffTrees lists every file that was added, removed or changed between two
+// manifests, in path order
+func DiffTrees(expected, actual Tree) (diffs []string) {
+	i, j := 0, 0
+	for i < len(expected) || j < len(actual) {
+		switch {
+		case j == len(actual) || (i < len(expected) && expected[i].Path < actual[j].Path):
+			diffs = append(diffs, fmt.Sprintf("- %s %s", expected[i].Path, expected[i]))
+			i++
+		case i == len(expected) || actual[j].Path < expected[i].Path:
+			diffs = append(diffs, fmt.Sprintf("+ %s %s", actual[j].Path, actual[j]))
+			j++
+		default:
+			if expected[i] != actual[j] {
+				diffs = append(diffs, fmt.Sprintf("~ %s %s => %s", expected[i].Path, expected[i], actual[j]))
+			}
+			i++
+			j++
+		}
+	}
+	return
+}
@@ -15,19 +15,29 @@
 package util
 
 import (
-	"os/exec"
+	"context"
+	"fmt"
 	"regexp"
-	"strings"
 	"testing"
 )
 
 func RegexpSearch(t *testing.T, itemName, pattern string, data []byte) string {
+	match, err := RegexpSearchE(itemName, pattern, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return match
+}
+
+// RegexpSearchE returns the first submatch of pattern, or an error if
+// there isn't one
+func RegexpSearchE(itemName, pattern string, data []byte) (string, error) {
 	re := regexp.MustCompile(pattern)
 	match := re.FindSubmatch(data)
 	if len(match) < 2 {
-		t.Fatalf("couldn't find %s", itemName)
+		return "", fmt.Errorf("couldn't find %s", itemName)
 	}
-	return string(match[1])
+	return string(match[1]), nil
 }
 
 func RegexpContains(t *testing.T, itemName, pattern string, data []byte) bool {
@@ -36,11 +46,21 @@ func RegexpContains(t *testing.T, itemName, pattern string, data []byte) bool {
 	return len(match) > 0
 }
 
-func RegexpSearchAll(t *testing.T, itemName, pattern string, data []byte) (ret []string) {
+func RegexpSearchAll(t *testing.T, itemName, pattern string, data []byte) []string {
+	matches, err := RegexpSearchAllE(itemName, pattern, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+// RegexpSearchAllE returns the first submatch of every match of pattern, or
+// an error if there are none
+func RegexpSearchAllE(itemName, pattern string, data []byte) (ret []string, err error) {
 	re := regexp.MustCompile(pattern)
 	match := re.FindAllSubmatch(data, -1)
 	if match == nil {
-		t.Fatalf("couldn't find %s", itemName)
+		return nil, fmt.Errorf("couldn't find %s", itemName)
 	}
 
 	for _, m := range match {
@@ -49,16 +69,40 @@ func RegexpSearchAll(t *testing.T, itemName, pattern string, data []byte) (ret [
 	return
 }
 
+// MustRun runs the command and returns its stdout, failing the test if it
+// doesn't succeed
 func MustRun(t *testing.T, command string, opts ...string) []byte {
-	out, err := exec.Command(command, opts...).CombinedOutput()
+	return MustRunContext(context.Background(), t, command, opts...).Stdout
+}
+
+// MustRunContext is MustRun, stopping the command early if ctx is done
+func MustRunContext(ctx context.Context, t *testing.T, command string, opts ...string) CmdResult {
+	result, err := RunE(ctx, command, opts...)
 	if err != nil {
-		t.Log(string(out))
-		t.Fatalf("%s %s failed: %v", command, strings.Join(opts, " "), err)
+		t.Log(string(result.Stdout))
+		t.Fatal(err)
 	}
-	return out
+	return result
 }
 
 func Run(t *testing.T, command string, opts ...string) error {
-	_, err := exec.Command(command, opts...).CombinedOutput()
+	return RunContext(context.Background(), t, command, opts...)
+}
+
+func RunContext(ctx context.Context, t *testing.T, command string, opts ...string) error {
+	_, err := RunE(ctx, command, opts...)
 	return err
 }
+
+// RunE runs the command and returns an error naming it if it couldn't be
+// run, timed out or exited nonzero
+func RunE(ctx context.Context, command string, opts ...string) (CmdResult, error) {
+	result, err := Exec(ctx, command, opts...)
+	if err == nil {
+		err = result.Err()
+	}
+	if err != nil {
+		return result, fmt.Errorf("%s failed: %v", FormatCommand(command, opts...), err)
+	}
+	return result, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+const regexpTestData = `Disk /dev/loop0: 20971520 sectors
+Number  Start (sector)    End (sector)  Size       Code  Name
+   1            4096          266239   128.0 MiB   EF00  EFI-SYSTEM
+   9         2990080        20971486   8.6 GiB     FFFF  ROOT
+`
+
+func TestRegexpSearchE(t *testing.T) {
+	for _, c := range []struct {
+		pattern string
+		match   string
+		err     bool
+	}{
+		{`Disk (\S+):`, "/dev/loop0", false},
+		// only the first match
+		{`(?m)^\s+(\d+)\s`, "1", false},
+		{`Disk (/dev/sda)`, "", true},
+		// a match without a group isn't a submatch
+		{`Disk`, "", true},
+	} {
+		match, err := RegexpSearchE("item", c.pattern, []byte(regexpTestData))
+		if (err != nil) != c.err {
+			t.Errorf("%s: unexpected error %v", c.pattern, err)
+		}
+		if match != c.match {
+			t.Errorf("%s: matched %q, expected %q", c.pattern, match, c.match)
+		}
+	}
+}
+
+func TestRegexpSearchAllE(t *testing.T) {
+	matches, err := RegexpSearchAllE("partition", `(?m)^\s+\d+\s.*\s(\S+)$`, []byte(regexpTestData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"EFI-SYSTEM", "ROOT"}; !reflect.DeepEqual(matches, expected) {
+		t.Errorf("matched %q, expected %q", matches, expected)
+	}
+
+	if _, err := RegexpSearchAllE("partition", `(USR-A)`, []byte(regexpTestData)); err == nil {
+		t.Errorf("expected an error without matches")
+	}
+}
+
+func TestRegexpContains(t *testing.T) {
+	if !RegexpContains(t, "root", `FFFF\s+ROOT`, []byte(regexpTestData)) {
+		t.Errorf("didn't find ROOT")
+	}
+	if RegexpContains(t, "oem", `OEM`, []byte(regexpTestData)) {
+		t.Errorf("found OEM")
+	}
+}
@@ -0,0 +1,396 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// ParseYAML decodes the block YAML cloud-configs are written in into the
+// same types encoding/json uses: block mappings and sequences, plain and
+// quoted scalars, | and > block scalars, and flow collections that are also
+// valid JSON. Anchors, tags and multiple documents aren't supported.
+func ParseYAML(data []byte) (interface{}, error) {
+	p := &yamlParser{}
+	// the newline ending the last line doesn't start another one, which
+	// would be kept by |+
+	text := strings.Replace(string(data), "\r\n", "\n", -1)
+	raw := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, line := range raw {
+		if strings.Contains(line, "\t") && strings.TrimLeft(line, " ") != strings.TrimLeft(line, " \t") {
+			return nil, fmt.Errorf("line %d: tabs can't be used for indentation", i+1)
+		}
+		p.lines = append(p.lines, yamlLine{
+			number: i + 1,
+			indent: len(line) - len(strings.TrimLeft(line, " ")),
+			text:   strings.TrimRight(strings.TrimLeft(line, " "), " "),
+		})
+	}
+
+	p.skipBlank()
+	if p.pos < len(p.lines) && p.lines[p.pos].text == "---" {
+		p.pos++
+		p.skipBlank()
+	}
+	if p.pos == len(p.lines) {
+		return nil, nil
+	}
+
+	v, err := p.node(p.lines[p.pos].indent)
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipBlank()
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected %q", p.lines[p.pos].number, p.lines[p.pos].text)
+	}
+	return v, nil
+}
+
+func isBlankYAML(text string) bool {
+	return text == "" || strings.HasPrefix(text, "#")
+}
+
+func (p *yamlParser) skipBlank() {
+	for p.pos < len(p.lines) && isBlankYAML(p.lines[p.pos].text) {
+		p.pos++
+	}
+}
+
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// node parses the block starting at the current line, which is at indent
+func (p *yamlParser) node(indent int) (interface{}, error) {
+	line := p.lines[p.pos]
+	if isSequenceItem(line.text) {
+		return p.sequence(indent)
+	}
+	if _, _, ok := splitKey(line.text); ok {
+		return p.mapping(indent)
+	}
+
+	p.pos++
+	return scalar(stripComment(line.text), line.number)
+}
+
+func (p *yamlParser) sequence(indent int) (interface{}, error) {
+	seq := []interface{}{}
+	for {
+		p.skipBlank()
+		if p.pos == len(p.lines) {
+			return seq, nil
+		}
+
+		line := p.lines[p.pos]
+		if line.indent != indent || !isSequenceItem(line.text) {
+			if line.indent > indent {
+				return nil, fmt.Errorf("line %d: bad indentation", line.number)
+			}
+			return seq, nil
+		}
+
+		content := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		if isBlankYAML(content) {
+			p.pos++
+			v, err := p.child(indent)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+			continue
+		}
+
+		// parse the item's content as if it started on its own line at
+		// the column it's at, e.g. "- name: x" starts a mapping
+		p.lines[p.pos] = yamlLine{
+			number: line.number,
+			indent: line.indent + len(line.text) - len(content),
+			text:   content,
+		}
+		v, err := p.node(p.lines[p.pos].indent)
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, v)
+	}
+}
+
+func (p *yamlParser) mapping(indent int) (interface{}, error) {
+	m := map[string]interface{}{}
+	for {
+		p.skipBlank()
+		if p.pos == len(p.lines) {
+			return m, nil
+		}
+
+		line := p.lines[p.pos]
+		if line.indent != indent || isSequenceItem(line.text) {
+			if line.indent > indent {
+				return nil, fmt.Errorf("line %d: bad indentation", line.number)
+			}
+			return m, nil
+		}
+
+		key, rest, ok := splitKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected a key, found %q", line.number, line.text)
+		}
+		if _, dup := m[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line.number, key)
+		}
+		p.pos++
+
+		rest = stripComment(rest)
+		var v interface{}
+		var err error
+		switch {
+		case rest == "":
+			v, err = p.child(indent)
+		case rest[0] == '|' || rest[0] == '>':
+			v, err = p.blockScalar(indent, rest, line.number)
+		default:
+			v, err = scalar(rest, line.number)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+}
+
+// child parses the value of a key or sequence item that's on the following
+// lines, a sequence can be a mapping's value at the mapping's own indent
+func (p *yamlParser) child(indent int) (interface{}, error) {
+	p.skipBlank()
+	if p.pos == len(p.lines) {
+		return nil, nil
+	}
+
+	next := p.lines[p.pos]
+	if next.indent > indent || (next.indent == indent && isSequenceItem(next.text)) {
+		return p.node(next.indent)
+	}
+	return nil, nil
+}
+
+func (p *yamlParser) blockScalar(indent int, header string, number int) (interface{}, error) {
+	folded := header[0] == '>'
+	chomp := strings.TrimLeft(header[1:], "0123456789")
+	if chomp != "" && chomp != "-" && chomp != "+" {
+		return nil, fmt.Errorf("line %d: unsupported block scalar header %q", number, header)
+	}
+
+	var lines []string
+	blockIndent := -1
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.text == "" {
+			lines = append(lines, "")
+			p.pos++
+			continue
+		}
+		if line.indent <= indent {
+			break
+		}
+		if blockIndent == -1 {
+			blockIndent = line.indent
+		}
+		if line.indent < blockIndent {
+			return nil, fmt.Errorf("line %d: bad indentation in block scalar", line.number)
+		}
+		lines = append(lines, strings.Repeat(" ", line.indent-blockIndent)+line.text)
+		p.pos++
+	}
+
+	// trailing blank lines belong to the chomping, not the content
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+
+	var text string
+	if folded {
+		// a break between two lines folds into a space, or into the
+		// empty lines that follow it, unless a line next to it is more
+		// indented
+		moreIndented := func(l string) bool { return strings.HasPrefix(l, " ") }
+		for i, l := range lines {
+			if i > 0 {
+				prev := lines[i-1]
+				switch {
+				case l == "" && prev != "" && !moreIndented(prev) && !moreIndented(nextYAMLText(lines, i)):
+				case l != "" && prev != "" && !moreIndented(l) && !moreIndented(prev):
+					text += " "
+				default:
+					text += "\n"
+				}
+			}
+			text += l
+		}
+	} else {
+		text = strings.Join(lines, "\n")
+	}
+
+	switch {
+	case len(lines) == 0:
+	case chomp == "-":
+	case chomp == "+":
+		text += "\n" + strings.Repeat("\n", trailing)
+	default:
+		text += "\n"
+	}
+	return text, nil
+}
+
+// nextYAMLText is the first line from i on that isn't empty
+func nextYAMLText(lines []string, i int) string {
+	for ; i < len(lines); i++ {
+		if lines[i] != "" {
+			return lines[i]
+		}
+	}
+	return ""
+}
+
+// splitKey splits "key: value" and "key:", keys can be quoted
+func splitKey(text string) (string, string, bool) {
+	if text == "" || text[0] == '#' || text[0] == '[' || text[0] == '{' {
+		return "", "", false
+	}
+
+	if text[0] == '"' || text[0] == '\'' {
+		end := closingQuote(text)
+		if end < 0 || end+1 >= len(text) || text[end+1] != ':' {
+			return "", "", false
+		}
+		rest := text[end+2:]
+		if rest != "" && rest[0] != ' ' {
+			return "", "", false
+		}
+		key, err := scalar(text[:end+1], 0)
+		if err != nil {
+			return "", "", false
+		}
+		return fmt.Sprint(key), strings.TrimSpace(rest), true
+	}
+
+	if strings.HasSuffix(text, ":") && !strings.Contains(text, ": ") {
+		return text[:len(text)-1], "", true
+	}
+	i := strings.Index(text, ": ")
+	if i < 0 {
+		return "", "", false
+	}
+	return text[:i], strings.TrimSpace(text[i+2:]), true
+}
+
+func closingQuote(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case quote == '\'' && text[i] == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// stripComment removes a trailing " # comment" outside of quotes
+func stripComment(text string) string {
+	if text != "" && (text[0] == '"' || text[0] == '\'') {
+		if end := closingQuote(text); end >= 0 {
+			rest := text[end+1:]
+			if i := strings.Index(rest, " #"); i >= 0 {
+				rest = rest[:i]
+			}
+			return strings.TrimSpace(text[:end+1] + rest)
+		}
+	}
+
+	if strings.HasPrefix(text, "#") {
+		return ""
+	}
+	if i := strings.Index(text, " #"); i >= 0 {
+		text = text[:i]
+	}
+	return strings.TrimSpace(text)
+}
+
+// scalar resolves a flow scalar to a string, bool, float64 or nil like
+// YAML 1.2's core schema
+func scalar(text string, number int) (interface{}, error) {
+	switch {
+	case text == "":
+		return nil, nil
+	case text[0] == '"':
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bad double quoted string %s", number, text)
+		}
+		return s, nil
+	case text[0] == '\'':
+		if len(text) < 2 || text[len(text)-1] != '\'' {
+			return nil, fmt.Errorf("line %d: bad single quoted string %s", number, text)
+		}
+		return strings.Replace(text[1:len(text)-1], "''", "'", -1), nil
+	case text[0] == '[' || text[0] == '{':
+		var v interface{}
+		if err := json.Unmarshal([]byte(text), &v); err != nil {
+			return nil, fmt.Errorf("line %d: only flow collections that are valid JSON are supported: %v", number, err)
+		}
+		return v, nil
+	case text[0] == '&' || text[0] == '*' || text[0] == '!':
+		return nil, fmt.Errorf("line %d: anchors, aliases and tags aren't supported", number)
+	}
+
+	switch text {
+	case "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+
+	if n, err := strconv.ParseInt(text, 0, 64); err == nil {
+		return float64(n), nil
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f, nil
+	}
+	return text, nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2017 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		yaml     string
+		expected interface{}
+	}{
+		{"empty", "", nil},
+		{"only comments", "# nothing\n\n  # here\n", nil},
+		{"document start", "---\na: 1\n", map[string]interface{}{"a": 1.0}},
+		{"scalars", "s: text\nn: 12\nhex: 0x1f\nf: 1.5\nt: true\nF: False\nz: ~\nnull: null\nempty:\n", map[string]interface{}{
+			"s": "text", "n": 12.0, "hex": 31.0, "f": 1.5, "t": true, "F": false, "z": nil, "null": nil, "empty": nil,
+		}},
+		{"nested", "a:\n  b:\n    c: d\n  e: f\n", map[string]interface{}{
+			"a": map[string]interface{}{"b": map[string]interface{}{"c": "d"}, "e": "f"},
+		}},
+		{"sequence at the mapping's indent", "users:\n- name: core\n  groups:\n    - sudo\n    - docker\n- name: other\n", map[string]interface{}{
+			"users": []interface{}{
+				map[string]interface{}{"name": "core", "groups": []interface{}{"sudo", "docker"}},
+				map[string]interface{}{"name": "other"},
+			},
+		}},
+		{"nested sequences", "- - a\n  - b\n-\n  - c\n", []interface{}{[]interface{}{"a", "b"}, []interface{}{"c"}}},
+		{"windows line endings", "a: 1\r\nb: 2\r\n", map[string]interface{}{"a": 1.0, "b": 2.0}},
+
+		// quoting
+		{"double quoted", `s: "a \"b\"\tc\n"`, map[string]interface{}{"s": "a \"b\"\tc\n"}},
+		{"single quoted", "s: 'it''s \\n'", map[string]interface{}{"s": "it's \\n"}},
+		{"quoted scalars stay strings", "a: \"true\"\nb: '12'\nc: \"\"\n", map[string]interface{}{"a": "true", "b": "12", "c": ""}},
+		{"quoted keys", "\"a: b\": 1\n'c''d': 2\n", map[string]interface{}{"a: b": 1.0, "c'd": 2.0}},
+		{"colon without a space", "url: http://example.com:80/x\n", map[string]interface{}{"url": "http://example.com:80/x"}},
+
+		// comments
+		{"trailing comments", "a: b # comment\nc: # comment\n  - d # comment\n", map[string]interface{}{"a": "b", "c": []interface{}{"d"}}},
+		{"hash in quotes", "a: \"b # c\" # comment\nb: 'x#y'\n", map[string]interface{}{"a": "b # c", "b": "x#y"}},
+		{"hash without a space", "a: b#c\n", map[string]interface{}{"a": "b#c"}},
+		{"comment lines between items", "a: 1\n# comment\n  # indented comment\nb: 2\n", map[string]interface{}{"a": 1.0, "b": 2.0}},
+
+		// block scalars
+		{"literal", "s: |\n  line one\n    indented\n\n  line three\nnext: x\n", map[string]interface{}{
+			"s": "line one\n  indented\n\nline three\n", "next": "x",
+		}},
+		{"literal strip", "s: |-\n  text\n\n", map[string]interface{}{"s": "text"}},
+		{"literal keep", "s: |+\n  text\n\n\n", map[string]interface{}{"s": "text\n\n\n"}},
+		{"literal with a comment header", "s: | # comment\n  text\n", map[string]interface{}{"s": "text\n"}},
+		{"empty literal", "s: |\nnext: x\n", map[string]interface{}{"s": "", "next": "x"}},
+		{"folded", "s: >\n  one\n  two\n\n  three\n    more indented\n  four\n", map[string]interface{}{
+			"s": "one two\nthree\n  more indented\nfour\n",
+		}},
+		// the YAML spec's example 8.10
+		{"folded with more indented lines", "s: >\n\n  folded\n  line\n\n  next\n  line\n    * bullet\n\n    * list\n    * lines\n\n  last\n  line\n", map[string]interface{}{
+			"s": "\nfolded line\nnext line\n  * bullet\n\n  * list\n  * lines\n\nlast line\n",
+		}},
+		{"folded strip", "s: >-\n  one\n  two\n", map[string]interface{}{"s": "one two"}},
+		{"block scalar in a sequence", "files:\n  - contents: |\n      #!/bin/sh\n      exit 0\n    mode: 0755\n", map[string]interface{}{
+			"files": []interface{}{map[string]interface{}{"contents": "#!/bin/sh\nexit 0\n", "mode": 493.0}},
+		}},
+
+		// flow collections
+		{"flow sequence", "a: [1, \"b\", true]\n", map[string]interface{}{"a": []interface{}{1.0, "b", true}}},
+		{"flow mapping", "a: {\"b\": [null]}\n", map[string]interface{}{"a": map[string]interface{}{"b": []interface{}{nil}}}},
+		{"flow sequence item", "- [1, 2]\n- {}\n", []interface{}{[]interface{}{1.0, 2.0}, map[string]interface{}{}}},
+		{"top level flow", "[\"a\"]", []interface{}{"a"}},
+	} {
+		v, err := ParseYAML([]byte(c.yaml))
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+		} else if !reflect.DeepEqual(v, c.expected) {
+			t.Errorf("%s: parsed %#v, expected %#v", c.name, v, c.expected)
+		}
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		yaml string
+		err  string
+	}{
+		{"tab indentation", "a:\n\tb: c\n", "line 2: tabs can't be used for indentation"},
+		{"bad indentation", "a: 1\n  b: 2\n", "line 2: bad indentation"},
+		{"sequence indentation", "- a\n   - b\n", "line 2: bad indentation"},
+		{"duplicate key", "a: 1\na: 2\n", "line 2: duplicate key \"a\""},
+		{"unterminated double quote", "a: \"b\n", "line 1: bad double quoted string"},
+		{"unterminated single quote", "a: 'b\n", "line 1: bad single quoted string"},
+		{"flow collection that isn't JSON", "a: [b, c]\n", "line 1: only flow collections that are valid JSON are supported"},
+		{"anchor", "a: &x 1\n", "line 1: anchors, aliases and tags aren't supported"},
+		{"tag", "a: !!str 1\n", "line 1: anchors, aliases and tags aren't supported"},
+		{"block scalar header", "a: |x\n  b\n", "line 1: unsupported block scalar header \"|x\""},
+		{"less indented block scalar", "a: |\n    b\n   c\n", "line 3: bad indentation in block scalar"},
+		{"trailing content", "a\nb\n", "line 2: unexpected \"b\""},
+	} {
+		_, err := ParseYAML([]byte(c.yaml))
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: got error %v, expected %q", c.name, err, c.err)
+		}
+	}
+}